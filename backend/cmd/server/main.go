@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
@@ -79,18 +80,6 @@ func main() {
 	}
 	s3Client := s3.NewFromConfig(awsCfg)
 
-	// Initialize services
-	emailService := services.NewEmailService(cfg)
-	authService := services.NewAuthService(pgClient, cfg, emailService)
-	oauthService := services.NewOAuthService(pgClient, cfg, authService)
-
-	// Initialize services
-	appService := services.NewAppService(pgClient)
-	versionService := services.NewVersionService(pgClient)
-	commentService := services.NewCommentService(pgClient)
-	uploadService := services.NewUploadService(pgClient, s3Client, cfg)
-	vercelService := services.NewVercelService(cfg)
-
 	// Initialize Redis client (Upstash)
 	var redisClient *redis.Client
 	if cfg.RedisURL != "" {
@@ -111,14 +100,63 @@ func main() {
 		log.Println("Warning: Redis URL not configured, SSE will not work")
 	}
 
+	// Initialize services
+	emailService := services.NewEmailService(cfg)
+	authService := services.NewAuthService(pgClient, cfg, emailService)
+	oauthService := services.NewOAuthService(pgClient, cfg, authService)
+
+	// Initialize services
+	vercelService := services.NewVercelService(cfg)
+	eventService := services.NewEventService(redisClient, pgClient)
+	appService := services.NewAppService(pgClient)
+	versionService := services.NewVersionService(pgClient, vercelService, eventService)
+	domainService := services.NewDomainService(pgClient, vercelService)
+	secretService := services.NewSecretService(pgClient, vercelService, cfg)
+	schemaService := services.NewSchemaService(pgClient, mongoClient)
+	appDatabaseService := services.NewAppDatabaseService(mongoClient)
+	dataService := services.NewDataService(pgClient, appDatabaseService, schemaService)
+	appUserService := services.NewAppUserService(mongoClient, emailService, versionService)
+	previewService := services.NewPreviewService(appDatabaseService, appUserService, versionService)
+	shareService := services.NewShareService(pgClient, cfg, versionService)
+	commentService := services.NewCommentService(pgClient)
+	uploadService := services.NewUploadService(pgClient, s3Client, cfg)
+	cloneService := services.NewCloneService(appService, versionService, secretService, uploadService)
+	templateService := services.NewTemplateService(cfg)
+	gitExportService := services.NewGitExportService(pgClient, cfg, secretService)
+	webhookService := services.NewWebhookService(pgClient, cfg, secretService)
+
 	// Initialize worker
-	builder := worker.NewBuilder(cfg, appService, versionService, vercelService, s3Client, redisClient)
+	builder := worker.NewBuilder(cfg, appService, versionService, vercelService, secretService, schemaService, appDatabaseService, templateService, gitExportService, s3Client, redisClient)
+
+	// Start background workers
+	reconciler := worker.NewReconciler(cfg, versionService, vercelService, eventService)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go reconciler.Start(workerCtx)
+
+	// Cleanup of deleted apps/versions runs as durable jobs
+	jobRunner := worker.NewJobRunner(services.NewJobService(pgClient), cfg.JobPollInterval)
+	worker.NewCleaner(cfg, s3Client, vercelService, mongoClient).Register(jobRunner)
+	worker.NewGitExporter(cfg, s3Client, gitExportService, versionService, commentService).Register(jobRunner)
+	worker.NewWebhookSender(webhookService).Register(jobRunner)
+	go jobRunner.Start(workerCtx)
 
 	// Initialize API handlers
 	authHandler := api.NewAuthHandler(authService, oauthService, cfg)
-	appHandler := api.NewAppHandler(appService, versionService, commentService, builder)
+	appHandler := api.NewAppHandler(appService, versionService, commentService, templateService, builder)
 	uploadHandler := api.NewUploadHandler(uploadService)
-	previewHandler := api.NewPreviewHandler(appService, versionService, mongoClient)
+	previewHandler := api.NewPreviewHandler(appService, previewService)
+	domainHandler := api.NewDomainHandler(appService, domainService)
+	secretHandler := api.NewSecretHandler(appService, secretService)
+	dataHandler := api.NewDataHandler(appService, dataService)
+	appUserHandler := api.NewAppUserHandler(appService, appUserService)
+	shareHandler := api.NewShareHandler(appService, shareService)
+	signingKeyHandler := api.NewSigningKeyHandler(appService, appDatabaseService)
+	gitExportHandler := api.NewGitExportHandler(appService, versionService, gitExportService)
+	historyHandler := api.NewHistoryHandler(appService, versionService, builder)
+	webhookHandler := api.NewWebhookHandler(appService, webhookService)
+	cloneHandler := api.NewCloneHandler(appService, cloneService, builder)
+	importHandler := api.NewImportHandler(appService, versionService, builder)
 
 	// Setup router
 	r := mux.NewRouter()
@@ -126,6 +164,15 @@ func main() {
 	// Apply CORS middleware globally
 	r.Use(middleware.CORSMiddleware)
 
+	// Opened share links are served on their own host, ahead of every other route
+	if cfg.ShareBaseURL != "" {
+		shareURL, err := url.Parse(cfg.ShareBaseURL)
+		if err != nil || shareURL.Host == "" {
+			log.Fatalf("Invalid SHARE_BASE_URL: %s", cfg.ShareBaseURL)
+		}
+		r.Host(shareURL.Host).Handler(api.NewShareProxyHandler(shareService))
+	}
+
 	// Public routes (no auth required)
 	r.HandleFunc("/health", healthCheck).Methods("GET")
 
@@ -141,6 +188,13 @@ func main() {
 	authRoutes.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST", "OPTIONS")
 	authRoutes.HandleFunc("/logout", authHandler.Logout).Methods("POST", "OPTIONS")
 
+	// Share link routes (public, the slug is the credential)
+	r.HandleFunc("/s/{slug}", shareHandler.RedirectShareLink).Methods("GET")
+	shareRoutes := r.PathPrefix("/api/v1/share").Subrouter()
+	shareRoutes.HandleFunc("/{slug}", shareHandler.GetShareLink).Methods("GET", "OPTIONS")
+	shareRoutes.HandleFunc("/{slug}/open", shareHandler.OpenShareLink).Methods("POST", "OPTIONS")
+	shareRoutes.HandleFunc("/{slug}/comments", shareHandler.AddShareComment).Methods("POST", "OPTIONS")
+
 	// Protected routes (require authentication)
 	protectedAuth := r.PathPrefix("/api/v1/auth").Subrouter()
 	protectedAuth.Use(middleware.AuthMiddleware(cfg))
@@ -153,9 +207,14 @@ func main() {
 	// App routes
 	api.HandleFunc("/apps", appHandler.ListApps).Methods("GET", "OPTIONS")
 	api.HandleFunc("/apps", appHandler.CreateApp).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/import", importHandler.ImportApp).Methods("POST", "OPTIONS")
 	api.HandleFunc("/apps/{id}", appHandler.GetApp).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{id}", appHandler.UpdateApp).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/apps/{id}", appHandler.DeleteApp).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/apps/{id}/clone", cloneHandler.CloneApp).Methods("POST", "OPTIONS")
+	api.HandleFunc("/templates", appHandler.ListTemplates).Methods("GET", "OPTIONS")
 	api.HandleFunc("/apps/{id}/preview-token", previewHandler.GeneratePreviewToken).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/{id}/preview-token/refresh", previewHandler.RefreshPreviewToken).Methods("POST", "OPTIONS")
 
 	// Version routes
 	api.HandleFunc("/apps/{appId}/versions", appHandler.ListVersions).Methods("GET", "OPTIONS")
@@ -164,6 +223,64 @@ func main() {
 	api.HandleFunc("/apps/{appId}/versions/{versionId}", appHandler.DeleteVersion).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/apps/{appId}/versions/{versionId}/promote", appHandler.PromoteVersion).Methods("POST", "OPTIONS")
 
+	// Custom domain routes
+	api.HandleFunc("/apps/{appId}/domains", domainHandler.ListDomains).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/domains", domainHandler.AddDomain).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/domains/{domainId}/verify", domainHandler.VerifyDomain).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/domains/{domainId}", domainHandler.RemoveDomain).Methods("DELETE", "OPTIONS")
+
+	// Secret routes
+	api.HandleFunc("/apps/{appId}/secrets", secretHandler.ListSecrets).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/secrets/{key}", secretHandler.SetSecret).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/secrets/{key}", secretHandler.DeleteSecret).Methods("DELETE", "OPTIONS")
+
+	// App data browser routes
+	api.HandleFunc("/apps/{appId}/data/collections", dataHandler.ListCollections).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/data/collections/{collection}/documents", dataHandler.FindDocuments).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/data/collections/{collection}/documents", dataHandler.InsertDocument).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/data/collections/{collection}/documents/{documentId}", dataHandler.GetDocument).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/data/collections/{collection}/documents/{documentId}", dataHandler.UpdateDocument).Methods("PATCH", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/data/collections/{collection}/documents/{documentId}", dataHandler.DeleteDocument).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/data/audit", dataHandler.ListAuditLog).Methods("GET", "OPTIONS")
+
+	// Generated app end user routes
+	api.HandleFunc("/apps/{appId}/users", appUserHandler.ListUsers).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/users", appUserHandler.InviteUser).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/users/{userId}", appUserHandler.GetUser).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/users/{userId}", appUserHandler.UpdateUser).Methods("PATCH", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/users/{userId}/reset-password", appUserHandler.ResetPassword).Methods("POST", "OPTIONS")
+
+	// Generated app signing key routes
+	api.HandleFunc("/apps/{appId}/signing-keys", signingKeyHandler.ListSigningKeys).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/signing-keys/rotate", signingKeyHandler.RotateSigningKey).Methods("POST", "OPTIONS")
+
+	// Git export routes
+	api.HandleFunc("/apps/{appId}/git-export", gitExportHandler.GetGitExport).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/git-export", gitExportHandler.SetGitExport).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/git-export", gitExportHandler.DeleteGitExport).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/git-export/push", gitExportHandler.PushGitExport).Methods("POST", "OPTIONS")
+
+	// Version history routes
+	api.HandleFunc("/apps/{appId}/history", historyHandler.GetHistory).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/history/diff", historyHandler.GetHistoryDiff).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/history/blame", historyHandler.GetHistoryBlame).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/history/restore", historyHandler.RestoreCommit).Methods("POST", "OPTIONS")
+
+	// Webhook routes
+	api.HandleFunc("/webhooks", webhookHandler.ListWebhooks).Methods("GET", "OPTIONS")
+	api.HandleFunc("/webhooks", webhookHandler.CreateWebhook).Methods("POST", "OPTIONS")
+	api.HandleFunc("/webhooks/{id}", webhookHandler.GetWebhook).Methods("GET", "OPTIONS")
+	api.HandleFunc("/webhooks/{id}", webhookHandler.UpdateWebhook).Methods("PATCH", "OPTIONS")
+	api.HandleFunc("/webhooks/{id}", webhookHandler.DeleteWebhook).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/webhooks/{id}/deliveries", webhookHandler.ListDeliveries).Methods("GET", "OPTIONS")
+	api.HandleFunc("/webhooks/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.RedeliverDelivery).Methods("POST", "OPTIONS")
+	api.HandleFunc("/webhooks/{id}/ping", webhookHandler.PingWebhook).Methods("POST", "OPTIONS")
+
+	// Share link management routes
+	api.HandleFunc("/apps/{appId}/share-links", shareHandler.ListShareLinks).Methods("GET", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/share-links", shareHandler.CreateShareLink).Methods("POST", "OPTIONS")
+	api.HandleFunc("/apps/{appId}/share-links/{linkId}", shareHandler.RevokeShareLink).Methods("DELETE", "OPTIONS")
+
 	// Comment routes
 	api.HandleFunc("/apps/{appId}/comments", appHandler.ListComments).Methods("GET", "OPTIONS")
 	api.HandleFunc("/apps/{appId}/comments", appHandler.AddComment).Methods("POST", "OPTIONS")
@@ -176,6 +293,9 @@ func main() {
 	// SSE route for build progress
 	api.HandleFunc("/versions/{versionId}/progress", appHandler.SSEHandler).Methods("GET", "OPTIONS")
 
+	// Redeploy a version's stored build output
+	api.HandleFunc("/versions/{versionId}/redeploy", appHandler.RedeployVersion).Methods("POST", "OPTIONS")
+
 	// Create server
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -199,6 +319,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -47,10 +47,14 @@ func main() {
 	s3Client := s3.NewFromConfig(awsCfg)
 
 	// Create services
-	versionService := services.NewVersionService(dbClient)
+	vercelService := services.NewVercelService(cfg)
+	appService := services.NewAppService(dbClient)
+	versionService := services.NewVersionService(dbClient, vercelService, nil)
+	secretService := services.NewSecretService(dbClient, vercelService, cfg)
+	templateService := services.NewTemplateService(cfg)
 
-	// Create builder
-	builder := worker.NewBuilder(cfg, versionService, s3Client)
+	// Create builder (without app databases, git export or Redis progress updates)
+	builder := worker.NewBuilder(cfg, appService, versionService, vercelService, secretService, nil, nil, templateService, nil, s3Client, nil)
 
 	// Test parameters
 	versionID := "22222222-aaaa-bbbb-cccc-222222222222"
@@ -60,7 +64,7 @@ func main() {
 	fmt.Printf("Starting build for version %s, app %s\n", versionID, appID)
 
 	// Run build
-	err = builder.BuildApp(context.Background(), versionID, appID, requirements, nil)
+	err = builder.BuildApp(context.Background(), versionID, appID, requirements, nil, "")
 	if err != nil {
 		log.Fatalf("Build failed: %v", err)
 	}
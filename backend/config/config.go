@@ -17,8 +17,8 @@ type Config struct {
 	MongoURL string
 
 	// JWT
-	JWTSecret         string
-	JWTExpiry         time.Duration
+	JWTSecret          string
+	JWTExpiry          time.Duration
 	RefreshTokenExpiry time.Duration
 
 	// SMTP Email
@@ -40,15 +40,64 @@ type Config struct {
 	S3Bucket     string
 
 	// Vercel
-	VercelToken string
+	VercelToken  string
+	VercelTeamID string // optional, deploys into a team instead of the token's personal account
+	VercelAPIURL string // overridable so a local mock server can stand in
+
+	// Deployment reconciler (polls Vercel for the state of recent/production deployments)
+	ReconcileInterval time.Duration
+	ReconcileWindow   time.Duration
+
+	// Background jobs (cleanup of deleted apps/versions)
+	JobPollInterval time.Duration
 
 	// Workspace
 	WorkspaceDir   string
 	StarterCodeDir string
+	TemplatesDir   string // one subdirectory per starter template, each with a template.json
+
+	// Code import (existing projects as an app's first version)
+	ImportMaxMB   int64
+	ImportGitRoot string // local git repositories can be imported from under this directory; disabled if empty
+
+	// Git export (push versions to a remote)
+	GitExportLocalRoot string // local (e.g. bare) repositories under this directory are allowed as remotes; disabled if empty
+
+	// Build quality gates (typecheck, lint, tests run after vercel build)
+	QualityGatesEnabled bool
+	QualityGateTimeout  time.Duration
+
+	// Dependency cache (node_modules keyed by lockfile hash)
+	BuildCacheEnabled bool
+	BuildCacheDir     string
+	BuildCacheMaxMB   int64
+	BuildCacheS3      bool
+
+	// Version history (a Git repository per app with a commit per completed version)
+	HistoryDir string
+	HistoryS3  bool // mirror each repository to S3 as a bundle
+
+	// Outgoing webhooks
+	WebhookTimeout      time.Duration
+	WebhookAllowPrivate bool // allow deliveries to loopback and private network addresses
+
+	// Post-deploy smoke tests
+	SmokeTestEnabled    bool
+	SmokeTestAttempts   int
+	SmokeTestRetryDelay time.Duration
+
+	// App secrets (AES-256 key, base64 or hex, used to encrypt per-app env vars at rest)
+	SecretsMasterKey string
 
 	// Frontend URL (for email links)
 	FrontendURL string
 
+	// Share links (deployments are proxied through a dedicated host so visitors never get the raw URL)
+	ShareBaseURL      string        // e.g. https://share.example.com, routed to this server; sharing is disabled if empty
+	ShareSessionTTL   time.Duration // how long an opened link can be viewed before it must be opened again
+	TrustProxyHeaders bool          // take client IPs from X-Forwarded-For, for password attempt limits
+	TrustedProxyHops  int           // proxies in front of the server that append to X-Forwarded-For
+
 	// Redis (Upstash - for build progress pub/sub)
 	RedisURL string
 }
@@ -59,6 +108,42 @@ func Load() *Config {
 
 	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
 
+	qualityGatesEnabled, _ := strconv.ParseBool(getEnv("QUALITY_GATES_ENABLED", "true"))
+	qualityGateTimeout, err := time.ParseDuration(getEnv("QUALITY_GATE_TIMEOUT", "10m"))
+	if err != nil || qualityGateTimeout <= 0 {
+		qualityGateTimeout = 10 * time.Minute
+	}
+
+	reconcileInterval, _ := time.ParseDuration(getEnv("DEPLOYMENT_RECONCILE_INTERVAL", "5m"))
+	reconcileWindow, _ := time.ParseDuration(getEnv("DEPLOYMENT_RECONCILE_WINDOW", "168h")) // 7 days
+
+	jobPollInterval, _ := time.ParseDuration(getEnv("JOB_POLL_INTERVAL", "5s"))
+
+	importMaxMB, _ := strconv.ParseInt(getEnv("IMPORT_MAX_MB", "100"), 10, 64)
+
+	buildCacheEnabled, _ := strconv.ParseBool(getEnv("BUILD_CACHE_ENABLED", "true"))
+	buildCacheMaxMB, _ := strconv.ParseInt(getEnv("BUILD_CACHE_MAX_MB", "10240"), 10, 64)
+	buildCacheS3, _ := strconv.ParseBool(getEnv("BUILD_CACHE_S3", "false"))
+
+	historyS3, _ := strconv.ParseBool(getEnv("HISTORY_S3", "false"))
+
+	shareSessionTTL, err := time.ParseDuration(getEnv("SHARE_SESSION_TTL", "1h"))
+	if err != nil || shareSessionTTL <= 0 {
+		shareSessionTTL = time.Hour
+	}
+	trustProxyHeaders, _ := strconv.ParseBool(getEnv("TRUST_PROXY_HEADERS", "false"))
+	trustedProxyHops, err := strconv.Atoi(getEnv("TRUSTED_PROXY_HOPS", "1"))
+	if err != nil || trustedProxyHops < 1 {
+		trustedProxyHops = 1
+	}
+
+	webhookTimeout, _ := time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	webhookAllowPrivate, _ := strconv.ParseBool(getEnv("WEBHOOK_ALLOW_PRIVATE", "false"))
+
+	smokeTestEnabled, _ := strconv.ParseBool(getEnv("SMOKE_TEST_ENABLED", "true"))
+	smokeTestAttempts, _ := strconv.Atoi(getEnv("SMOKE_TEST_ATTEMPTS", "5"))
+	smokeTestRetryDelay, _ := time.ParseDuration(getEnv("SMOKE_TEST_RETRY_DELAY", "10s"))
+
 	return &Config{
 		// Server
 		Port: getEnv("PORT", "8092"),
@@ -93,15 +178,64 @@ func Load() *Config {
 		S3Bucket:     getEnv("S3_BUCKET", "rapidbuild-apps"),
 
 		// Vercel
-		VercelToken: getEnv("VERCEL_TOKEN", ""),
+		VercelToken:  getEnv("VERCEL_TOKEN", ""),
+		VercelTeamID: getEnv("VERCEL_TEAM_ID", ""),
+		VercelAPIURL: getEnv("VERCEL_API_URL", "https://api.vercel.com"),
+
+		// Deployment reconciler
+		ReconcileInterval: reconcileInterval,
+		ReconcileWindow:   reconcileWindow,
+
+		// Background jobs
+		JobPollInterval: jobPollInterval,
 
 		// Workspace
 		WorkspaceDir:   getEnv("WORKSPACE_DIR", "/tmp/rapidbuild-workspaces"),
 		StarterCodeDir: getEnv("STARTER_CODE_DIR", "../../react-app"),
+		TemplatesDir:   getEnv("TEMPLATES_DIR", ""),
+
+		// Code import
+		ImportMaxMB:   importMaxMB,
+		ImportGitRoot: getEnv("IMPORT_GIT_ROOT", ""),
+
+		// Git export
+		GitExportLocalRoot: getEnv("GIT_EXPORT_LOCAL_ROOT", ""),
+
+		// Quality gates
+		QualityGatesEnabled: qualityGatesEnabled,
+		QualityGateTimeout:  qualityGateTimeout,
+
+		// Build cache
+		BuildCacheEnabled: buildCacheEnabled,
+		BuildCacheDir:     getEnv("BUILD_CACHE_DIR", "/tmp/rapidbuild-cache"),
+		BuildCacheMaxMB:   buildCacheMaxMB,
+		BuildCacheS3:      buildCacheS3,
+
+		// Version history
+		HistoryDir: getEnv("HISTORY_DIR", "/tmp/rapidbuild-history"),
+		HistoryS3:  historyS3,
+
+		// Webhooks
+		WebhookTimeout:      webhookTimeout,
+		WebhookAllowPrivate: webhookAllowPrivate,
+
+		// Smoke tests
+		SmokeTestEnabled:    smokeTestEnabled,
+		SmokeTestAttempts:   smokeTestAttempts,
+		SmokeTestRetryDelay: smokeTestRetryDelay,
+
+		// App secrets
+		SecretsMasterKey: getEnv("SECRETS_MASTER_KEY", ""),
 
 		// Frontend
 		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
 
+		// Share links
+		ShareBaseURL:      getEnv("SHARE_BASE_URL", ""),
+		ShareSessionTTL:   shareSessionTTL,
+		TrustProxyHeaders: trustProxyHeaders,
+		TrustedProxyHops:  trustedProxyHops,
+
 		// Redis
 		RedisURL: getEnv("REDIS_URL", ""),
 	}
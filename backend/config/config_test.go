@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQualityGateTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 10 * time.Minute},
+		{"90s", 90 * time.Second},
+		{"15m", 15 * time.Minute},
+		{"10", 10 * time.Minute},
+		{"ten minutes", 10 * time.Minute},
+		{"-5m", 10 * time.Minute},
+		{"0s", 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("QUALITY_GATE_TIMEOUT", tt.value)
+			if got := Load().QualityGateTimeout; got != tt.want {
+				t.Errorf("QualityGateTimeout = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
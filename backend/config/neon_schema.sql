@@ -41,6 +41,15 @@ CREATE TABLE IF NOT EXISTS apps (
     description TEXT,
     status TEXT NOT NULL DEFAULT 'draft',
     prod_version INTEGER,
+    prod_url TEXT,           -- Stable Vercel production URL once a version is promoted
+    vercel_project_id TEXT,
+    schema_version INTEGER,  -- Last schema migration applied to the app's MongoDB database
+    icon TEXT,               -- Emoji or image URL
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    framework TEXT,          -- e.g. vite, nextjs; detected on the first build unless set
+    visibility TEXT NOT NULL DEFAULT 'private',  -- private, unlisted, public
+    template TEXT,           -- Starter template of the first version, NULL for the default starter
+    revision INTEGER NOT NULL DEFAULT 1,  -- Bumped by user edits only, the app's ETag
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -52,15 +61,47 @@ CREATE TABLE IF NOT EXISTS versions (
     version_number INTEGER NOT NULL,
     status TEXT NOT NULL DEFAULT 'pending',
     s3_code_path TEXT,
+    s3_output_path TEXT,  -- Prebuilt .vercel/output archive for redeploys
     vercel_url TEXT,
     vercel_deploy_id TEXT,
+    s3_prod_output_path TEXT,  -- Output built with production secrets at promotion
+    prod_deploy_id TEXT,  -- Production deployment of that output
+    deployment_state TEXT,  -- Last Vercel state seen by the reconciler
     build_log TEXT,
     error_message TEXT,
+    checks_report JSONB,  -- Post-build quality gate results
+    smoke_report JSONB,   -- Post-deploy smoke test results
+    instructions TEXT,    -- Requirements the version was built from (comments are linked separately)
+    commit_sha TEXT,      -- Commit of the version in the app's history repository
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     completed_at TIMESTAMP WITH TIME ZONE,
     UNIQUE(app_id, version_number)
 );
 
+-- Public share links for an app or one of its versions
+CREATE TABLE IF NOT EXISTS share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    version_id UUID REFERENCES versions(id) ON DELETE CASCADE,  -- NULL shares the app's production (or latest) deployment
+    slug TEXT UNIQUE NOT NULL,
+    password_hash TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    max_views INTEGER,
+    view_count INTEGER NOT NULL DEFAULT 0,
+    allow_comments BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Failed share link password attempts, for rate limiting per link and client IP
+CREATE TABLE IF NOT EXISTS share_password_failures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    share_link_id UUID NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- Comments table
 CREATE TABLE IF NOT EXISTS comments (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -71,6 +112,8 @@ CREATE TABLE IF NOT EXISTS comments (
     element_path TEXT NOT NULL,
     content TEXT NOT NULL,
     status TEXT NOT NULL DEFAULT 'draft',
+    author_name TEXT,  -- set for anonymous comments left through a share link
+    share_link_id UUID REFERENCES share_links(id) ON DELETE SET NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     submitted_at TIMESTAMP WITH TIME ZONE
 );
@@ -86,6 +129,119 @@ CREATE TABLE IF NOT EXISTS requirement_files (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Custom domains table
+CREATE TABLE IF NOT EXISTS app_domains (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL UNIQUE,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    verification JSONB,  -- DNS records Vercel still needs to see
+    verified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- App secrets table (per-app environment variables, encrypted with SECRETS_MASTER_KEY)
+CREATE TABLE IF NOT EXISTS app_secrets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT 'preview',  -- preview, production
+    encrypted_value TEXT NOT NULL,          -- base64 AES-GCM nonce + ciphertext
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(app_id, key, scope)
+);
+
+-- Git export settings (push each completed or promoted version to a remote)
+CREATE TABLE IF NOT EXISTS git_exports (
+    app_id UUID PRIMARY KEY REFERENCES apps(id) ON DELETE CASCADE,
+    remote_url TEXT NOT NULL,
+    branch TEXT NOT NULL DEFAULT 'main',
+    username TEXT,
+    encrypted_token TEXT,   -- base64 AES-GCM nonce + ciphertext, like app_secrets
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_pushed_version INTEGER,
+    last_commit TEXT,
+    last_pushed_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Webhook subscriptions (HMAC-signed app events sent to user URLs). app_id is
+-- not a foreign key so app.deleted still reaches the app's webhooks; NULL means
+-- every app of the user.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    app_id UUID,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',  -- empty for every event
+    encrypted_secret TEXT NOT NULL,       -- base64 AES-GCM nonce + ciphertext, like app_secrets
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Webhook delivery log (one row per event sent to a webhook, sent by jobs)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    app_id UUID,
+    payload TEXT NOT NULL,                    -- JSON body, resent as is
+    status TEXT NOT NULL DEFAULT 'pending',   -- pending, retrying, succeeded, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    duration_ms INTEGER,
+    redelivery_of UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Background jobs table (durable work such as cleaning up deleted apps' resources)
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'pending',  -- pending, running, completed, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 8,
+    last_error TEXT,
+    run_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    locked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Schema migrations applied to generated app databases
+CREATE TABLE IF NOT EXISTS app_schema_migrations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    version_id UUID REFERENCES versions(id) ON DELETE SET NULL,
+    schema_version INTEGER NOT NULL,
+    schema JSONB NOT NULL,  -- Full collection/index snapshot after this migration
+    steps JSONB NOT NULL,
+    backup_path TEXT,       -- S3 prefix of collection backups taken before applying
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(app_id, schema_version)
+);
+
+-- Audit log of owner edits made through the app data browser
+CREATE TABLE IF NOT EXISTS app_data_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    collection TEXT NOT NULL,
+    document_id TEXT NOT NULL,
+    action TEXT NOT NULL,  -- insert, update, delete
+    before JSONB,          -- document before the change (extended JSON)
+    after JSONB,           -- document after the change (extended JSON)
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- Indexes for users
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id);
@@ -119,6 +275,49 @@ CREATE INDEX IF NOT EXISTS idx_comments_status ON comments(status);
 CREATE INDEX IF NOT EXISTS idx_requirement_files_app_id ON requirement_files(app_id);
 CREATE INDEX IF NOT EXISTS idx_requirement_files_version_id ON requirement_files(version_id);
 
+-- Indexes for custom domains
+CREATE INDEX IF NOT EXISTS idx_app_domains_app_id ON app_domains(app_id);
+
+-- Indexes for app secrets
+CREATE INDEX IF NOT EXISTS idx_app_secrets_app_id ON app_secrets(app_id);
+
+-- Indexes for webhooks
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_app_id ON webhooks(app_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- Indexes for jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs(status, run_at);
+
+-- Indexes for the data audit log
+CREATE INDEX IF NOT EXISTS idx_app_data_audit_log_app_id ON app_data_audit_log(app_id, created_at DESC);
+
+-- Indexes for share links
+CREATE INDEX IF NOT EXISTS idx_share_links_app_id ON share_links(app_id);
+CREATE INDEX IF NOT EXISTS idx_share_password_failures_link ON share_password_failures(share_link_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_share_password_failures_ip ON share_password_failures(ip, created_at);
+
+-- Column additions for databases created before the columns above existed
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS prod_url TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS vercel_project_id TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS schema_version INTEGER;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS icon TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS framework TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'private';
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS template TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS author_name TEXT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS share_link_id UUID REFERENCES share_links(id) ON DELETE SET NULL;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS checks_report JSONB;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS smoke_report JSONB;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS s3_output_path TEXT;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS deployment_state TEXT;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS instructions TEXT;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS commit_sha TEXT;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS s3_prod_output_path TEXT;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS prod_deploy_id TEXT;
+
 -- Cleanup function for expired tokens (optional - can be run periodically)
 CREATE OR REPLACE FUNCTION cleanup_expired_tokens()
 RETURNS void AS $$
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type AppUserHandler struct {
+	AppService     *services.AppService
+	AppUserService *services.AppUserService
+}
+
+func NewAppUserHandler(appService *services.AppService, appUserService *services.AppUserService) *AppUserHandler {
+	return &AppUserHandler{
+		AppService:     appService,
+		AppUserService: appUserService,
+	}
+}
+
+// ListUsers handles GET /apps/{appId}/users?role=&status=&search=
+func (h *AppUserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	query := r.URL.Query()
+	users, err := h.AppUserService.ListUsers(r.Context(), appID, query.Get("role"), query.Get("status"), query.Get("search"))
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, users)
+}
+
+// GetUser handles GET /apps/{appId}/users/{userId}
+func (h *AppUserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	appUser, err := h.AppUserService.GetUser(r.Context(), appID, vars["userId"])
+	if err != nil {
+		respondAppUserError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, appUser)
+}
+
+// InviteUser handles POST /apps/{appId}/users
+func (h *AppUserHandler) InviteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.InviteAppUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	appUser, inviteURL, err := h.AppUserService.InviteUser(r.Context(), app, req)
+	if err != nil {
+		respondAppUserError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"user":       appUser,
+		"invite_url": inviteURL,
+	})
+}
+
+// UpdateUser handles PATCH /apps/{appId}/users/{userId} to change roles or disable a user
+func (h *AppUserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	_, ownerEmail, err := h.AppService.GetAppWithOwnerEmail(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.UpdateAppUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	appUser, err := h.AppUserService.UpdateUser(r.Context(), appID, ownerEmail, vars["userId"], req)
+	if err != nil {
+		respondAppUserError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, appUser)
+}
+
+// ResetPassword handles POST /apps/{appId}/users/{userId}/reset-password
+func (h *AppUserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	// An empty body sends a reset link
+	var req models.ResetAppUserPasswordRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	resetURL, err := h.AppUserService.ResetPassword(r.Context(), app, vars["userId"], req.Password)
+	if err != nil {
+		respondAppUserError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"reset_url": resetURL,
+	})
+}
+
+// respondAppUserError maps app user service errors to HTTP statuses
+func respondAppUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrPasswordTooShort):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAppUserNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrAppUserExists), errors.Is(err, services.ErrOwnerAccount):
+		middleware.RespondError(w, http.StatusConflict, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
@@ -13,23 +16,26 @@ import (
 )
 
 type AppHandler struct {
-	AppService     *services.AppService
-	VersionService *services.VersionService
-	CommentService *services.CommentService
-	Builder        *worker.Builder
+	AppService      *services.AppService
+	VersionService  *services.VersionService
+	CommentService  *services.CommentService
+	TemplateService *services.TemplateService
+	Builder         *worker.Builder
 }
 
 func NewAppHandler(
 	appService *services.AppService,
 	versionService *services.VersionService,
 	commentService *services.CommentService,
+	templateService *services.TemplateService,
 	builder *worker.Builder,
 ) *AppHandler {
 	return &AppHandler{
-		AppService:     appService,
-		VersionService: versionService,
-		CommentService: commentService,
-		Builder:        builder,
+		AppService:      appService,
+		VersionService:  versionService,
+		CommentService:  commentService,
+		TemplateService: templateService,
+		Builder:         builder,
 	}
 }
 
@@ -47,6 +53,13 @@ func (h *AppHandler) CreateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Template != "" {
+		if _, err := h.TemplateService.GetTemplate(req.Template); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Unknown template: "+req.Template)
+			return
+		}
+	}
+
 	// Create app
 	app, err := h.AppService.CreateApp(r.Context(), user.Sub, req)
 	if err != nil {
@@ -86,13 +99,19 @@ func (h *AppHandler) ListApps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apps, err := h.AppService.ListApps(r.Context(), user.Sub)
+	q, err := parseListQuery(r)
 	if err != nil {
-		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	middleware.RespondJSON(w, http.StatusOK, apps)
+	apps, next, err := h.AppService.ListApps(r.Context(), user.Sub, q)
+	if err != nil {
+		respondListError(w, err)
+		return
+	}
+
+	respondList(w, apps, next)
 }
 
 // GetApp handles GET /apps/{id}
@@ -112,6 +131,53 @@ func (h *AppHandler) GetApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", appETag(app))
+	middleware.RespondJSON(w, http.StatusOK, app)
+}
+
+// UpdateApp handles PATCH /apps/{id}. Send the ETag from GET /apps/{id} in
+// If-Match to reject the edit if the app changed in the meantime.
+func (h *AppHandler) UpdateApp(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["id"]
+
+	var ifRevision *int
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		revision, ok := parseAppETag(ifMatch)
+		if !ok {
+			middleware.RespondError(w, http.StatusPreconditionFailed, services.ErrAppModified.Error())
+			return
+		}
+		ifRevision = &revision
+	}
+
+	var req models.UpdateAppRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	app, err := h.AppService.EditApp(r.Context(), appID, user.Sub, req, ifRevision)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidApp):
+			middleware.RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrAppNotFound):
+			middleware.RespondError(w, http.StatusNotFound, "App not found")
+		case errors.Is(err, services.ErrAppModified):
+			middleware.RespondError(w, http.StatusPreconditionFailed, err.Error())
+		default:
+			middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("ETag", appETag(app))
 	middleware.RespondJSON(w, http.StatusOK, app)
 }
 
@@ -133,3 +199,27 @@ func (h *AppHandler) DeleteApp(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListTemplates handles GET /templates
+func (h *AppHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	middleware.RespondJSON(w, http.StatusOK, h.TemplateService.ListTemplates())
+}
+
+// appETag identifies a revision of an app's user-editable details. It is not
+// the update time, which builds change while the user may be editing.
+func appETag(app *models.App) string {
+	return `"r` + strconv.Itoa(app.Revision) + `"`
+}
+
+// parseAppETag reads the revision back from an ETag made by appETag
+func parseAppETag(etag string) (int, bool) {
+	etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+	if !strings.HasPrefix(etag, "r") {
+		return 0, false
+	}
+	revision, err := strconv.Atoi(etag[1:])
+	if err != nil || revision < 1 {
+		return 0, false
+	}
+	return revision, true
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+func TestParseAppETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		etag   string
+		want   int
+		wantOK bool
+	}{
+		{"strong", `"r7"`, 7, true},
+		{"weak", `W/"r7"`, 7, true},
+		{"surrounding space", ` "r12" `, 12, true},
+		{"unquoted", `r3`, 3, true},
+		{"update time from an old ETag", `"1760000000000000"`, 0, false},
+		{"zero revision", `"r0"`, 0, false},
+		{"negative revision", `"r-1"`, 0, false},
+		{"not a number", `"rabc"`, 0, false},
+		{"empty", `""`, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseAppETag(tt.etag)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseAppETag(%q) = %d, %v, want %d, %v", tt.etag, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAppETagRoundTrip(t *testing.T) {
+	app := &models.App{Revision: 42}
+	if got, ok := parseAppETag(appETag(app)); !ok || got != 42 {
+		t.Errorf("parseAppETag(appETag()) = %d, %v, want 42, true", got, ok)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+	"github.com/rapidbuildapp/rapidbuild/internal/worker"
+)
+
+type CloneHandler struct {
+	AppService   *services.AppService
+	CloneService *services.CloneService
+	Builder      *worker.Builder
+}
+
+func NewCloneHandler(appService *services.AppService, cloneService *services.CloneService, builder *worker.Builder) *CloneHandler {
+	return &CloneHandler{
+		AppService:   appService,
+		CloneService: cloneService,
+		Builder:      builder,
+	}
+}
+
+// CloneApp handles POST /apps/{id}/clone. The new app's first version is built
+// from the chosen version's code without running the agent.
+func (h *CloneHandler) CloneApp(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["id"]
+
+	// Verify user owns the app
+	source, ownerEmail, err := h.AppService.GetAppWithOwnerEmail(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.CloneAppRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	app, version, snapshot, err := h.CloneService.Clone(r.Context(), source, user.Sub, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidApp):
+			middleware.RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrVersionNotFound):
+			middleware.RespondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrNoCodeSnapshot):
+			middleware.RespondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrSecretsDisabled):
+			middleware.RespondError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// Build in the background with a new context (not the request context)
+	go h.Builder.BuildFromSnapshot(context.Background(), version.ID, app.ID, source.ID, *snapshot.S3CodePath, ownerEmail)
+
+	middleware.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"app":               app,
+		"version":           version,
+		"source_version_id": snapshot.ID,
+	})
+}
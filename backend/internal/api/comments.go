@@ -60,14 +60,20 @@ func (h *AppHandler) ListComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get draft comments
-	comments, err := h.CommentService.GetDraftComments(r.Context(), appID, user.Sub)
+	q, err := parseListQuery(r)
 	if err != nil {
-		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Draft comments unless filtered by status
+	comments, next, err := h.CommentService.ListComments(r.Context(), appID, user.Sub, q)
+	if err != nil {
+		respondListError(w, err)
 		return
 	}
 
-	middleware.RespondJSON(w, http.StatusOK, comments)
+	respondList(w, comments, next)
 }
 
 // GetVersionComments handles GET /apps/{appId}/versions/{versionId}/comments
@@ -89,13 +95,19 @@ func (h *AppHandler) GetVersionComments(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	comments, err := h.CommentService.GetVersionComments(r.Context(), versionID)
+	q, err := parseListQuery(r)
 	if err != nil {
-		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comments, next, err := h.CommentService.ListVersionComments(r.Context(), appID, versionID, q)
+	if err != nil {
+		respondListError(w, err)
 		return
 	}
 
-	middleware.RespondJSON(w, http.StatusOK, comments)
+	respondList(w, comments, next)
 }
 
 // DeleteComment handles DELETE /apps/{appId}/comments/{commentId}
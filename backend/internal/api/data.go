@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// maxDocumentBody caps the size of a document sent to the data browser
+const maxDocumentBody = 1 << 20
+
+type DataHandler struct {
+	AppService  *services.AppService
+	DataService *services.DataService
+}
+
+func NewDataHandler(appService *services.AppService, dataService *services.DataService) *DataHandler {
+	return &DataHandler{
+		AppService:  appService,
+		DataService: dataService,
+	}
+}
+
+// ListCollections handles GET /apps/{appId}/data/collections
+func (h *DataHandler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	appID, _, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	collections, err := h.DataService.ListCollections(r.Context(), appID)
+	if err != nil {
+		respondDataError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, collections)
+}
+
+// FindDocuments handles GET /apps/{appId}/data/collections/{collection}/documents
+// Query parameters: filter (extended JSON), sort (e.g. -createdAt,name), limit, skip.
+func (h *DataHandler) FindDocuments(w http.ResponseWriter, r *http.Request) {
+	appID, _, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.DocumentQuery{
+		Filter: params.Get("filter"),
+		Sort:   params.Get("sort"),
+	}
+	query.Limit, _ = strconv.ParseInt(params.Get("limit"), 10, 64)
+	query.Skip, _ = strconv.ParseInt(params.Get("skip"), 10, 64)
+
+	page, err := h.DataService.FindDocuments(r.Context(), appID, mux.Vars(r)["collection"], query)
+	if err != nil {
+		respondDataError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, page)
+}
+
+// GetDocument handles GET /apps/{appId}/data/collections/{collection}/documents/{documentId}
+func (h *DataHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	appID, _, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	doc, err := h.DataService.GetDocument(r.Context(), appID, vars["collection"], vars["documentId"])
+	if err != nil {
+		respondDataError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, doc)
+}
+
+// InsertDocument handles POST /apps/{appId}/data/collections/{collection}/documents
+func (h *DataHandler) InsertDocument(w http.ResponseWriter, r *http.Request) {
+	appID, userID, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDocumentBody))
+	if err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	doc, err := h.DataService.InsertDocument(r.Context(), appID, userID, mux.Vars(r)["collection"], body)
+	if err != nil {
+		respondDataError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusCreated, doc)
+}
+
+// UpdateDocument handles PATCH /apps/{appId}/data/collections/{collection}/documents/{documentId}
+// The body holds the top-level fields to set.
+func (h *DataHandler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	appID, userID, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDocumentBody))
+	if err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	doc, err := h.DataService.UpdateDocument(r.Context(), appID, userID, vars["collection"], vars["documentId"], body)
+	if err != nil {
+		respondDataError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, doc)
+}
+
+// DeleteDocument handles DELETE /apps/{appId}/data/collections/{collection}/documents/{documentId}
+func (h *DataHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	appID, userID, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.DataService.DeleteDocument(r.Context(), appID, userID, vars["collection"], vars["documentId"]); err != nil {
+		respondDataError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAuditLog handles GET /apps/{appId}/data/audit?limit=
+func (h *DataHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	appID, _, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	entries, err := h.DataService.ListAuditLog(r.Context(), appID, limit)
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, entries)
+}
+
+// authorize verifies the user owns the app in the path, responding with an
+// error and returning false otherwise
+func (h *DataHandler) authorize(w http.ResponseWriter, r *http.Request) (appID, userID string, ok bool) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return "", "", false
+	}
+
+	appID = mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return "", "", false
+	}
+
+	return appID, user.Sub, true
+}
+
+// respondDataError maps data service errors to HTTP statuses
+func respondDataError(w http.ResponseWriter, err error) {
+	var validationErr *services.DocumentValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		middleware.RespondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":    "Document does not match the collection schema",
+			"problems": validationErr.Problems,
+		})
+	case errors.Is(err, services.ErrInvalidFilter), errors.Is(err, services.ErrInvalidDocument):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAppDatabaseMissing):
+		middleware.RespondError(w, http.StatusNotFound, "App has no database yet")
+	case errors.Is(err, services.ErrCollectionNotFound), errors.Is(err, services.ErrDocumentNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type DomainHandler struct {
+	AppService    *services.AppService
+	DomainService *services.DomainService
+}
+
+func NewDomainHandler(appService *services.AppService, domainService *services.DomainService) *DomainHandler {
+	return &DomainHandler{
+		AppService:    appService,
+		DomainService: domainService,
+	}
+}
+
+// ListDomains handles GET /apps/{appId}/domains
+func (h *DomainHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	_, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	domains, err := h.DomainService.ListDomains(r.Context(), appID)
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, domains)
+}
+
+// AddDomain handles POST /apps/{appId}/domains
+func (h *DomainHandler) AddDomain(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.AddDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	domain, err := h.DomainService.AddDomain(r.Context(), app, req.Domain)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusCreated, domain)
+}
+
+// VerifyDomain handles POST /apps/{appId}/domains/{domainId}/verify
+func (h *DomainHandler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+	domainID := vars["domainId"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	domain, err := h.DomainService.VerifyDomain(r.Context(), app, domainID)
+	if err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, domain)
+}
+
+// RemoveDomain handles DELETE /apps/{appId}/domains/{domainId}
+func (h *DomainHandler) RemoveDomain(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+	domainID := vars["domainId"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	if err := h.DomainService.RemoveDomain(r.Context(), app, domainID); err != nil {
+		respondDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondDomainError maps domain service errors to HTTP statuses
+func respondDomainError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidDomain):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrDomainTaken), errors.Is(err, services.ErrNoVercelProject):
+		middleware.RespondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrDomainNotFound):
+		middleware.RespondError(w, http.StatusNotFound, "Domain not found")
+	default:
+		middleware.RespondError(w, http.StatusBadGateway, err.Error())
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type GitExportHandler struct {
+	AppService       *services.AppService
+	VersionService   *services.VersionService
+	GitExportService *services.GitExportService
+}
+
+func NewGitExportHandler(appService *services.AppService, versionService *services.VersionService, gitExportService *services.GitExportService) *GitExportHandler {
+	return &GitExportHandler{
+		AppService:       appService,
+		VersionService:   versionService,
+		GitExportService: gitExportService,
+	}
+}
+
+// GetGitExport handles GET /apps/{appId}/git-export
+func (h *GitExportHandler) GetGitExport(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.GitExportService.GetGitExport(r.Context(), appID)
+	if err != nil {
+		respondGitExportError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, export)
+}
+
+// SetGitExport handles PUT /apps/{appId}/git-export
+func (h *GitExportHandler) SetGitExport(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SetGitExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	export, err := h.GitExportService.SetGitExport(r.Context(), appID, req)
+	if err != nil {
+		respondGitExportError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, export)
+}
+
+// DeleteGitExport handles DELETE /apps/{appId}/git-export
+func (h *GitExportHandler) DeleteGitExport(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.GitExportService.DeleteGitExport(r.Context(), appID); err != nil {
+		respondGitExportError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PushGitExport handles POST /apps/{appId}/git-export/push, queueing a push of
+// a version (the latest completed one by default) even if the export is disabled
+func (h *GitExportHandler) PushGitExport(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PushGitExportRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if _, err := h.GitExportService.GetGitExport(r.Context(), appID); err != nil {
+		respondGitExportError(w, err)
+		return
+	}
+
+	var version *models.Version
+	if req.VersionID != "" {
+		v, err := h.VersionService.GetVersion(r.Context(), req.VersionID)
+		if err != nil || v.AppID != appID {
+			middleware.RespondError(w, http.StatusNotFound, "Version not found")
+			return
+		}
+		version = v
+	} else {
+		versions, err := h.VersionService.ListVersions(r.Context(), appID)
+		if err != nil {
+			middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// Versions are listed newest first
+		for i := range versions {
+			if versions[i].Status == "completed" || versions[i].Status == "promoted" {
+				version = &versions[i]
+				break
+			}
+		}
+	}
+	if version == nil || version.S3CodePath == nil || (version.Status != "completed" && version.Status != "promoted") {
+		middleware.RespondError(w, http.StatusConflict, "Only completed versions can be pushed")
+		return
+	}
+
+	event := services.GitExportEventCompleted
+	if version.Status == "promoted" {
+		event = services.GitExportEventPromoted
+	}
+	if err := h.GitExportService.QueuePush(r.Context(), appID, version.ID, event); err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusAccepted, map[string]string{
+		"status":     "queued",
+		"version_id": version.ID,
+	})
+}
+
+// ownedApp returns the {appId} of the request if the user owns it, otherwise
+// it responds with an error
+func (h *GitExportHandler) ownedApp(w http.ResponseWriter, r *http.Request) (string, bool) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return "", false
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return "", false
+	}
+	return appID, true
+}
+
+// respondGitExportError maps git export errors to HTTP statuses
+func respondGitExportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGitExport):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrGitExportNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrSecretsDisabled):
+		middleware.RespondError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+	"github.com/rapidbuildapp/rapidbuild/internal/worker"
+)
+
+type HistoryHandler struct {
+	AppService     *services.AppService
+	VersionService *services.VersionService
+	Builder        *worker.Builder
+}
+
+func NewHistoryHandler(appService *services.AppService, versionService *services.VersionService, builder *worker.Builder) *HistoryHandler {
+	return &HistoryHandler{
+		AppService:     appService,
+		VersionService: versionService,
+		Builder:        builder,
+	}
+}
+
+// GetHistory handles GET /apps/{appId}/history?limit=50&path=src/App.tsx,
+// listing the app's commits newest first
+func (h *HistoryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	commits, err := h.Builder.History.Log(r.Context(), appID, r.URL.Query().Get("path"), limit)
+	if err != nil {
+		respondHistoryError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, commits)
+}
+
+// GetHistoryDiff handles GET /apps/{appId}/history/diff?from=sha&to=sha&path=...
+// By default it compares the latest commit with its parent.
+func (h *HistoryHandler) GetHistoryDiff(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	diff, err := h.Builder.History.Diff(r.Context(), appID, query.Get("from"), query.Get("to"), query.Get("path"))
+	if err != nil {
+		respondHistoryError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, diff)
+}
+
+// GetHistoryBlame handles GET /apps/{appId}/history/blame?path=...&commit=sha
+func (h *HistoryHandler) GetHistoryBlame(w http.ResponseWriter, r *http.Request) {
+	appID, ok := h.ownedApp(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	lines, err := h.Builder.History.Blame(r.Context(), appID, query.Get("commit"), query.Get("path"))
+	if err != nil {
+		respondHistoryError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, lines)
+}
+
+// RestoreCommit handles POST /apps/{appId}/history/restore. A new version is
+// built from the commit's code without running the agent.
+func (h *HistoryHandler) RestoreCommit(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	_, ownerEmail, err := h.AppService.GetAppWithOwnerEmail(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.RestoreCommitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Commit) == "" {
+		middleware.RespondError(w, http.StatusBadRequest, "commit is required")
+		return
+	}
+
+	commit, err := h.Builder.History.ResolveCommit(r.Context(), appID, strings.TrimSpace(req.Commit))
+	if err != nil {
+		respondHistoryError(w, err)
+		return
+	}
+
+	version, err := h.VersionService.CreateVersion(r.Context(), appID)
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Build in the background with a new context (not the request context)
+	go h.Builder.RestoreCommit(context.Background(), version.ID, appID, commit, ownerEmail)
+
+	middleware.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"version": version,
+		"commit":  commit,
+	})
+}
+
+// ownedApp returns the {appId} of the request if the user owns it, otherwise
+// it responds with an error
+func (h *HistoryHandler) ownedApp(w http.ResponseWriter, r *http.Request) (string, bool) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return "", false
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return "", false
+	}
+	return appID, true
+}
+
+// respondHistoryError maps history errors to HTTP statuses
+func respondHistoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, worker.ErrInvalidHistoryRef):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, worker.ErrHistoryNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+	"github.com/rapidbuildapp/rapidbuild/internal/worker"
+)
+
+type ImportHandler struct {
+	AppService     *services.AppService
+	VersionService *services.VersionService
+	Builder        *worker.Builder
+}
+
+func NewImportHandler(appService *services.AppService, versionService *services.VersionService, builder *worker.Builder) *ImportHandler {
+	return &ImportHandler{
+		AppService:     appService,
+		VersionService: versionService,
+		Builder:        builder,
+	}
+}
+
+// ImportApp handles POST /apps/import. The multipart form has the app's name
+// and description and either a "file" (a .zip or .tar.gz of the project) or a
+// "git_path" to a local repository. The code becomes version 1 without running
+// the agent; later versions iterate on it.
+func (h *ImportHandler) ImportApp(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	maxBytes := h.Builder.Config.ImportMaxMB << 20
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			middleware.RespondError(w, http.StatusRequestEntityTooLarge, "Upload is too large")
+			return
+		}
+		middleware.RespondError(w, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	req := models.CreateAppRequest{
+		Name:        strings.TrimSpace(r.FormValue("name")),
+		Description: r.FormValue("description"),
+	}
+	gitPath := strings.TrimSpace(r.FormValue("git_path"))
+
+	var stageDir string
+	file, header, err := r.FormFile("file")
+	switch {
+	case err == nil:
+		defer file.Close()
+		if req.Name == "" {
+			req.Name = importName(header.Filename)
+		}
+		stageDir, err = h.Builder.StageImportArchive(file, header.Size)
+	case gitPath != "":
+		if req.Name == "" {
+			req.Name = importName(gitPath)
+		}
+		stageDir, err = h.Builder.StageImportGit(r.Context(), gitPath)
+	default:
+		middleware.RespondError(w, http.StatusBadRequest, "Provide a file or a git_path to import")
+		return
+	}
+	if err != nil {
+		if errors.Is(err, worker.ErrInvalidImport) {
+			middleware.RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.RespondError(w, http.StatusInternalServerError, "Failed to import code")
+		return
+	}
+
+	app, err := h.AppService.CreateApp(r.Context(), user.Sub, req)
+	if err != nil {
+		h.Builder.DiscardImport(stageDir)
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ownerEmail, err := h.AppService.GetOwnerEmail(r.Context(), user.Sub)
+	if err != nil {
+		h.Builder.DiscardImport(stageDir)
+		middleware.RespondError(w, http.StatusInternalServerError, "Failed to get owner email")
+		return
+	}
+
+	version, err := h.VersionService.CreateVersion(r.Context(), app.ID)
+	if err != nil {
+		h.Builder.DiscardImport(stageDir)
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Build in the background with a new context (not the request context)
+	go h.Builder.BuildImport(context.Background(), version.ID, app.ID, stageDir, ownerEmail)
+
+	middleware.RespondJSON(w, http.StatusCreated, map[string]interface{}{
+		"app":     app,
+		"version": version,
+	})
+}
+
+// importName derives an app name from an uploaded file name or repository path
+func importName(path string) string {
+	path = strings.TrimRight(strings.ReplaceAll(path, "\\", "/"), "/")
+	name := path[strings.LastIndex(path, "/")+1:]
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip", ".git"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if name == "" {
+		return "Imported app"
+	}
+	return name
+}
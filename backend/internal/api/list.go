@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// nextCursorHeader carries the cursor of the next page of a list response, so
+// list bodies stay plain JSON arrays
+const nextCursorHeader = "X-Next-Cursor"
+
+// parseListQuery reads the list query parameters shared by list endpoints:
+// limit, cursor, sort (e.g. -created_at), status (comma separated), since and
+// until (RFC 3339 or YYYY-MM-DD), search and view=summary.
+func parseListQuery(r *http.Request) (models.ListQuery, error) {
+	params := r.URL.Query()
+	q := models.ListQuery{
+		Cursor:  params.Get("cursor"),
+		Sort:    params.Get("sort"),
+		Search:  strings.TrimSpace(params.Get("search")),
+		Summary: params.Get("view") == "summary",
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, fmt.Errorf("invalid limit %q", limit)
+		}
+		q.Limit = n
+	}
+
+	if status := params.Get("status"); status != "" {
+		q.Status = strings.Split(status, ",")
+	}
+
+	var err error
+	if q.Since, err = parseTimeParam(params, "since"); err != nil {
+		return q, err
+	}
+	if q.Until, err = parseTimeParam(params, "until"); err != nil {
+		return q, err
+	}
+	if q.Until != nil && len(params.Get("until")) == len(time.DateOnly) {
+		// A bare date includes the whole day
+		until := q.Until.AddDate(0, 0, 1)
+		q.Until = &until
+	}
+
+	return q, nil
+}
+
+func parseTimeParam(params url.Values, name string) (*time.Time, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q, expected RFC 3339 or YYYY-MM-DD", name, value)
+		}
+	}
+	return &t, nil
+}
+
+// respondList writes a page of a list along with the next page's cursor
+func respondList(w http.ResponseWriter, items interface{}, nextCursor string) {
+	if nextCursor != "" {
+		w.Header().Set(nextCursorHeader, nextCursor)
+	}
+	middleware.RespondJSON(w, http.StatusOK, items)
+}
+
+// respondListError maps list query errors to HTTP statuses
+func respondListError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidListQuery) {
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+}
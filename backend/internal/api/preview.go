@@ -1,36 +1,34 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
 	"github.com/rapidbuildapp/rapidbuild/internal/services"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type PreviewHandler struct {
 	AppService     *services.AppService
-	VersionService *services.VersionService
-	MongoClient    *mongo.Client
+	PreviewService *services.PreviewService
 }
 
 func NewPreviewHandler(
 	appService *services.AppService,
-	versionService *services.VersionService,
-	mongoClient *mongo.Client,
+	previewService *services.PreviewService,
 ) *PreviewHandler {
 	return &PreviewHandler{
 		AppService:     appService,
-		VersionService: versionService,
-		MongoClient:    mongoClient,
+		PreviewService: previewService,
 	}
 }
 
-// GeneratePreviewToken generates a JWT for the owner to preview their app
+// GeneratePreviewToken generates a JWT for the owner to preview their app.
+// The optional body picks the version, a role or end user to preview as, and
+// the token lifetime; by default it is the latest version as admin for 5 minutes.
 func (h *PreviewHandler) GeneratePreviewToken(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
@@ -43,90 +41,79 @@ func (h *PreviewHandler) GeneratePreviewToken(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// 1. Verify ownership and get owner email from PostgreSQL
-	_, ownerEmail, err := h.AppService.GetAppWithOwnerEmail(ctx, appID, user.Sub)
+	// Verify ownership and get owner email from PostgreSQL
+	app, ownerEmail, err := h.AppService.GetAppWithOwnerEmail(ctx, appID, user.Sub)
 	if err != nil {
 		middleware.RespondError(w, http.StatusForbidden, "App not found or unauthorized")
 		return
 	}
 
-	// 2. Get MongoDB app configuration (for JWT secret)
-	mongoDb := h.MongoClient.Database("system_db")
-	appsCol := mongoDb.Collection("apps")
-
-	var mongoApp struct {
-		ID           string `bson:"_id"`
-		DatabaseName string `bson:"databaseName"`
-		JWT          struct {
-			Secret string `bson:"secret"`
-		} `bson:"jwt"`
+	var req models.PreviewTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
 	}
 
-	err = appsCol.FindOne(ctx, bson.M{"_id": appID}).Decode(&mongoApp)
+	token, err := h.PreviewService.IssueToken(ctx, app, ownerEmail, req)
 	if err != nil {
-		middleware.RespondError(w, http.StatusNotFound, "App configuration not found in MongoDB")
+		respondPreviewError(w, err)
 		return
 	}
 
-	// 3. Get admin user from app_users
-	appUsersCol := mongoDb.Collection("app_users")
+	middleware.RespondJSON(w, http.StatusOK, token)
+}
 
-	var adminUser struct {
-		ID    string   `bson:"_id"`
-		Email string   `bson:"email"`
-		Roles []string `bson:"roles"`
-	}
+// RefreshPreviewToken handles POST /apps/{id}/preview-token/refresh, exchanging a
+// current or just expired preview token for a new one with the same settings
+func (h *PreviewHandler) RefreshPreviewToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	appID := mux.Vars(r)["id"]
 
-	err = appUsersCol.FindOne(ctx, bson.M{
-		"appId": appID,
-		"email": ownerEmail,
-	}).Decode(&adminUser)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
 
+	app, err := h.AppService.GetApp(ctx, appID, user.Sub)
 	if err != nil {
-		middleware.RespondError(w, http.StatusNotFound, "Admin user not found. App may need to be rebuilt.")
+		middleware.RespondError(w, http.StatusForbidden, "App not found or unauthorized")
 		return
 	}
 
-	// 4. Generate JWT using app's secret
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":    adminUser.ID,
-		"userId": adminUser.ID,
-		"email":  adminUser.Email,
-		"appId":  appID,
-		"roles":  adminUser.Roles,
-		"exp":    time.Now().Add(5 * time.Minute).Unix(),
-		"iat":    time.Now().Unix(),
-	})
-
-	signedToken, err := token.SignedString([]byte(mongoApp.JWT.Secret))
-	if err != nil {
-		middleware.RespondError(w, http.StatusInternalServerError, "Failed to generate token")
+	var req models.RefreshPreviewTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// 5. Get latest completed version URL
-	versions, err := h.VersionService.ListVersions(ctx, appID)
+	token, err := h.PreviewService.RefreshToken(ctx, app, req.Token)
 	if err != nil {
-		middleware.RespondError(w, http.StatusInternalServerError, "Failed to get versions")
+		respondPreviewError(w, err)
 		return
 	}
 
-	var latestURL *string
-	for _, v := range versions {
-		if v.Status == "completed" && v.VercelURL != nil {
-			latestURL = v.VercelURL
-			break
-		}
-	}
+	middleware.RespondJSON(w, http.StatusOK, token)
+}
 
-	if latestURL == nil {
-		middleware.RespondError(w, http.StatusNotFound, "No deployed version found")
-		return
+// respondPreviewError maps preview service errors to HTTP statuses
+func respondPreviewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPreviewTTL), errors.Is(err, services.ErrInvalidRole):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvalidPreviewToken):
+		middleware.RespondError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrPreviewUserDisabled):
+		middleware.RespondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrAppDatabaseMissing):
+		middleware.RespondError(w, http.StatusNotFound, "App configuration not found in MongoDB")
+	case errors.Is(err, services.ErrAppUserNotFound):
+		middleware.RespondError(w, http.StatusNotFound, "User not found. App may need to be rebuilt.")
+	case errors.Is(err, services.ErrVersionNotFound), errors.Is(err, services.ErrVersionNotDeployed), errors.Is(err, services.ErrNoProductionVersion):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
 	}
-
-	// 6. Return token + preview URL
-	middleware.RespondJSON(w, http.StatusOK, map[string]string{
-		"token":      signedToken,
-		"previewUrl": *latestURL,
-	})
 }
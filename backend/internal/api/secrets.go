@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type SecretHandler struct {
+	AppService    *services.AppService
+	SecretService *services.SecretService
+}
+
+func NewSecretHandler(appService *services.AppService, secretService *services.SecretService) *SecretHandler {
+	return &SecretHandler{
+		AppService:    appService,
+		SecretService: secretService,
+	}
+}
+
+// ListSecrets handles GET /apps/{appId}/secrets (keys and scopes only, never values)
+func (h *SecretHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	_, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	secrets, err := h.SecretService.ListSecrets(r.Context(), appID)
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, secrets)
+}
+
+// SetSecret handles PUT /apps/{appId}/secrets/{key}
+func (h *SecretHandler) SetSecret(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+	key := vars["key"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.SetSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	secret, err := h.SecretService.SetSecret(r.Context(), app, key, req)
+	if err != nil {
+		respondSecretError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, secret)
+}
+
+// DeleteSecret handles DELETE /apps/{appId}/secrets/{key}?scope=preview|production
+// Without a scope the secret is removed from both scopes.
+func (h *SecretHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+	key := vars["key"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	if err := h.SecretService.DeleteSecret(r.Context(), app, key, r.URL.Query().Get("scope")); err != nil {
+		respondSecretError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondSecretError maps secret service errors to HTTP statuses
+func respondSecretError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSecretKey), errors.Is(err, services.ErrInvalidSecretScope), errors.Is(err, services.ErrSecretTooLarge):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSecretNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrSecretsDisabled):
+		middleware.RespondError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
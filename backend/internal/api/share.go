@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type ShareHandler struct {
+	AppService   *services.AppService
+	ShareService *services.ShareService
+}
+
+func NewShareHandler(appService *services.AppService, shareService *services.ShareService) *ShareHandler {
+	return &ShareHandler{
+		AppService:   appService,
+		ShareService: shareService,
+	}
+}
+
+// ListShareLinks handles GET /apps/{appId}/share-links
+func (h *ShareHandler) ListShareLinks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	links, err := h.ShareService.ListShareLinks(r.Context(), appID)
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, links)
+}
+
+// CreateShareLink handles POST /apps/{appId}/share-links
+func (h *ShareHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	app, err := h.AppService.GetApp(r.Context(), appID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	link, err := h.ShareService.CreateShareLink(r.Context(), app, user.Sub, req)
+	if err != nil {
+		respondShareError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusCreated, link)
+}
+
+// RevokeShareLink handles DELETE /apps/{appId}/share-links/{linkId}
+func (h *ShareHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	appID := vars["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	if err := h.ShareService.RevokeShareLink(r.Context(), appID, vars["linkId"]); err != nil {
+		respondShareError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Share link revoked",
+	})
+}
+
+// GetShareLink handles GET /share/{slug} (public). It doesn't count as a view.
+func (h *ShareHandler) GetShareLink(w http.ResponseWriter, r *http.Request) {
+	info, err := h.ShareService.GetInfo(r.Context(), mux.Vars(r)["slug"])
+	if err != nil {
+		respondShareError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, info)
+}
+
+// OpenShareLink handles POST /share/{slug}/open (public), returning the deployment URL
+func (h *ShareHandler) OpenShareLink(w http.ResponseWriter, r *http.Request) {
+	// Links without a password can be opened with an empty body
+	var req models.OpenShareLinkRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	target, err := h.ShareService.Open(r.Context(), mux.Vars(r)["slug"], req.Password, h.clientIP(r))
+	if err != nil {
+		respondShareError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, target)
+}
+
+// AddShareComment handles POST /share/{slug}/comments (public)
+func (h *ShareHandler) AddShareComment(w http.ResponseWriter, r *http.Request) {
+	var req models.AddShareCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	comment, err := h.ShareService.AddComment(r.Context(), mux.Vars(r)["slug"], req, h.clientIP(r))
+	if err != nil {
+		respondShareError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusCreated, comment)
+}
+
+// RedirectShareLink handles GET /s/{slug} (public). Password protected links
+// redirect to the frontend's share page, which asks for the password.
+func (h *ShareHandler) RedirectShareLink(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	target, err := h.ShareService.Open(r.Context(), slug, "", h.clientIP(r))
+	if errors.Is(err, services.ErrSharePasswordRequired) {
+		http.Redirect(w, r, h.ShareService.PageURL(slug), http.StatusFound)
+		return
+	}
+	if err != nil {
+		respondShareError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, target.URL, http.StatusFound)
+}
+
+// clientIP is the visitor's address, from X-Forwarded-For when the server is
+// configured to trust it
+func (h *ShareHandler) clientIP(r *http.Request) string {
+	if h.ShareService.Config.TrustProxyHeaders {
+		forwarded := strings.Join(r.Header.Values("X-Forwarded-For"), ",")
+		if ip := forwardedClientIP(forwarded, h.ShareService.Config.TrustedProxyHops); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedClientIP picks the client address from an X-Forwarded-For value.
+// Clients can send the header themselves, so only the entries appended by the
+// trusted proxies count: the client is the hops-th entry from the right.
+func forwardedClientIP(forwarded string, hops int) string {
+	var entries []string
+	for _, entry := range strings.Split(forwarded, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	if hops < 1 {
+		hops = 1
+	}
+	if hops > len(entries) {
+		hops = len(entries)
+	}
+	return entries[len(entries)-hops]
+}
+
+// respondShareError maps share service errors to HTTP statuses
+func respondShareError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidShareLink), errors.Is(err, services.ErrInvalidShareComment):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSharePasswordRequired), errors.Is(err, services.ErrSharePasswordInvalid):
+		middleware.RespondError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrShareCommentsDisabled):
+		middleware.RespondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrShareLinkNotFound), errors.Is(err, services.ErrVersionNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrShareLinkExpired):
+		middleware.RespondError(w, http.StatusGone, err.Error())
+	case errors.Is(err, services.ErrShareRateLimited):
+		middleware.RespondError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrShareDisabled):
+		middleware.RespondError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, services.ErrVersionNotDeployed), errors.Is(err, services.ErrNoProductionVersion):
+		middleware.RespondError(w, http.StatusConflict, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// shareSessionCookie holds the share session token on the share host
+const shareSessionCookie = "rapidbuild_share"
+
+// ShareProxyHandler serves opened share links on the share host. Visiting
+// /_share/{token} stores the session in a cookie; every other request is
+// proxied to the shared deployment while the session and link are valid.
+type ShareProxyHandler struct {
+	ShareService *services.ShareService
+}
+
+func NewShareProxyHandler(shareService *services.ShareService) *ShareProxyHandler {
+	return &ShareProxyHandler{ShareService: shareService}
+}
+
+func (h *ShareProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if token, ok := strings.CutPrefix(r.URL.Path, services.ShareSessionPath); ok {
+		h.startSession(w, r, token)
+		return
+	}
+
+	cookie, err := r.Cookie(shareSessionCookie)
+	if err != nil {
+		http.Error(w, "This share link has not been opened or its session has ended", http.StatusUnauthorized)
+		return
+	}
+	target, _, err := h.ShareService.ResolveSession(r.Context(), cookie.Value)
+	if err != nil {
+		respondShareProxyError(w, err)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.Host = target.Host
+			// The session is only for the share host
+			pr.Out.Header.Del("Cookie")
+			pr.Out.Header.Del("Authorization")
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// startSession checks a token from ShareService.Open, keeps it in a cookie
+// and redirects to the deployment's root
+func (h *ShareProxyHandler) startSession(w http.ResponseWriter, r *http.Request, token string) {
+	_, expiresAt, err := h.ShareService.ResolveSession(r.Context(), token)
+	if err != nil {
+		respondShareProxyError(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     shareSessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.ShareService.Config.ShareBaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// respondShareProxyError answers with plain text, as share host responses are
+// shown to visitors in the browser
+func respondShareProxyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidShareSession):
+		http.Error(w, "This share session has ended, open the share link again", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrShareLinkNotFound), errors.Is(err, services.ErrVersionNotFound):
+		http.Error(w, "Share link not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrShareLinkExpired):
+		http.Error(w, "This share link has expired", http.StatusGone)
+	case errors.Is(err, services.ErrVersionNotDeployed):
+		http.Error(w, "This version is not deployed", http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import "testing"
+
+func TestForwardedClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded string
+		hops      int
+		want      string
+	}{
+		{"single proxy", "203.0.113.7", 1, "203.0.113.7"},
+		{"spoofed entry before the proxy's", "1.2.3.4, 203.0.113.7", 1, "203.0.113.7"},
+		{"two proxies", "1.2.3.4, 203.0.113.7, 10.0.0.2", 2, "203.0.113.7"},
+		{"fewer entries than hops", "203.0.113.7", 3, "203.0.113.7"},
+		{"empty entries", "203.0.113.7, ,", 1, "203.0.113.7"},
+		{"zero hops", "1.2.3.4, 203.0.113.7", 0, "203.0.113.7"},
+		{"no header", "", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := forwardedClientIP(tt.forwarded, tt.hops); got != tt.want {
+				t.Errorf("forwardedClientIP(%q, %d) = %q, want %q", tt.forwarded, tt.hops, got, tt.want)
+			}
+		})
+	}
+}
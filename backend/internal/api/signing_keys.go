@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type SigningKeyHandler struct {
+	AppService         *services.AppService
+	AppDatabaseService *services.AppDatabaseService
+}
+
+func NewSigningKeyHandler(appService *services.AppService, appDatabaseService *services.AppDatabaseService) *SigningKeyHandler {
+	return &SigningKeyHandler{
+		AppService:         appService,
+		AppDatabaseService: appDatabaseService,
+	}
+}
+
+// ListSigningKeys handles GET /apps/{appId}/signing-keys
+func (h *SigningKeyHandler) ListSigningKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	keys, err := h.AppDatabaseService.SigningKeys(r.Context(), appID)
+	if err != nil {
+		respondSigningKeyError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, keys)
+}
+
+// RotateSigningKey handles POST /apps/{appId}/signing-keys/rotate
+func (h *SigningKeyHandler) RotateSigningKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	appID := mux.Vars(r)["appId"]
+
+	// Verify user owns the app
+	if _, err := h.AppService.GetApp(r.Context(), appID, user.Sub); err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	var req models.RotateSigningKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	keys, err := h.AppDatabaseService.RotateSigningKey(r.Context(), appID, req)
+	if err != nil {
+		respondSigningKeyError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, keys)
+}
+
+// respondSigningKeyError maps signing key errors to HTTP statuses
+func respondSigningKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGracePeriod):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAppDatabaseMissing):
+		middleware.RespondError(w, http.StatusNotFound, "App has no database yet")
+	case errors.Is(err, services.ErrRotationConflict):
+		middleware.RespondError(w, http.StatusConflict, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -50,8 +50,8 @@ func (h *AppHandler) SSEHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if version is already completed/failed
-	if version.Status == "completed" || version.Status == "failed" {
+	// Check if version is already completed/failed/unhealthy
+	if version.Status == "completed" || version.Status == "failed" || version.Status == "unhealthy" {
 		data, _ := json.Marshal(map[string]string{
 			"version_id": versionID,
 			"status":     version.Status,
@@ -136,8 +136,8 @@ func (h *AppHandler) SSEHandler(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprintf(w, "data: %s\n\n", data)
 			flusher.Flush()
 
-			// Close connection when build is complete or failed
-			if progress.Status == "completed" || progress.Status == "failed" {
+			// Close connection when build is complete, failed or unhealthy
+			if progress.Status == "completed" || progress.Status == "failed" || progress.Status == "unhealthy" {
 				log.Printf("[SSE] Build %s for version %s\n", progress.Status, versionID)
 				return
 			}
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
 	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
 )
 
 // ListVersions handles GET /apps/{appId}/versions
@@ -27,13 +30,19 @@ func (h *AppHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	versions, err := h.VersionService.ListVersions(r.Context(), appID)
+	q, err := parseListQuery(r)
 	if err != nil {
-		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	middleware.RespondJSON(w, http.StatusOK, versions)
+	versions, next, err := h.VersionService.QueryVersions(r.Context(), appID, q)
+	if err != nil {
+		respondListError(w, err)
+		return
+	}
+
+	respondList(w, versions, next)
 }
 
 // GetVersion handles GET /apps/{appId}/versions/{versionId}
@@ -56,7 +65,7 @@ func (h *AppHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	version, err := h.VersionService.GetVersion(r.Context(), versionID)
-	if err != nil {
+	if err != nil || version.AppID != appID {
 		middleware.RespondError(w, http.StatusNotFound, "Version not found")
 		return
 	}
@@ -88,6 +97,12 @@ func (h *AppHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ownerEmail, err := h.AppService.GetOwnerEmail(r.Context(), user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, "Failed to get owner email")
+		return
+	}
+
 	// Create version
 	version, err := h.VersionService.CreateVersion(r.Context(), appID)
 	if err != nil {
@@ -109,9 +124,9 @@ func (h *AppHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
 		comments, _ = h.CommentService.GetVersionComments(r.Context(), version.ID)
 	}
 
-	// Start build process in background
-	// Pass empty string for ownerEmail since admin user was created during app creation
-	go h.Builder.BuildApp(r.Context(), version.ID, appID, "", comments, "")
+	// Start build process in background with new context (not request context)
+	// The owner email is needed if this build creates the app database
+	go h.Builder.BuildApp(context.Background(), version.ID, appID, "", comments, ownerEmail)
 
 	middleware.RespondJSON(w, http.StatusCreated, version)
 }
@@ -135,7 +150,20 @@ func (h *AppHandler) PromoteVersion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.VersionService.PromoteVersion(r.Context(), versionID); err != nil {
+	version, err := h.VersionService.GetVersion(r.Context(), versionID)
+	if err != nil || version.AppID != appID {
+		middleware.RespondError(w, http.StatusNotFound, "Version not found")
+		return
+	}
+
+	// Production secrets need their own build; keep going if the client disconnects
+	ctx := context.WithoutCancel(r.Context())
+	if err := h.Builder.BuildProduction(ctx, versionID); err != nil {
+		middleware.RespondError(w, http.StatusInternalServerError, "Failed to build for production: "+err.Error())
+		return
+	}
+
+	if err := h.VersionService.PromoteVersion(ctx, versionID); err != nil {
 		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -162,10 +190,60 @@ func (h *AppHandler) DeleteVersion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, err := h.VersionService.GetVersion(r.Context(), versionID)
+	if err != nil || version.AppID != appID {
+		middleware.RespondError(w, http.StatusNotFound, "Version not found")
+		return
+	}
+
 	if err := h.VersionService.DeleteVersion(r.Context(), versionID); err != nil {
+		if errors.Is(err, services.ErrVersionPromoted) {
+			middleware.RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
 		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// RedeployVersion handles POST /versions/{versionId}/redeploy
+func (h *AppHandler) RedeployVersion(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	versionID := vars["versionId"]
+
+	version, err := h.VersionService.GetVersion(r.Context(), versionID)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "Version not found")
+		return
+	}
+
+	// Verify user owns the app
+	_, err = h.AppService.GetApp(r.Context(), version.AppID, user.Sub)
+	if err != nil {
+		middleware.RespondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	if version.Status == "pending" || version.Status == "building" {
+		middleware.RespondError(w, http.StatusConflict, "Version is still building")
+		return
+	}
+
+	if version.S3OutputPath == nil || *version.S3OutputPath == "" {
+		middleware.RespondError(w, http.StatusConflict, "Version has no stored build output to redeploy")
+		return
+	}
+
+	// Redeploy in background with new context (not request context)
+	go h.Builder.RedeployVersion(context.Background(), versionID)
+
+	middleware.RespondJSON(w, http.StatusAccepted, version)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rapidbuildapp/rapidbuild/internal/middleware"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+type WebhookHandler struct {
+	AppService     *services.AppService
+	WebhookService *services.WebhookService
+}
+
+func NewWebhookHandler(appService *services.AppService, webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		AppService:     appService,
+		WebhookService: webhookService,
+	}
+}
+
+// ListWebhooks handles GET /webhooks?app_id=...
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	webhooks, err := h.WebhookService.ListWebhooks(r.Context(), user.Sub, r.URL.Query().Get("app_id"))
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, webhooks)
+}
+
+// CreateWebhook handles POST /webhooks. The response includes the signing
+// secret, which is not returned again.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Verify user owns the app the webhook is for
+	if req.AppID != nil && *req.AppID != "" {
+		if _, err := h.AppService.GetApp(r.Context(), *req.AppID, user.Sub); err != nil {
+			middleware.RespondError(w, http.StatusNotFound, "App not found")
+			return
+		}
+	}
+
+	webhook, err := h.WebhookService.CreateWebhook(r.Context(), user.Sub, req)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusCreated, webhook)
+}
+
+// GetWebhook handles GET /webhooks/{id}
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	webhook, err := h.WebhookService.GetWebhook(r.Context(), mux.Vars(r)["id"], user.Sub)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, webhook)
+}
+
+// UpdateWebhook handles PATCH /webhooks/{id}
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	webhook, err := h.WebhookService.UpdateWebhook(r.Context(), mux.Vars(r)["id"], user.Sub, req)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{id}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	if err := h.WebhookService.DeleteWebhook(r.Context(), mux.Vars(r)["id"], user.Sub); err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/{id}/deliveries?limit=50, newest first
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			middleware.RespondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.WebhookService.ListDeliveries(r.Context(), mux.Vars(r)["id"], user.Sub, limit)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, deliveries)
+}
+
+// RedeliverDelivery handles POST /webhooks/{id}/deliveries/{deliveryId}/redeliver,
+// queueing a new delivery of the same payload
+func (h *WebhookHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	delivery, err := h.WebhookService.Redeliver(r.Context(), vars["id"], vars["deliveryId"], user.Sub)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusAccepted, delivery)
+}
+
+// PingWebhook handles POST /webhooks/{id}/ping, sending a ping event right away.
+// The delivery in the response tells whether it was received.
+func (h *WebhookHandler) PingWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	delivery, err := h.WebhookService.Ping(r.Context(), mux.Vars(r)["id"], user.Sub)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	middleware.RespondJSON(w, http.StatusOK, delivery)
+}
+
+// respondWebhookError maps webhook errors to HTTP statuses
+func respondWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		middleware.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound):
+		middleware.RespondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrSecretsDisabled):
+		middleware.RespondError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		middleware.RespondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/rapidbuildapp/rapidbuild/config"
 )
 
+// ErrNoRows is returned by Row.Scan when a query matched nothing
+var ErrNoRows = pgx.ErrNoRows
+
 type PostgresClient struct {
 	Pool *pgxpool.Pool
 }
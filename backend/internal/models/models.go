@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -19,43 +20,363 @@ type User struct {
 
 // App represents a user's application
 type App struct {
-	ID          string    `json:"id" db:"id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	Status      string    `json:"status" db:"status"` // draft, building, active, error
-	ProdVersion *int      `json:"prod_version" db:"prod_version"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID              string    `json:"id" db:"id"`
+	UserID          string    `json:"user_id" db:"user_id"`
+	Name            string    `json:"name" db:"name"`
+	Description     string    `json:"description" db:"description"`
+	Status          string    `json:"status" db:"status"` // draft, building, active, error
+	ProdVersion     *int      `json:"prod_version" db:"prod_version"`
+	ProdURL         *string   `json:"prod_url,omitempty" db:"prod_url"` // stable production URL once promoted
+	VercelProjectID *string   `json:"vercel_project_id,omitempty" db:"vercel_project_id"`
+	SchemaVersion   *int      `json:"schema_version,omitempty" db:"schema_version"` // last applied database schema migration
+	Icon            *string   `json:"icon,omitempty" db:"icon"`                     // emoji or image URL
+	Tags            []string  `json:"tags" db:"tags"`
+	Framework       *string   `json:"framework,omitempty" db:"framework"`
+	Visibility      string    `json:"visibility" db:"visibility"`       // private, unlisted, public
+	Template        *string   `json:"template,omitempty" db:"template"` // starter template, nil for the default
+	Revision        int       `json:"revision" db:"revision"`           // bumped by user edits, not by builds
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ListQuery holds the pagination, filters and sort shared by list endpoints
+type ListQuery struct {
+	Limit   int
+	Cursor  string     // opaque cursor from the previous page's X-Next-Cursor header
+	Sort    string     // a sortable field, prefixed with - for descending
+	Status  []string   // any of these statuses
+	Since   *time.Time // created at or after
+	Until   *time.Time // created before
+	Search  string     // case-insensitive substring match
+	Summary bool       // leave out heavy fields such as build logs
 }
 
 // Version represents a version of an app
 type Version struct {
-	ID             string     `json:"id" db:"id"`
-	AppID          string     `json:"app_id" db:"app_id"`
-	VersionNumber  int        `json:"version_number" db:"version_number"`
-	Status         string     `json:"status" db:"status"` // pending, building, completed, failed, promoted
-	S3CodePath     *string    `json:"s3_code_path,omitempty" db:"s3_code_path"`
-	VercelURL      *string    `json:"vercel_url,omitempty" db:"vercel_url"`
-	VercelDeployID *string    `json:"vercel_deploy_id,omitempty" db:"vercel_deploy_id"`
-	BuildLog       *string    `json:"build_log,omitempty" db:"build_log"`
-	ErrorMessage   *string    `json:"error_message,omitempty" db:"error_message"`
-	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
-	CompletedAt    *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ID              string        `json:"id" db:"id"`
+	AppID           string        `json:"app_id" db:"app_id"`
+	VersionNumber   int           `json:"version_number" db:"version_number"`
+	Status          string        `json:"status" db:"status"` // pending, building, completed, unhealthy, failed, promoted
+	S3CodePath      *string       `json:"s3_code_path,omitempty" db:"s3_code_path"`
+	S3OutputPath    *string       `json:"s3_output_path,omitempty" db:"s3_output_path"` // prebuilt .vercel/output
+	VercelURL       *string       `json:"vercel_url,omitempty" db:"vercel_url"`
+	VercelDeployID  *string       `json:"vercel_deploy_id,omitempty" db:"vercel_deploy_id"`
+	ProdOutputPath  *string       `json:"s3_prod_output_path,omitempty" db:"s3_prod_output_path"` // output built with production secrets
+	ProdDeployID    *string       `json:"prod_deploy_id,omitempty" db:"prod_deploy_id"`           // production deployment of that output
+	DeploymentState *string       `json:"deployment_state,omitempty" db:"deployment_state"`       // last seen Vercel state: READY, ERROR, DELETED...
+	BuildLog        *string       `json:"build_log,omitempty" db:"build_log"`
+	ErrorMessage    *string       `json:"error_message,omitempty" db:"error_message"`
+	ChecksReport    *ChecksReport `json:"checks_report,omitempty" db:"checks_report"`
+	SmokeReport     *SmokeReport  `json:"smoke_report,omitempty" db:"smoke_report"`
+	Instructions    *string       `json:"instructions,omitempty" db:"instructions"` // requirements the version was built from
+	CommitSHA       *string       `json:"commit_sha,omitempty" db:"commit_sha"`     // commit in the app's version history
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	CompletedAt     *time.Time    `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// ChecksReport holds the results of the post-build quality gates for a version
+type ChecksReport struct {
+	Passed    bool          `json:"passed"`
+	Attempt   int           `json:"attempt"`
+	Checks    []CheckResult `json:"checks"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// CheckResult is the outcome of a single quality gate (typecheck, lint, tests...)
+type CheckResult struct {
+	Name       string `json:"name"`
+	Command    string `json:"command"`
+	Required   bool   `json:"required"`
+	Passed     bool   `json:"passed"`
+	DurationMs int64  `json:"duration_ms"`
+	Output     string `json:"output,omitempty"`
+}
+
+// SmokeReport holds the results of probing a deployed version
+type SmokeReport struct {
+	Healthy      bool         `json:"healthy"`
+	BaseURL      string       `json:"base_url"`
+	Routes       []SmokeCheck `json:"routes"`
+	BundleURL    string       `json:"bundle_url,omitempty"`
+	BundleLoaded bool         `json:"bundle_loaded"`
+	CheckedAt    time.Time    `json:"checked_at"`
+}
+
+// SmokeCheck is the result of probing one route of a deployed version
+type SmokeCheck struct {
+	Path       string `json:"path"`
+	StatusCode int    `json:"status_code"`
+	RootMount  bool   `json:"root_mount"`
+	Passed     bool   `json:"passed"`
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error,omitempty"`
+}
+
+// AppDomain is a custom domain attached to an app's Vercel project
+type AppDomain struct {
+	ID           string               `json:"id" db:"id"`
+	AppID        string               `json:"app_id" db:"app_id"`
+	Domain       string               `json:"domain" db:"domain"`
+	Verified     bool                 `json:"verified" db:"verified"`
+	Verification []DomainVerification `json:"verification,omitempty" db:"verification"` // DNS records still required
+	VerifiedAt   *time.Time           `json:"verified_at,omitempty" db:"verified_at"`
+	CreatedAt    time.Time            `json:"created_at" db:"created_at"`
+}
+
+// DomainVerification is a DNS record the owner must create to verify a domain
+type DomainVerification struct {
+	Type   string `json:"type"`
+	Domain string `json:"domain"`
+	Value  string `json:"value"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AppSecret is an encrypted per-app environment variable. The value is only
+// ever decrypted for builds and Vercel sync, never returned by the API.
+type AppSecret struct {
+	ID        string    `json:"id" db:"id"`
+	AppID     string    `json:"app_id" db:"app_id"`
+	Key       string    `json:"key" db:"key"`
+	Scope     string    `json:"scope" db:"scope"` // preview, production
+	Value     string    `json:"-" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HistoryCommit is a commit in an app's version history
+type HistoryCommit struct {
+	Commit        string    `json:"commit"`
+	Parents       []string  `json:"parents"`
+	Subject       string    `json:"subject"`
+	Message       string    `json:"message"`
+	Author        string    `json:"author"`
+	Date          time.Time `json:"date"`
+	VersionID     *string   `json:"version_id,omitempty"`
+	VersionNumber *int      `json:"version_number,omitempty"`
+}
+
+// HistoryDiff compares two commits of an app's version history
+type HistoryDiff struct {
+	From      string              `json:"from"`
+	To        string              `json:"to"`
+	Files     []HistoryFileChange `json:"files"`
+	Patch     string              `json:"patch"`
+	Truncated bool                `json:"truncated,omitempty"` // the patch was cut short
+}
+
+// HistoryFileChange is one changed file of a HistoryDiff
+type HistoryFileChange struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// BlameLine attributes a line of a file to the commit that last changed it
+type BlameLine struct {
+	Line          int    `json:"line"`
+	Commit        string `json:"commit"`
+	VersionNumber *int   `json:"version_number,omitempty"`
+	Summary       string `json:"summary"`
+	Content       string `json:"content"`
+}
+
+// GitExport pushes an app's versions to a Git remote. The token is stored
+// encrypted like app secrets and never returned by the API.
+type GitExport struct {
+	AppID             string     `json:"app_id" db:"app_id"`
+	RemoteURL         string     `json:"remote_url" db:"remote_url"`
+	Branch            string     `json:"branch" db:"branch"`
+	Username          *string    `json:"username,omitempty" db:"username"`
+	HasToken          bool       `json:"has_token" db:"-"`
+	Token             string     `json:"-" db:"-"`
+	Enabled           bool       `json:"enabled" db:"enabled"`
+	LastPushedVersion *int       `json:"last_pushed_version,omitempty" db:"last_pushed_version"`
+	LastCommit        *string    `json:"last_commit,omitempty" db:"last_commit"`
+	LastPushedAt      *time.Time `json:"last_pushed_at,omitempty" db:"last_pushed_at"`
+	LastError         *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Webhook subscribes a URL to a user's app events, for one app or (AppID nil)
+// all of them. The signing secret is only returned when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	AppID     *string   `json:"app_id,omitempty" db:"app_id"`
+	URL       string    `json:"url" db:"url"`
+	Events    []string  `json:"events" db:"events"` // empty for every event
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	Secret    string    `json:"secret,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is one event sent (or being sent) to a webhook, with the
+// outcome of its latest attempt
+type WebhookDelivery struct {
+	ID             string          `json:"id" db:"id"`
+	WebhookID      string          `json:"webhook_id" db:"webhook_id"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	AppID          *string         `json:"app_id,omitempty" db:"app_id"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"` // pending, retrying, succeeded, failed
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   *string         `json:"response_body,omitempty" db:"response_body"`
+	Error          *string         `json:"error,omitempty" db:"error"`
+	DurationMS     *int            `json:"duration_ms,omitempty" db:"duration_ms"`
+	RedeliveryOf   *string         `json:"redelivery_of,omitempty" db:"redelivery_of"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// CollectionSchema is one collection of a generated app's database as declared
+// in the app's schemas/*.json files
+type CollectionSchema struct {
+	Name      string                 `json:"name"`
+	Validator map[string]interface{} `json:"validator,omitempty"` // $jsonSchema
+	Indexes   []IndexSpec            `json:"indexes,omitempty"`
+}
+
+// IndexSpec describes a MongoDB index
+type IndexSpec struct {
+	Name               string     `json:"name"`
+	Keys               []IndexKey `json:"keys"`
+	Unique             bool       `json:"unique,omitempty"`
+	Sparse             bool       `json:"sparse,omitempty"`
+	ExpireAfterSeconds *int32     `json:"expire_after_seconds,omitempty"`
+}
+
+// IndexKey is one field of an index in order; Value is "1", "-1" or an index type such as "text"
+type IndexKey struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// MigrationStep is one change applied to an app database
+type MigrationStep struct {
+	Action     string                 `json:"action"` // create_collection, update_validator, create_index, drop_index
+	Collection string                 `json:"collection"`
+	Index      *IndexSpec             `json:"index,omitempty"`
+	IndexName  string                 `json:"index_name,omitempty"`
+	Validator  map[string]interface{} `json:"validator,omitempty"`
+}
+
+// SchemaMigration records a schema version applied to an app database
+type SchemaMigration struct {
+	ID            string             `json:"id" db:"id"`
+	AppID         string             `json:"app_id" db:"app_id"`
+	VersionID     *string            `json:"version_id,omitempty" db:"version_id"`
+	SchemaVersion int                `json:"schema_version" db:"schema_version"`
+	Schema        []CollectionSchema `json:"schema" db:"schema"`
+	Steps         []MigrationStep    `json:"steps" db:"steps"`
+	BackupPath    *string            `json:"backup_path,omitempty" db:"backup_path"`
+	AppliedAt     time.Time          `json:"applied_at" db:"applied_at"`
+}
+
+// DataCollection is a collection of an app database as shown in the data browser
+type DataCollection struct {
+	Name          string            `json:"name"`
+	DocumentCount int64             `json:"document_count"`
+	Schema        *CollectionSchema `json:"schema,omitempty"` // from the last applied migration
+}
+
+// DocumentPage is one page of documents from an app database, each encoded as relaxed extended JSON
+type DocumentPage struct {
+	Documents []json.RawMessage `json:"documents"`
+	Total     int64             `json:"total"`
+	Limit     int64             `json:"limit"`
+	Skip      int64             `json:"skip"`
+}
+
+// DocumentQuery selects documents in the data browser
+type DocumentQuery struct {
+	Filter string // extended JSON query document
+	Sort   string // comma separated fields, - prefix for descending
+	Limit  int64
+	Skip   int64
+}
+
+// DataAuditEntry records an owner's change to an app database made through the data browser
+type DataAuditEntry struct {
+	ID         string          `json:"id" db:"id"`
+	AppID      string          `json:"app_id" db:"app_id"`
+	UserID     string          `json:"user_id" db:"user_id"`
+	Collection string          `json:"collection" db:"collection"`
+	DocumentID string          `json:"document_id" db:"document_id"`
+	Action     string          `json:"action" db:"action"` // insert, update, delete
+	Before     json.RawMessage `json:"before,omitempty" db:"before"`
+	After      json.RawMessage `json:"after,omitempty" db:"after"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AppUser is an end user of a generated app, stored in system_db.app_users
+type AppUser struct {
+	ID          string     `json:"id" bson:"_id"`
+	AppID       string     `json:"app_id" bson:"appId"`
+	Email       string     `json:"email" bson:"email"`
+	Name        string     `json:"name,omitempty" bson:"name,omitempty"`
+	Roles       []string   `json:"roles" bson:"roles"`
+	Status      string     `json:"status" bson:"status"` // active, invited, disabled
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" bson:"lastLoginAt,omitempty"`
+	LastLoginIP string     `json:"last_login_ip,omitempty" bson:"lastLoginIp,omitempty"`
+	LoginCount  int        `json:"login_count" bson:"loginCount"`
+	InvitedAt   *time.Time `json:"invited_at,omitempty" bson:"invitedAt,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"createdAt"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updatedAt"`
+}
+
+// ShareLink is a public link to an app or version deployment that works without a RapidBuild login
+type ShareLink struct {
+	ID                string     `json:"id" db:"id"`
+	AppID             string     `json:"app_id" db:"app_id"`
+	VersionID         *string    `json:"version_id,omitempty" db:"version_id"` // nil shares the app's production (or latest) deployment
+	Slug              string     `json:"slug" db:"slug"`
+	URL               string     `json:"url" db:"-"`
+	PasswordProtected bool       `json:"password_protected" db:"-"`
+	PasswordHash      *string    `json:"-" db:"password_hash"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	MaxViews          *int       `json:"max_views,omitempty" db:"max_views"`
+	ViewCount         int        `json:"view_count" db:"view_count"`
+	AllowComments     bool       `json:"allow_comments" db:"allow_comments"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedBy         string     `json:"created_by" db:"created_by"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ShareLinkInfo is what anyone holding a share link can see before opening it
+type ShareLinkInfo struct {
+	AppName           string     `json:"app_name"`
+	PasswordProtected bool       `json:"password_protected"`
+	AllowComments     bool       `json:"allow_comments"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+// ShareLinkTarget is where an opened share link is viewed: a URL on the share
+// host that is valid until ExpiresAt
+type ShareLinkTarget struct {
+	URL       string    `json:"url"`
+	VersionID string    `json:"version_id"`
+	AppName   string    `json:"app_name"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // Comment represents a user comment on an app
 type Comment struct {
-	ID            string     `json:"id" db:"id"`
-	AppID         string     `json:"app_id" db:"app_id"`
-	VersionID     *string    `json:"version_id" db:"version_id"` // null until submitted
-	UserID        string     `json:"user_id" db:"user_id"`
-	PagePath      string     `json:"page_path" db:"page_path"`     // e.g., "/home", "/about"
-	ElementPath   string     `json:"element_path" db:"element_path"` // CSS selector or XPath
-	Content       string     `json:"content" db:"content"`
-	Status        string     `json:"status" db:"status"` // draft, submitted, resolved
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-	SubmittedAt   *time.Time `json:"submitted_at" db:"submitted_at"`
+	ID          string     `json:"id" db:"id"`
+	AppID       string     `json:"app_id" db:"app_id"`
+	VersionID   *string    `json:"version_id" db:"version_id"` // null until submitted
+	UserID      string     `json:"user_id" db:"user_id"`
+	PagePath    string     `json:"page_path" db:"page_path"`       // e.g., "/home", "/about"
+	ElementPath string     `json:"element_path" db:"element_path"` // CSS selector or XPath
+	Content     string     `json:"content" db:"content"`
+	Status      string     `json:"status" db:"status"`                         // draft, submitted, resolved
+	AuthorName  *string    `json:"author_name,omitempty" db:"author_name"`     // anonymous author via a share link
+	ShareLinkID *string    `json:"share_link_id,omitempty" db:"share_link_id"` // share link the comment was left through
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	SubmittedAt *time.Time `json:"submitted_at" db:"submitted_at"`
 }
 
 // RequirementFile represents uploaded requirement files
@@ -74,7 +395,37 @@ type CreateAppRequest struct {
 	Name         string   `json:"name"`
 	Description  string   `json:"description"`
 	Requirements string   `json:"requirements"`
-	Files        []string `json:"files"` // S3 paths of uploaded files
+	Files        []string `json:"files"`    // S3 paths of uploaded files
+	Template     string   `json:"template"` // starter template ID from GET /templates, default starter if empty
+}
+
+// Template is a starter template new apps can be created from
+type Template struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Framework    string `json:"framework,omitempty"`
+	Description  string `json:"description,omitempty"`
+	BuildCommand string `json:"build_command,omitempty"`
+	PreviewImage string `json:"preview_image,omitempty"` // URL of a screenshot
+	Archive      string `json:"-"`                       // S3 key of a .tar.gz with the template's files
+	Dir          string `json:"-"`                       // directory with the template's files, if not archived
+}
+
+// UpdateAppRequest represents request to edit an app's details. Omitted fields are unchanged.
+type UpdateAppRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Icon        *string   `json:"icon"` // "" removes the icon
+	Tags        *[]string `json:"tags"`
+	Framework   *string   `json:"framework"`
+	Visibility  *string   `json:"visibility"`
+}
+
+// CloneAppRequest represents request to clone an app into a new app
+type CloneAppRequest struct {
+	Name        string `json:"name"`         // defaults to "<name> (copy)"
+	VersionID   string `json:"version_id"`   // code snapshot to start from, default production or latest
+	CopySecrets bool   `json:"copy_secrets"` // copy the app's secrets to the clone
 }
 
 // CreateVersionRequest represents request to create a new version
@@ -82,6 +433,133 @@ type CreateVersionRequest struct {
 	Comments []string `json:"comments"` // Comment IDs to include in this version
 }
 
+// AddDomainRequest represents request to attach a custom domain to an app
+type AddDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// SetSecretRequest represents request to create or replace an app secret
+type SetSecretRequest struct {
+	Value string `json:"value"`
+	Scope string `json:"scope"` // preview (default), production
+}
+
+// SetGitExportRequest configures an app's Git export. A nil Token keeps the
+// stored one and an empty string removes it.
+type SetGitExportRequest struct {
+	RemoteURL string  `json:"remote_url"`
+	Branch    string  `json:"branch"` // default main
+	Username  *string `json:"username"`
+	Token     *string `json:"token"` // password or access token for HTTPS remotes
+	Enabled   *bool   `json:"enabled"`
+}
+
+// PushGitExportRequest pushes a version now; the latest completed version by default
+type PushGitExportRequest struct {
+	VersionID string `json:"version_id"`
+}
+
+// RestoreCommitRequest builds a new version from a commit of the app's history
+type RestoreCommitRequest struct {
+	Commit string `json:"commit"`
+}
+
+// CreateWebhookRequest subscribes a URL to events of one app (AppID) or all
+// of the user's apps
+type CreateWebhookRequest struct {
+	URL     string   `json:"url"`
+	AppID   *string  `json:"app_id"`
+	Events  []string `json:"events"` // empty for every event
+	Enabled *bool    `json:"enabled"`
+}
+
+// UpdateWebhookRequest changes the fields that are set
+type UpdateWebhookRequest struct {
+	URL     *string   `json:"url"`
+	Events  *[]string `json:"events"`
+	Enabled *bool     `json:"enabled"`
+}
+
+// InviteAppUserRequest represents request to invite an end user to a generated app
+type InviteAppUserRequest struct {
+	Email string   `json:"email"`
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"` // defaults to ["user"]
+}
+
+// UpdateAppUserRequest represents request to change an end user's roles or disable them
+type UpdateAppUserRequest struct {
+	Roles    *[]string `json:"roles"`
+	Disabled *bool     `json:"disabled"`
+}
+
+// ResetAppUserPasswordRequest represents request to reset an end user's password.
+// Without a password the user is emailed a reset link instead.
+type ResetAppUserPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// PreviewTokenRequest represents request to preview an app. All fields are
+// optional: by default the owner previews the latest deployed version as admin.
+type PreviewTokenRequest struct {
+	Version    string `json:"version"`     // latest (default), production, a version ID or number
+	Role       string `json:"role"`        // preview as the owner with only this role
+	UserID     string `json:"user_id"`     // preview as this app end user
+	TTLSeconds int    `json:"ttl_seconds"` // token lifetime, default 5 minutes
+}
+
+// RefreshPreviewTokenRequest represents request to extend a preview session
+type RefreshPreviewTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// PreviewToken is a short-lived end-user token for a generated app and the URL to use it with
+type PreviewToken struct {
+	Token      string    `json:"token"`
+	PreviewURL string    `json:"previewUrl"`
+	VersionID  string    `json:"versionId,omitempty"`
+	UserID     string    `json:"userId"`
+	Email      string    `json:"email"`
+	Roles      []string  `json:"roles"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// RotateSigningKeyRequest represents request to rotate an app's JWT signing secret
+type RotateSigningKeyRequest struct {
+	GraceSeconds *int `json:"grace_seconds"` // how long the old secret stays valid, default 24 hours; 0 revokes it now
+}
+
+// SigningKey describes one of an app's JWT signing secrets, without the secret
+type SigningKey struct {
+	KID       string     `json:"kid"`
+	Active    bool       `json:"active"` // new tokens are signed with the active key
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // retired keys are accepted until then
+}
+
+// CreateShareLinkRequest represents request to create a public share link
+type CreateShareLinkRequest struct {
+	VersionID     *string    `json:"version_id"` // omit to share the app's production (or latest) deployment
+	Password      string     `json:"password"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	MaxViews      *int       `json:"max_views"`
+	AllowComments bool       `json:"allow_comments"`
+}
+
+// OpenShareLinkRequest represents request to open a share link
+type OpenShareLinkRequest struct {
+	Password string `json:"password"`
+}
+
+// AddShareCommentRequest represents an anonymous comment left through a share link
+type AddShareCommentRequest struct {
+	Password    string `json:"password"`
+	AuthorName  string `json:"author_name"`
+	PagePath    string `json:"page_path"`
+	ElementPath string `json:"element_path"`
+	Content     string `json:"content"`
+}
+
 // AddCommentRequest represents request to add a comment
 type AddCommentRequest struct {
 	PagePath    string `json:"page_path"`
@@ -89,6 +567,31 @@ type AddCommentRequest struct {
 	Content     string `json:"content"`
 }
 
+// AppEvent is a lifecycle event raised for an app, e.g. a live deployment breaking
+type AppEvent struct {
+	Type      string                 `json:"type"`
+	AppID     string                 `json:"app_id"`
+	VersionID string                 `json:"version_id,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Job is a durable unit of background work, retried with backoff until it
+// succeeds or runs out of attempts
+type Job struct {
+	ID          string            `json:"id" db:"id"`
+	Type        string            `json:"type" db:"type"`
+	Payload     map[string]string `json:"payload" db:"payload"`
+	Status      string            `json:"status" db:"status"` // pending, running, completed, failed
+	Attempts    int               `json:"attempts" db:"attempts"`
+	MaxAttempts int               `json:"max_attempts" db:"max_attempts"`
+	LastError   *string           `json:"last_error,omitempty" db:"last_error"`
+	RunAt       time.Time         `json:"run_at" db:"run_at"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
+}
+
 // BuildProgress represents real-time build progress
 type BuildProgress struct {
 	VersionID string    `json:"version_id"`
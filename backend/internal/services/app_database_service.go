@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrOwnerEmailRequired = errors.New("owner email is required to provision an app database")
+	ErrAppDatabaseMissing = errors.New("app database not provisioned")
+	ErrInvalidGracePeriod = errors.New("grace_seconds must be between 0 and 2592000 (30 days)")
+	ErrRotationConflict   = errors.New("the signing key was rotated concurrently, try again")
+)
+
+// Signing key rotation grace periods
+const (
+	defaultSigningKeyGrace = 24 * time.Hour
+	maxSigningKeyGrace     = 30 * 24 * time.Hour
+)
+
+// mongoNamespaceExists is the server error code for creating a collection that already exists
+const mongoNamespaceExists = 48
+
+// systemDatabase holds the registry of app databases and the end users of every app
+const systemDatabase = "system_db"
+
+// AppDatabase is an app's record in system_db.apps
+type AppDatabase struct {
+	ID           string    `bson:"_id"`
+	DatabaseName string    `bson:"databaseName"`
+	JWT          AppJWT    `bson:"jwt"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	UpdatedAt    time.Time `bson:"updatedAt"`
+}
+
+// AppJWT is the key set generated apps sign and verify end-user tokens with.
+// New tokens are signed with Secret and carry KID in their header. A token is
+// accepted if its kid names a key in Keys that hasn't expired; tokens without
+// a kid are checked against every unexpired key. Records created before
+// rotation existed only have Secret.
+type AppJWT struct {
+	Secret string          `bson:"secret"`
+	KID    string          `bson:"kid,omitempty"`
+	Keys   []AppSigningKey `bson:"keys,omitempty"`
+}
+
+// AppSigningKey is one secret in an app's key set
+type AppSigningKey struct {
+	KID       string     `bson:"kid"`
+	Secret    string     `bson:"secret"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty"` // set when the key is retired
+}
+
+// AcceptedKeys returns the keys tokens may still be verified with
+func (j AppJWT) AcceptedKeys(now time.Time) []AppSigningKey {
+	if len(j.Keys) == 0 {
+		return []AppSigningKey{{KID: j.KID, Secret: j.Secret}}
+	}
+
+	keys := make([]AppSigningKey, 0, len(j.Keys))
+	for _, key := range j.Keys {
+		if key.ExpiresAt == nil || key.ExpiresAt.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Keyfunc resolves the secret for a token from its kid header, for jwt.Parse
+func (j AppJWT) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	set := jwt.VerificationKeySet{}
+	for _, key := range j.AcceptedKeys(time.Now()) {
+		if kid == "" || key.KID == kid {
+			set.Keys = append(set.Keys, []byte(key.Secret))
+		}
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("unknown or expired signing key %q", kid)
+	}
+	return set, nil
+}
+
+// AppDatabaseService provisions the MongoDB databases of generated apps. Every
+// operation is idempotent, so provisioning can be retried after a partial failure.
+type AppDatabaseService struct {
+	Mongo *mongo.Client
+
+	mu           sync.Mutex
+	indexesReady bool
+}
+
+func NewAppDatabaseService(mongoClient *mongo.Client) *AppDatabaseService {
+	return &AppDatabaseService{Mongo: mongoClient}
+}
+
+// Provision creates the app's database record, its collections and the owner's
+// admin account, returning the database record
+func (s *AppDatabaseService) Provision(ctx context.Context, appID, ownerEmail string, schemas []models.CollectionSchema) (*AppDatabase, error) {
+	if ownerEmail == "" {
+		return nil, ErrOwnerEmailRequired
+	}
+
+	appDB, err := s.EnsureApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, schema := range schemas {
+		if err := s.EnsureCollection(ctx, appDB.DatabaseName, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.EnsureAdmin(ctx, appID, ownerEmail); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AppDatabase] Provisioned %s for app %s (%d collections)\n", appDB.DatabaseName, appID, len(schemas))
+	return appDB, nil
+}
+
+// GetApp returns an app's database record, or ErrAppDatabaseMissing
+func (s *AppDatabaseService) GetApp(ctx context.Context, appID string) (*AppDatabase, error) {
+	var appDB AppDatabase
+	err := s.Mongo.Database(systemDatabase).Collection("apps").FindOne(ctx, bson.M{"_id": appID}).Decode(&appDB)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAppDatabaseMissing
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app database: %w", err)
+	}
+
+	return &appDB, nil
+}
+
+// EnsureApp registers the app in system_db.apps with a database name and JWT
+// secret, keeping both if the app is already registered
+func (s *AppDatabaseService) EnsureApp(ctx context.Context, appID string) (*AppDatabase, error) {
+	if err := s.ensureSystemIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key, err := newSigningKey(now)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"databaseName": databaseNameFor(appID),
+			"jwt":          AppJWT{Secret: key.Secret, KID: key.KID, Keys: []AppSigningKey{key}},
+			"createdAt":    now,
+		},
+		"$set": bson.M{"updatedAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var appDB AppDatabase
+	err = s.Mongo.Database(systemDatabase).Collection("apps").
+		FindOneAndUpdate(ctx, bson.M{"_id": appID}, update, opts).Decode(&appDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register app database: %w", err)
+	}
+
+	return &appDB, nil
+}
+
+// SigningKeys lists an app's unexpired signing keys, active key first
+func (s *AppDatabaseService) SigningKeys(ctx context.Context, appID string) ([]models.SigningKey, error) {
+	appDB, err := s.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	return describeSigningKeys(appDB.JWT), nil
+}
+
+// RotateSigningKey makes a new secret the app's active signing key. The old
+// key keeps verifying tokens for the grace period, so signed-in end users
+// aren't logged out; a zero grace revokes it immediately, e.g. after a leak.
+// Keys that have already expired are dropped from the set.
+func (s *AppDatabaseService) RotateSigningKey(ctx context.Context, appID string, req models.RotateSigningKeyRequest) ([]models.SigningKey, error) {
+	grace := defaultSigningKeyGrace
+	if req.GraceSeconds != nil {
+		grace = time.Duration(*req.GraceSeconds) * time.Second
+	}
+	if grace < 0 || grace > maxSigningKeyGrace {
+		return nil, ErrInvalidGracePeriod
+	}
+
+	appDB, err := s.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key, err := newSigningKey(now)
+	if err != nil {
+		return nil, err
+	}
+
+	retiredAt := now.Add(grace)
+	keys := []AppSigningKey{key}
+	if len(appDB.JWT.Keys) == 0 {
+		// Give the secret of a record created before rotation existed a kid of its own
+		legacyKID, err := generateSecureToken(8)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key ID: %w", err)
+		}
+		appDB.JWT.Keys = []AppSigningKey{{KID: legacyKID, Secret: appDB.JWT.Secret, CreatedAt: appDB.CreatedAt}}
+	}
+	for _, old := range appDB.JWT.Keys {
+		if old.ExpiresAt == nil || old.ExpiresAt.After(retiredAt) {
+			old.ExpiresAt = &retiredAt
+		}
+		if grace > 0 && old.ExpiresAt.After(now) {
+			keys = append(keys, old)
+		}
+	}
+
+	jwtSet := AppJWT{Secret: key.Secret, KID: key.KID, Keys: keys}
+
+	// Matching on the current secret keeps concurrent rotations from losing a key
+	result, err := s.Mongo.Database(systemDatabase).Collection("apps").UpdateOne(ctx,
+		bson.M{"_id": appID, "jwt.secret": appDB.JWT.Secret},
+		bson.M{"$set": bson.M{"jwt": jwtSet, "updatedAt": now}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate signing key: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrRotationConflict
+	}
+
+	log.Printf("[AppDatabase] Rotated signing key for app %s (new kid %s, grace %s)\n", appID, key.KID, grace)
+	return describeSigningKeys(jwtSet), nil
+}
+
+// EnsureCollection creates a collection with its validator and indexes. An
+// existing collection gets the validator and any missing indexes.
+func (s *AppDatabaseService) EnsureCollection(ctx context.Context, dbName string, schema models.CollectionSchema) error {
+	database := s.Mongo.Database(dbName)
+
+	names, err := database.ListCollectionNames(ctx, bson.M{"name": schema.Name})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	if len(names) == 0 {
+		opts := options.CreateCollection()
+		if schema.Validator != nil {
+			opts.SetValidator(bson.M{"$jsonSchema": schema.Validator})
+		}
+		err := database.CreateCollection(ctx, schema.Name, opts)
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == mongoNamespaceExists) {
+			return fmt.Errorf("failed to create collection %s: %w", schema.Name, err)
+		}
+	} else if schema.Validator != nil {
+		err := database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: schema.Name},
+			{Key: "validator", Value: bson.M{"$jsonSchema": schema.Validator}},
+			{Key: "validationLevel", Value: "moderate"},
+		}).Err()
+		if err != nil {
+			return fmt.Errorf("failed to set validator on %s: %w", schema.Name, err)
+		}
+	}
+
+	if len(schema.Indexes) == 0 {
+		return nil
+	}
+
+	indexes := make([]mongo.IndexModel, 0, len(schema.Indexes))
+	for _, index := range schema.Indexes {
+		indexes = append(indexes, indexModel(index))
+	}
+	if _, err := database.Collection(schema.Name).Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create indexes on %s: %w", schema.Name, err)
+	}
+
+	return nil
+}
+
+// EnsureAdmin makes sure the owner has an admin account in system_db.app_users
+func (s *AppDatabaseService) EnsureAdmin(ctx context.Context, appID, email string) error {
+	if email == "" {
+		return ErrOwnerEmailRequired
+	}
+	if err := s.ensureSystemIndexes(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":        uuid.New().String(),
+			"status":     "active",
+			"loginCount": 0,
+			"createdAt":  now,
+		},
+		"$addToSet": bson.M{"roles": "admin"},
+		"$set":      bson.M{"updatedAt": now},
+	}
+
+	_, err := s.Mongo.Database(systemDatabase).Collection("app_users").UpdateOne(ctx,
+		bson.M{"appId": appID, "email": email}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to create admin user: %w", err)
+	}
+
+	return nil
+}
+
+// ensureSystemIndexes creates the system_db indexes provisioning relies on, once per process
+func (s *AppDatabaseService) ensureSystemIndexes(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.indexesReady {
+		return nil
+	}
+
+	_, err := s.Mongo.Database(systemDatabase).Collection("app_users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "appId", Value: 1}, {Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create app_users index: %w", err)
+	}
+
+	s.indexesReady = true
+	return nil
+}
+
+// databaseNameFor derives the MongoDB database name of an app. Names are limited
+// to 64 bytes, which app_ plus a UUID without dashes stays well under.
+func databaseNameFor(appID string) string {
+	return "app_" + strings.ReplaceAll(appID, "-", "")
+}
+
+// newSigningKey creates a signing key with a random secret and key ID
+func newSigningKey(now time.Time) (AppSigningKey, error) {
+	secret, err := generateJWTSecret()
+	if err != nil {
+		return AppSigningKey{}, err
+	}
+	kid, err := generateSecureToken(8)
+	if err != nil {
+		return AppSigningKey{}, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	return AppSigningKey{KID: kid, Secret: secret, CreatedAt: now}, nil
+}
+
+// describeSigningKeys lists a key set's unexpired keys without their secrets
+func describeSigningKeys(set AppJWT) []models.SigningKey {
+	keys := []models.SigningKey{}
+	for _, key := range set.AcceptedKeys(time.Now()) {
+		keys = append(keys, models.SigningKey{
+			KID:       key.KID,
+			Active:    key.Secret == set.Secret,
+			CreatedAt: key.CreatedAt,
+			ExpiresAt: key.ExpiresAt,
+		})
+	}
+	return keys
+}
+
+// generateJWTSecret returns a random secret used to sign the app's end-user tokens
+func generateJWTSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate JWT secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
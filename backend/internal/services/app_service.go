@@ -2,14 +2,57 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/rapidbuildapp/rapidbuild/internal/db"
 	"github.com/rapidbuildapp/rapidbuild/internal/models"
 )
 
+// appColumns is the column list scanned by scanApp
+const appColumns = "id, user_id, name, description, status, prod_version, prod_url, vercel_project_id, schema_version, icon, tags, framework, visibility, template, revision, created_at, updated_at"
+
+// appSummaryColumns is appColumns with the description shortened, for list summaries
+const appSummaryColumns = "id, user_id, name, LEFT(description, 200), status, prod_version, prod_url, vercel_project_id, schema_version, icon, tags, framework, visibility, template, revision, created_at, updated_at"
+
+var (
+	ErrAppNotFound = errors.New("app not found")
+	ErrAppModified = errors.New("app was modified since it was loaded, reload and try again")
+	ErrInvalidApp  = errors.New("invalid app details")
+)
+
+// App visibilities
+var appVisibilities = []string{"private", "unlisted", "public"}
+
+// Limits on editable app details
+const (
+	maxAppNameLength        = 100
+	maxAppDescriptionLength = 5000
+	maxAppTags              = 20
+)
+
+var (
+	tagPattern       = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+	frameworkPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{0,31}$`)
+)
+
+// appListSpec is how list queries apply to apps
+var appListSpec = listSpec{
+	sorts: map[string]sortColumn{
+		"created_at": {"created_at", "timestamptz"},
+		"updated_at": {"updated_at", "timestamptz"},
+		"name":       {"name", "text"},
+	},
+	defaultSort:   "-created_at",
+	searchColumns: []string{"name", "description"},
+}
+
 type AppService struct {
 	DB *db.PostgresClient
 }
@@ -29,19 +72,18 @@ func (s *AppService) CreateApp(ctx context.Context, userID string, req models.Cr
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if req.Template != "" {
+		app.Template = &req.Template
+	}
 
 	query := `
-		INSERT INTO apps (id, user_id, name, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, name, description, status, prod_version, created_at, updated_at
-	`
+		INSERT INTO apps (id, user_id, name, description, status, template, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + appColumns
 
-	err := s.DB.QueryRow(ctx, query,
-		app.ID, app.UserID, app.Name, app.Description, app.Status, app.CreatedAt, app.UpdatedAt,
-	).Scan(
-		&app.ID, &app.UserID, &app.Name, &app.Description, &app.Status,
-		&app.ProdVersion, &app.CreatedAt, &app.UpdatedAt,
-	)
+	err := scanApp(s.DB.QueryRow(ctx, query,
+		app.ID, app.UserID, app.Name, app.Description, app.Status, app.Template, app.CreatedAt, app.UpdatedAt,
+	), &app)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create app: %w", err)
@@ -53,16 +95,9 @@ func (s *AppService) CreateApp(ctx context.Context, userID string, req models.Cr
 // GetApp retrieves an app by ID
 func (s *AppService) GetApp(ctx context.Context, appID, userID string) (*models.App, error) {
 	app := &models.App{}
-	query := `
-		SELECT id, user_id, name, description, status, prod_version, created_at, updated_at
-		FROM apps
-		WHERE id = $1 AND user_id = $2
-	`
+	query := `SELECT ` + appColumns + ` FROM apps WHERE id = $1 AND user_id = $2`
 
-	err := s.DB.QueryRow(ctx, query, appID, userID).Scan(
-		&app.ID, &app.UserID, &app.Name, &app.Description, &app.Status,
-		&app.ProdVersion, &app.CreatedAt, &app.UpdatedAt,
-	)
+	err := scanApp(s.DB.QueryRow(ctx, query, appID, userID), app)
 
 	if err != nil {
 		return nil, fmt.Errorf("app not found: %w", err)
@@ -71,39 +106,61 @@ func (s *AppService) GetApp(ctx context.Context, appID, userID string) (*models.
 	return app, nil
 }
 
-// ListApps retrieves all apps for a user
-func (s *AppService) ListApps(ctx context.Context, userID string) ([]models.App, error) {
-	query := `
-		SELECT id, user_id, name, description, status, prod_version, created_at, updated_at
-		FROM apps
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
+// GetAppByID retrieves an app by ID without an ownership check, for internal callers
+func (s *AppService) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	app := &models.App{}
+	query := `SELECT ` + appColumns + ` FROM apps WHERE id = $1`
+
+	if err := scanApp(s.DB.QueryRow(ctx, query, appID), app); err != nil {
+		return nil, fmt.Errorf("app not found: %w", err)
+	}
+
+	return app, nil
+}
+
+// ListApps retrieves a page of a user's apps, and the cursor of the next page
+func (s *AppService) ListApps(ctx context.Context, userID string, q models.ListQuery) ([]models.App, string, error) {
+	plan, err := planList(q, appListSpec, []interface{}{userID})
+	if err != nil {
+		return nil, "", err
+	}
+
+	columns := appColumns
+	if q.Summary {
+		columns = appSummaryColumns
+	}
+	query := `SELECT ` + columns + ` FROM apps WHERE user_id = $1` + plan.where() + plan.tail()
 
-	rows, err := s.DB.Query(ctx, query, userID)
+	rows, err := s.DB.Query(ctx, query, plan.args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list apps: %w", err)
+		return nil, "", fmt.Errorf("failed to list apps: %w", err)
 	}
 	defer rows.Close()
 
-	var apps []models.App
+	apps := []models.App{}
 	for rows.Next() {
 		var app models.App
-		err := rows.Scan(
-			&app.ID, &app.UserID, &app.Name, &app.Description, &app.Status,
-			&app.ProdVersion, &app.CreatedAt, &app.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan app: %w", err)
+		if err := scanApp(rows, &app); err != nil {
+			return nil, "", fmt.Errorf("failed to scan app: %w", err)
 		}
 		apps = append(apps, app)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating apps: %w", err)
+		return nil, "", fmt.Errorf("error iterating apps: %w", err)
 	}
 
-	return apps, nil
+	apps, next := page(plan, apps, func(app models.App, field string) (string, string) {
+		switch field {
+		case "name":
+			return app.Name, app.ID
+		case "updated_at":
+			return app.UpdatedAt.Format(time.RFC3339Nano), app.ID
+		default:
+			return app.CreatedAt.Format(time.RFC3339Nano), app.ID
+		}
+	})
+	return apps, next, nil
 }
 
 // UpdateApp updates an app
@@ -140,6 +197,30 @@ func (s *AppService) UpdateApp(ctx context.Context, appID, userID string, update
 		argCount++
 	}
 
+	if prodURL, ok := updates["prod_url"].(string); ok {
+		query += fmt.Sprintf(", prod_url = $%d", argCount)
+		args = append(args, prodURL)
+		argCount++
+	}
+
+	if projectID, ok := updates["vercel_project_id"].(string); ok {
+		query += fmt.Sprintf(", vercel_project_id = $%d", argCount)
+		args = append(args, projectID)
+		argCount++
+	}
+
+	if schemaVersion, ok := updates["schema_version"].(int); ok {
+		query += fmt.Sprintf(", schema_version = $%d", argCount)
+		args = append(args, schemaVersion)
+		argCount++
+	}
+
+	if framework, ok := updates["framework"].(string); ok {
+		query += fmt.Sprintf(", framework = $%d", argCount)
+		args = append(args, framework)
+		argCount++
+	}
+
 	query += fmt.Sprintf(" WHERE id = $%d", argCount)
 	args = append(args, appID)
 	argCount++
@@ -150,14 +231,73 @@ func (s *AppService) UpdateApp(ctx context.Context, appID, userID string, update
 		argCount++
 	}
 
-	query += " RETURNING id, user_id, name, description, status, prod_version, created_at, updated_at"
+	query += " RETURNING " + appColumns
 
 	app := &models.App{}
-	err := s.DB.QueryRow(ctx, query, args...).Scan(
-		&app.ID, &app.UserID, &app.Name, &app.Description, &app.Status,
-		&app.ProdVersion, &app.CreatedAt, &app.UpdatedAt,
-	)
+	err := scanApp(s.DB.QueryRow(ctx, query, args...), app)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update app: %w", err)
+	}
+
+	return app, nil
+}
+
+// EditApp applies a user's edits to an app's details and bumps its revision.
+// When ifRevision is set the edit only applies if no other edit happened since,
+// so concurrent editors don't overwrite each other; ErrAppModified otherwise.
+// Builds and promotions don't change the revision.
+func (s *AppService) EditApp(ctx context.Context, appID, userID string, req models.UpdateAppRequest, ifRevision *int) (*models.App, error) {
+	if err := normalizeAppEdit(&req); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE apps SET updated_at = $1, revision = revision + 1`
+	args := []interface{}{time.Now()}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(", %s = $%d", column, len(args))
+	}
+
+	if req.Name != nil {
+		set("name", *req.Name)
+	}
+	if req.Description != nil {
+		set("description", *req.Description)
+	}
+	if req.Icon != nil {
+		if *req.Icon == "" {
+			query += ", icon = NULL"
+		} else {
+			set("icon", *req.Icon)
+		}
+	}
+	if req.Tags != nil {
+		set("tags", *req.Tags)
+	}
+	if req.Framework != nil {
+		set("framework", *req.Framework)
+	}
+	if req.Visibility != nil {
+		set("visibility", *req.Visibility)
+	}
 
+	args = append(args, appID, userID)
+	query += fmt.Sprintf(" WHERE id = $%d AND user_id = $%d", len(args)-1, len(args))
+	if ifRevision != nil {
+		args = append(args, *ifRevision)
+		query += fmt.Sprintf(" AND revision = $%d", len(args))
+	}
+	query += " RETURNING " + appColumns
+
+	app := &models.App{}
+	err := scanApp(s.DB.QueryRow(ctx, query, args...), app)
+	if errors.Is(err, db.ErrNoRows) {
+		if _, getErr := s.GetApp(ctx, appID, userID); getErr != nil {
+			return nil, ErrAppNotFound
+		}
+		return nil, ErrAppModified
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update app: %w", err)
 	}
@@ -165,10 +305,41 @@ func (s *AppService) UpdateApp(ctx context.Context, appID, userID string, update
 	return app, nil
 }
 
+// SetDetectedFramework records the framework found by a build, unless the user already chose one
+func (s *AppService) SetDetectedFramework(ctx context.Context, appID, framework string) error {
+	query := `UPDATE apps SET framework = $2, updated_at = NOW() WHERE id = $1 AND framework IS NULL`
+	if _, err := s.DB.Exec(ctx, query, appID, framework); err != nil {
+		return fmt.Errorf("failed to set framework: %w", err)
+	}
+	return nil
+}
+
 // DeleteApp deletes an app
 func (s *AppService) DeleteApp(ctx context.Context, appID, userID string) error {
+	app, err := s.GetApp(ctx, appID, userID)
+	if err != nil {
+		return fmt.Errorf("app not found")
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Queued first: deliveries are matched to webhooks through the app's owner
+	if err := queueWebhooks(ctx, NewJobService(s.DB), tx, models.AppEvent{
+		Type:      EventAppDeleted,
+		AppID:     app.ID,
+		Message:   fmt.Sprintf("App %s was deleted", app.Name),
+		Data:      map[string]interface{}{"name": app.Name},
+		Timestamp: time.Now(),
+	}); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM apps WHERE id = $1 AND user_id = $2`
-	rowsAffected, err := s.DB.Exec(ctx, query, appID, userID)
+	rowsAffected, err := tx.Exec(ctx, query, appID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete app: %w", err)
 	}
@@ -177,6 +348,34 @@ func (s *AppService) DeleteApp(ctx context.Context, appID, userID string) error
 		return fmt.Errorf("app not found")
 	}
 
+	// Projects are named after the app, so the name finds one whose ID was never stored
+	project := app.ID
+	if app.VercelProjectID != nil && *app.VercelProjectID != "" {
+		project = *app.VercelProjectID
+	}
+
+	// External resources are removed by background jobs, committed together with
+	// the delete so nothing is orphaned if the server stops
+	jobs := NewJobService(s.DB)
+	cleanups := []struct {
+		jobType string
+		payload map[string]string
+	}{
+		{JobCleanupS3Prefix, map[string]string{"prefix": fmt.Sprintf("apps/%s/", app.ID)}},
+		{JobCleanupVercelProject, map[string]string{"project": project}},
+		{JobCleanupAppDatabase, map[string]string{"app_id": app.ID}},
+		{JobCleanupAppHistory, map[string]string{"app_id": app.ID}},
+	}
+	for _, cleanup := range cleanups {
+		if err := jobs.EnqueueTx(ctx, tx, cleanup.jobType, cleanup.payload); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to delete app: %w", err)
+	}
+
 	return nil
 }
 
@@ -199,17 +398,13 @@ func (s *AppService) GetAppWithOwnerEmail(ctx context.Context, appID, userID str
 	app := &models.App{}
 
 	query := `
-		SELECT a.id, a.user_id, a.name, a.description, a.status, a.prod_version,
-		       a.created_at, a.updated_at, u.email
+		SELECT ` + prefixColumns("a", appColumns) + `, u.email
 		FROM apps a
 		JOIN users u ON a.user_id = u.id
 		WHERE a.id = $1 AND a.user_id = $2
 	`
 
-	err := s.DB.QueryRow(ctx, query, appID, userID).Scan(
-		&app.ID, &app.UserID, &app.Name, &app.Description, &app.Status,
-		&app.ProdVersion, &app.CreatedAt, &app.UpdatedAt, &email,
-	)
+	err := scanApp(s.DB.QueryRow(ctx, query, appID, userID), app, &email)
 
 	if err != nil {
 		return nil, "", fmt.Errorf("app not found: %w", err)
@@ -217,3 +412,86 @@ func (s *AppService) GetAppWithOwnerEmail(ctx context.Context, appID, userID str
 
 	return app, email, nil
 }
+
+// scanApp scans a row selected with appColumns into app, followed by any extra columns
+func scanApp(row db.Row, app *models.App, extra ...interface{}) error {
+	dest := []interface{}{
+		&app.ID, &app.UserID, &app.Name, &app.Description, &app.Status,
+		&app.ProdVersion, &app.ProdURL, &app.VercelProjectID, &app.SchemaVersion,
+		&app.Icon, &app.Tags, &app.Framework, &app.Visibility, &app.Template,
+		&app.Revision, &app.CreatedAt, &app.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// normalizeAppEdit trims and validates the fields of an app edit
+func normalizeAppEdit(req *models.UpdateAppRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxAppNameLength {
+			return fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidApp, maxAppNameLength)
+		}
+		req.Name = &name
+	}
+
+	if req.Description != nil && utf8.RuneCountInString(*req.Description) > maxAppDescriptionLength {
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidApp, maxAppDescriptionLength)
+	}
+
+	if req.Icon != nil {
+		icon := strings.TrimSpace(*req.Icon)
+		if icon != "" && !validAppIcon(icon) {
+			return fmt.Errorf("%w: icon must be an emoji or an http(s) image URL", ErrInvalidApp)
+		}
+		req.Icon = &icon
+	}
+
+	if req.Tags != nil {
+		tags := []string{}
+		for _, tag := range *req.Tags {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if !tagPattern.MatchString(tag) {
+				return fmt.Errorf("%w: tags must be up to 32 lowercase letters, digits or -", ErrInvalidApp)
+			}
+			if !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > maxAppTags {
+			return fmt.Errorf("%w: at most %d tags", ErrInvalidApp, maxAppTags)
+		}
+		req.Tags = &tags
+	}
+
+	if req.Framework != nil {
+		framework := strings.ToLower(strings.TrimSpace(*req.Framework))
+		if !frameworkPattern.MatchString(framework) {
+			return fmt.Errorf("%w: unknown framework %q", ErrInvalidApp, *req.Framework)
+		}
+		req.Framework = &framework
+	}
+
+	if req.Visibility != nil && !containsString(appVisibilities, *req.Visibility) {
+		return fmt.Errorf("%w: visibility must be one of %s", ErrInvalidApp, strings.Join(appVisibilities, ", "))
+	}
+
+	return nil
+}
+
+// validAppIcon accepts a short emoji (or other symbol) or an image URL
+func validAppIcon(icon string) bool {
+	if strings.HasPrefix(icon, "http://") || strings.HasPrefix(icon, "https://") {
+		u, err := url.Parse(icon)
+		return err == nil && u.Host != "" && len(icon) <= 2048
+	}
+	return utf8.RuneCountInString(icon) <= 8 && !strings.ContainsAny(icon, " <>\"'")
+}
+
+// prefixColumns qualifies each column in a comma separated list with a table alias
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, col := range parts {
+		parts[i] = alias + "." + col
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+func TestNormalizeAppEdit(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tags := func(t ...string) *[]string { return &t }
+	tooManyTags := []string{}
+	for i := 0; i <= maxAppTags; i++ {
+		tooManyTags = append(tooManyTags, fmt.Sprintf("tag-%d", i))
+	}
+
+	tests := []struct {
+		name    string
+		req     models.UpdateAppRequest
+		want    models.UpdateAppRequest
+		wantErr bool
+	}{
+		{
+			name: "empty edit",
+			req:  models.UpdateAppRequest{},
+			want: models.UpdateAppRequest{},
+		},
+		{
+			name: "trims name and icon",
+			req:  models.UpdateAppRequest{Name: str("  Todo  "), Icon: str(" 📝 ")},
+			want: models.UpdateAppRequest{Name: str("Todo"), Icon: str("📝")},
+		},
+		{
+			name: "empty icon clears it",
+			req:  models.UpdateAppRequest{Icon: str("  ")},
+			want: models.UpdateAppRequest{Icon: str("")},
+		},
+		{
+			name: "image icon",
+			req:  models.UpdateAppRequest{Icon: str("https://example.com/icon.png")},
+			want: models.UpdateAppRequest{Icon: str("https://example.com/icon.png")},
+		},
+		{
+			name: "lowercases and dedupes tags",
+			req:  models.UpdateAppRequest{Tags: tags(" CRM ", "crm", "sales-2")},
+			want: models.UpdateAppRequest{Tags: tags("crm", "sales-2")},
+		},
+		{
+			name: "empty tag list",
+			req:  models.UpdateAppRequest{Tags: &[]string{}},
+			want: models.UpdateAppRequest{Tags: &[]string{}},
+		},
+		{
+			name: "lowercases framework",
+			req:  models.UpdateAppRequest{Framework: str(" NextJS ")},
+			want: models.UpdateAppRequest{Framework: str("nextjs")},
+		},
+		{
+			name: "known visibility",
+			req:  models.UpdateAppRequest{Visibility: str("unlisted")},
+			want: models.UpdateAppRequest{Visibility: str("unlisted")},
+		},
+		{name: "blank name", req: models.UpdateAppRequest{Name: str("   ")}, wantErr: true},
+		{name: "long name", req: models.UpdateAppRequest{Name: str(strings.Repeat("a", maxAppNameLength+1))}, wantErr: true},
+		{name: "long description", req: models.UpdateAppRequest{Description: str(strings.Repeat("a", maxAppDescriptionLength+1))}, wantErr: true},
+		{name: "icon with markup", req: models.UpdateAppRequest{Icon: str("<b>")}, wantErr: true},
+		{name: "icon URL without host", req: models.UpdateAppRequest{Icon: str("https://")}, wantErr: true},
+		{name: "invalid tag", req: models.UpdateAppRequest{Tags: tags("no spaces")}, wantErr: true},
+		{name: "too many tags", req: models.UpdateAppRequest{Tags: &tooManyTags}, wantErr: true},
+		{name: "invalid framework", req: models.UpdateAppRequest{Framework: str("next js")}, wantErr: true},
+		{name: "unknown visibility", req: models.UpdateAppRequest{Visibility: str("secret")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := normalizeAppEdit(&req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidApp) {
+					t.Fatalf("normalizeAppEdit() error = %v, want ErrInvalidApp", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeAppEdit() error = %v", err)
+			}
+			if !reflect.DeepEqual(req, tt.want) {
+				t.Errorf("normalizeAppEdit() = %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
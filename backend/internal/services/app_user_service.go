@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrAppUserNotFound  = errors.New("user not found")
+	ErrAppUserExists    = errors.New("a user with this email already exists")
+	ErrInvalidEmail     = errors.New("invalid email")
+	ErrInvalidRole      = errors.New("roles must be lowercase letters, digits, - or _")
+	ErrOwnerAccount     = errors.New("the owner's admin account can't be disabled or lose the admin role")
+	ErrPasswordTooShort = errors.New("password must be at least 8 characters")
+)
+
+// App user statuses
+const (
+	AppUserActive   = "active"
+	AppUserInvited  = "invited"
+	AppUserDisabled = "disabled"
+)
+
+const appInviteExpiry = 7 * 24 * time.Hour
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	rolePattern  = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+)
+
+// AppUserService manages the end users of generated apps in system_db.app_users.
+// Generated apps authenticate against the same records, so invites and resets
+// are stored as tokens the app redeems at /accept-invite and /reset-password.
+type AppUserService struct {
+	Mongo    *mongo.Client
+	Email    *EmailService
+	Versions *VersionService
+}
+
+func NewAppUserService(mongoClient *mongo.Client, emailService *EmailService, versionService *VersionService) *AppUserService {
+	return &AppUserService{Mongo: mongoClient, Email: emailService, Versions: versionService}
+}
+
+// ListUsers lists an app's end users, optionally filtered by role, status or an email/name search
+func (s *AppUserService) ListUsers(ctx context.Context, appID, role, status, search string) ([]models.AppUser, error) {
+	filter := bson.M{"appId": appID}
+	if role != "" {
+		filter["roles"] = role
+	}
+	switch status {
+	case "":
+	case AppUserActive:
+		// Accounts created before statuses were tracked are active
+		filter["status"] = bson.M{"$in": bson.A{AppUserActive, nil}}
+	default:
+		filter["status"] = status
+	}
+	if search != "" {
+		pattern := regexp.QuoteMeta(search)
+		filter["$or"] = bson.A{
+			bson.M{"email": bson.M{"$regex": pattern, "$options": "i"}},
+			bson.M{"name": bson.M{"$regex": pattern, "$options": "i"}},
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetProjection(appUserProjection)
+	cursor, err := s.users().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.AppUser{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+	for i := range users {
+		normalizeAppUser(&users[i])
+	}
+
+	return users, nil
+}
+
+// GetUser returns one of an app's end users
+func (s *AppUserService) GetUser(ctx context.Context, appID, userID string) (*models.AppUser, error) {
+	var user models.AppUser
+	opts := options.FindOne().SetProjection(appUserProjection)
+	err := s.users().FindOne(ctx, bson.M{"_id": userID, "appId": appID}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAppUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	normalizeAppUser(&user)
+	return &user, nil
+}
+
+// GetUserByEmail returns the end user with the given email
+func (s *AppUserService) GetUserByEmail(ctx context.Context, appID, email string) (*models.AppUser, error) {
+	var user models.AppUser
+	opts := options.FindOne().SetProjection(appUserProjection)
+	err := s.users().FindOne(ctx, bson.M{"appId": appID, "email": email}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAppUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	normalizeAppUser(&user)
+	return &user, nil
+}
+
+// InviteUser creates an invited end user and emails them a link to set their
+// password. The link is also returned so the owner can share it directly.
+func (s *AppUserService) InviteUser(ctx context.Context, app *models.App, req models.InviteAppUserRequest) (*models.AppUser, string, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !emailPattern.MatchString(email) {
+		return nil, "", ErrInvalidEmail
+	}
+
+	roles := req.Roles
+	if len(roles) == 0 {
+		roles = []string{"user"}
+	}
+	if err := validateRoles(roles); err != nil {
+		return nil, "", err
+	}
+
+	// The unique index on (appId, email) also catches concurrent invites
+	existing, err := s.users().CountDocuments(ctx, bson.M{"appId": app.ID, "email": email})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check existing users: %w", err)
+	}
+	if existing > 0 {
+		return nil, "", ErrAppUserExists
+	}
+
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate invite token: %w", err)
+	}
+
+	now := time.Now()
+	user := models.AppUser{
+		ID:        uuid.New().String(),
+		AppID:     app.ID,
+		Email:     email,
+		Name:      strings.TrimSpace(req.Name),
+		Roles:     roles,
+		Status:    AppUserInvited,
+		InvitedAt: &now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	doc := bson.M{
+		"_id":             user.ID,
+		"appId":           user.AppID,
+		"email":           user.Email,
+		"roles":           user.Roles,
+		"status":          user.Status,
+		"loginCount":      0,
+		"inviteToken":     token,
+		"inviteExpiresAt": now.Add(appInviteExpiry),
+		"invitedAt":       now,
+		"createdAt":       now,
+		"updatedAt":       now,
+	}
+	if user.Name != "" {
+		doc["name"] = user.Name
+	}
+
+	if _, err := s.users().InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, "", ErrAppUserExists
+		}
+		return nil, "", fmt.Errorf("failed to invite user: %w", err)
+	}
+
+	inviteURL := s.appLink(ctx, app, "/accept-invite", token)
+	if inviteURL != "" {
+		go s.Email.SendAppInviteEmail(email, app.Name, inviteURL)
+	}
+
+	return &user, inviteURL, nil
+}
+
+// UpdateUser changes an end user's roles or enables/disables them. The owner's
+// admin account is protected so they can't lock themselves out.
+func (s *AppUserService) UpdateUser(ctx context.Context, appID, ownerEmail, userID string, req models.UpdateAppUserRequest) (*models.AppUser, error) {
+	user, err := s.GetUser(ctx, appID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	isOwner := strings.EqualFold(user.Email, ownerEmail)
+	set := bson.M{"updatedAt": time.Now()}
+
+	if req.Roles != nil {
+		roles := *req.Roles
+		if err := validateRoles(roles); err != nil {
+			return nil, err
+		}
+		if isOwner && !containsString(roles, "admin") {
+			return nil, ErrOwnerAccount
+		}
+		set["roles"] = roles
+	}
+
+	if req.Disabled != nil {
+		switch {
+		case *req.Disabled && isOwner:
+			return nil, ErrOwnerAccount
+		case *req.Disabled:
+			set["status"] = AppUserDisabled
+		case user.Status == AppUserDisabled:
+			set["status"] = AppUserActive
+		}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(appUserProjection)
+	var updated models.AppUser
+	err = s.users().FindOneAndUpdate(ctx, bson.M{"_id": userID, "appId": appID}, bson.M{"$set": set}, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAppUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	normalizeAppUser(&updated)
+	return &updated, nil
+}
+
+// ResetPassword sets an end user's password, or emails them a reset link when
+// no password is given. Returns the reset link, if one was created.
+func (s *AppUserService) ResetPassword(ctx context.Context, app *models.App, userID, password string) (string, error) {
+	user, err := s.GetUser(ctx, app.ID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	filter := bson.M{"_id": userID, "appId": app.ID}
+	now := time.Now()
+
+	if password != "" {
+		if len(password) < 8 {
+			return "", ErrPasswordTooShort
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"passwordHash": string(hash), "updatedAt": now},
+			"$unset": bson.M{"resetToken": "", "resetExpiresAt": ""},
+		}
+		if _, err := s.users().UpdateOne(ctx, filter, update); err != nil {
+			return "", fmt.Errorf("failed to reset password: %w", err)
+		}
+		return "", nil
+	}
+
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	update := bson.M{"$set": bson.M{
+		"resetToken":     token,
+		"resetExpiresAt": now.Add(resetTokenExpiry),
+		"updatedAt":      now,
+	}}
+	if _, err := s.users().UpdateOne(ctx, filter, update); err != nil {
+		return "", fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	resetURL := s.appLink(ctx, app, "/reset-password", token)
+	if resetURL != "" {
+		go s.Email.SendAppPasswordResetEmail(user.Email, app.Name, resetURL)
+	}
+
+	return resetURL, nil
+}
+
+func (s *AppUserService) users() *mongo.Collection {
+	return s.Mongo.Database(systemDatabase).Collection("app_users")
+}
+
+// appLink builds a link into the generated app: its production URL, or the
+// latest deployed version. Returns "" if the app has never been deployed.
+func (s *AppUserService) appLink(ctx context.Context, app *models.App, path, token string) string {
+	base := ""
+	if app.ProdURL != nil && *app.ProdURL != "" {
+		base = *app.ProdURL
+	} else if versions, err := s.Versions.ListVersions(ctx, app.ID); err == nil {
+		for _, v := range versions {
+			if (v.Status == "completed" || v.Status == "promoted") && v.VercelURL != nil {
+				base = *v.VercelURL
+				break
+			}
+		}
+	}
+	if base == "" {
+		return ""
+	}
+
+	return strings.TrimRight(base, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// appUserProjection keeps password hashes and tokens out of responses
+var appUserProjection = bson.M{
+	"passwordHash":    0,
+	"inviteToken":     0,
+	"inviteExpiresAt": 0,
+	"resetToken":      0,
+	"resetExpiresAt":  0,
+}
+
+// normalizeAppUser fills in fields missing from accounts created before they were tracked
+func normalizeAppUser(user *models.AppUser) {
+	if user.Status == "" {
+		user.Status = AppUserActive
+	}
+	if user.Roles == nil {
+		user.Roles = []string{}
+	}
+}
+
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if !rolePattern.MatchString(role) {
+			return ErrInvalidRole
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+var ErrNoCodeSnapshot = errors.New("version has no code snapshot to clone")
+
+// CloneService creates apps that start from another app's code. A clone shares
+// nothing with its source: it gets its own Vercel project, database and, if
+// requested, copies of the source's secrets.
+type CloneService struct {
+	Apps     *AppService
+	Versions *VersionService
+	Secrets  *SecretService
+	Uploads  *UploadService
+}
+
+func NewCloneService(appService *AppService, versionService *VersionService, secretService *SecretService, uploadService *UploadService) *CloneService {
+	return &CloneService{Apps: appService, Versions: versionService, Secrets: secretService, Uploads: uploadService}
+}
+
+// Clone creates the new app and its first, pending version. It returns the
+// app, the version and the source version whose code snapshot the version
+// should be built from.
+func (s *CloneService) Clone(ctx context.Context, source *models.App, userID string, req models.CloneAppRequest) (*models.App, *models.Version, *models.Version, error) {
+	if req.CopySecrets && s.Secrets.aead == nil {
+		return nil, nil, nil, ErrSecretsDisabled
+	}
+
+	snapshot, err := s.snapshotVersion(ctx, source, req.VersionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = copyName(source.Name)
+	}
+	details := models.UpdateAppRequest{
+		Name:       &name,
+		Icon:       source.Icon,
+		Tags:       &source.Tags,
+		Framework:  source.Framework,
+		Visibility: &source.Visibility,
+	}
+	if err := normalizeAppEdit(&details); err != nil {
+		return nil, nil, nil, err
+	}
+
+	app, err := s.Apps.CreateApp(ctx, userID, models.CreateAppRequest{
+		Name:        *details.Name,
+		Description: source.Description,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	version, err := s.populate(ctx, app, userID, source, snapshot, details, req.CopySecrets)
+	if err != nil {
+		// Removes anything copied so far along with the app
+		if delErr := s.Apps.DeleteApp(ctx, app.ID, userID); delErr != nil {
+			log.Printf("[Clone] Failed to remove incomplete clone %s: %v\n", app.ID, delErr)
+		}
+		return nil, nil, nil, err
+	}
+
+	return app, version, snapshot, nil
+}
+
+// copyName is the default name of a clone, shortening the source's name so the
+// suffix still fits
+func copyName(sourceName string) string {
+	const suffix = " (copy)"
+	name := []rune(strings.TrimSpace(sourceName))
+	if limit := maxAppNameLength - utf8.RuneCountInString(suffix); len(name) > limit {
+		name = []rune(strings.TrimSpace(string(name[:limit])))
+	}
+	return string(name) + suffix
+}
+
+// populate copies the source app's details, requirement files and secrets to
+// the clone and creates its first version
+func (s *CloneService) populate(ctx context.Context, app *models.App, userID string, source *models.App, snapshot *models.Version, details models.UpdateAppRequest, copySecrets bool) (*models.Version, error) {
+	details.Name = nil
+	edited, err := s.Apps.EditApp(ctx, app.ID, userID, details, nil)
+	if err != nil {
+		return nil, err
+	}
+	*app = *edited
+
+	version, err := s.Versions.CreateVersion(ctx, app.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Uploads.CopyRequirementFiles(ctx, source.ID, snapshot.VersionNumber, app.ID, version.ID); err != nil {
+		return nil, err
+	}
+
+	if copySecrets {
+		if err := s.Secrets.CopySecrets(ctx, source.ID, app.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return version, nil
+}
+
+// snapshotVersion finds the version to clone: the given one, else the
+// production version, else the newest deployed one
+func (s *CloneService) snapshotVersion(ctx context.Context, app *models.App, versionID string) (*models.Version, error) {
+	hasCode := func(v *models.Version) bool {
+		return v.S3CodePath != nil && *v.S3CodePath != ""
+	}
+
+	if versionID != "" {
+		version, err := s.Versions.GetVersion(ctx, versionID)
+		if err != nil || version.AppID != app.ID {
+			return nil, ErrVersionNotFound
+		}
+		if !hasCode(version) {
+			return nil, ErrNoCodeSnapshot
+		}
+		return version, nil
+	}
+
+	versions, err := s.Versions.ListVersions(ctx, app.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	var latest *models.Version
+	for i := range versions {
+		v := &versions[i]
+		if !hasCode(v) || (v.Status != "completed" && v.Status != "promoted") {
+			continue
+		}
+		if app.ProdVersion != nil && v.VersionNumber == *app.ProdVersion {
+			return v, nil
+		}
+		if latest == nil {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return nil, ErrNoCodeSnapshot
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCopyName(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"short name", "Todo app", "Todo app (copy)"},
+		{"longest name that fits", strings.Repeat("a", 93), strings.Repeat("a", 93) + " (copy)"},
+		{"name at the limit", strings.Repeat("a", 100), strings.Repeat("a", 93) + " (copy)"},
+		{"cut at a space", strings.Repeat("a", 92) + " bcdef", strings.Repeat("a", 92) + " (copy)"},
+		{"multibyte name", strings.Repeat("é", 100), strings.Repeat("é", 93) + " (copy)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := copyName(tt.source)
+			if got != tt.want {
+				t.Errorf("copyName() = %q, want %q", got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > maxAppNameLength {
+				t.Errorf("copyName() is %d characters, max is %d", n, maxAppNameLength)
+			}
+		})
+	}
+}
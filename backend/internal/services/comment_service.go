@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +11,21 @@ import (
 	"github.com/rapidbuildapp/rapidbuild/internal/models"
 )
 
+// commentColumns is the column list scanned by scanComment
+const commentColumns = "id, app_id, user_id, version_id, page_path, element_path, content, status, author_name, share_link_id, created_at, submitted_at"
+
+// commentSummaryColumns is commentColumns with the content shortened, for list summaries
+const commentSummaryColumns = "id, app_id, user_id, version_id, page_path, element_path, LEFT(content, 200), status, author_name, share_link_id, created_at, submitted_at"
+
+// commentListSpec is how list queries apply to comments
+var commentListSpec = listSpec{
+	sorts: map[string]sortColumn{
+		"created_at": {"created_at", "timestamptz"},
+	},
+	defaultSort:   "-created_at",
+	searchColumns: []string{"content", "page_path", "author_name"},
+}
+
 type CommentService struct {
 	DB *db.PostgresClient
 }
@@ -34,17 +50,12 @@ func (s *CommentService) AddComment(ctx context.Context, userID, appID string, r
 	query := `
 		INSERT INTO comments (id, app_id, user_id, page_path, element_path, content, status, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, app_id, user_id, version_id, page_path, element_path, content, status, created_at, submitted_at
-	`
+		RETURNING ` + commentColumns
 
-	err := s.DB.QueryRow(ctx, query,
+	err := scanComment(s.DB.QueryRow(ctx, query,
 		comment.ID, comment.AppID, comment.UserID, comment.PagePath,
 		comment.ElementPath, comment.Content, comment.Status, comment.CreatedAt,
-	).Scan(
-		&comment.ID, &comment.AppID, &comment.UserID, &comment.VersionID,
-		&comment.PagePath, &comment.ElementPath, &comment.Content,
-		&comment.Status, &comment.CreatedAt, &comment.SubmittedAt,
-	)
+	), &comment)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create comment: %w", err)
@@ -53,30 +64,63 @@ func (s *CommentService) AddComment(ctx context.Context, userID, appID string, r
 	return &comment, nil
 }
 
-// GetDraftComments retrieves all draft comments for an app
-func (s *CommentService) GetDraftComments(ctx context.Context, appID, userID string) ([]models.Comment, error) {
+// AddShareComment creates a draft comment left anonymously through a share link.
+// It is owned by the link's creator so it joins their drafts for the next version.
+func (s *CommentService) AddShareComment(ctx context.Context, link *models.ShareLink, req models.AddShareCommentRequest) (*models.Comment, error) {
+	authorName := strings.TrimSpace(req.AuthorName)
+	if authorName == "" {
+		authorName = "Anonymous"
+	}
+
+	comment := models.Comment{
+		ID:          uuid.New().String(),
+		AppID:       link.AppID,
+		UserID:      link.CreatedBy,
+		PagePath:    req.PagePath,
+		ElementPath: req.ElementPath,
+		Content:     req.Content,
+		Status:      "draft",
+		AuthorName:  &authorName,
+		ShareLinkID: &link.ID,
+		CreatedAt:   time.Now(),
+	}
+
 	query := `
-		SELECT id, app_id, user_id, version_id, page_path, element_path, content, status, created_at, submitted_at
+		INSERT INTO comments (id, app_id, user_id, page_path, element_path, content, status, author_name, share_link_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + commentColumns
+
+	err := scanComment(s.DB.QueryRow(ctx, query,
+		comment.ID, comment.AppID, comment.UserID, comment.PagePath, comment.ElementPath,
+		comment.Content, comment.Status, comment.AuthorName, comment.ShareLinkID, comment.CreatedAt,
+	), &comment)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return &comment, nil
+}
+
+// GetVersionComments retrieves all comments for a specific version
+func (s *CommentService) GetVersionComments(ctx context.Context, versionID string) ([]models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
 		FROM comments
-		WHERE app_id = $1 AND user_id = $2 AND status = 'draft'
-		ORDER BY created_at DESC
+		WHERE version_id = $1
+		ORDER BY created_at ASC
 	`
 
-	rows, err := s.DB.Query(ctx, query, appID, userID)
+	rows, err := s.DB.Query(ctx, query, versionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get draft comments: %w", err)
+		return nil, fmt.Errorf("failed to get version comments: %w", err)
 	}
 	defer rows.Close()
 
 	var comments []models.Comment
 	for rows.Next() {
 		var comment models.Comment
-		err := rows.Scan(
-			&comment.ID, &comment.AppID, &comment.UserID, &comment.VersionID,
-			&comment.PagePath, &comment.ElementPath, &comment.Content,
-			&comment.Status, &comment.CreatedAt, &comment.SubmittedAt,
-		)
-		if err != nil {
+		if err := scanComment(rows, &comment); err != nil {
 			return nil, fmt.Errorf("failed to scan comment: %w", err)
 		}
 		comments = append(comments, comment)
@@ -89,40 +133,59 @@ func (s *CommentService) GetDraftComments(ctx context.Context, appID, userID str
 	return comments, nil
 }
 
-// GetVersionComments retrieves all comments for a specific version
-func (s *CommentService) GetVersionComments(ctx context.Context, versionID string) ([]models.Comment, error) {
-	query := `
-		SELECT id, app_id, user_id, version_id, page_path, element_path, content, status, created_at, submitted_at
-		FROM comments
-		WHERE version_id = $1
-		ORDER BY created_at ASC
-	`
+// ListComments retrieves a page of the user's comments on an app, drafts
+// unless q filters by status, and the cursor of the next page
+func (s *CommentService) ListComments(ctx context.Context, appID, userID string, q models.ListQuery) ([]models.Comment, string, error) {
+	if len(q.Status) == 0 {
+		q.Status = []string{"draft"}
+	}
+	return s.queryComments(ctx, "app_id = $1 AND user_id = $2", []interface{}{appID, userID}, q)
+}
 
-	rows, err := s.DB.Query(ctx, query, versionID)
+// ListVersionComments retrieves a page of the comments submitted with a
+// version, oldest first unless q sorts otherwise
+func (s *CommentService) ListVersionComments(ctx context.Context, appID, versionID string, q models.ListQuery) ([]models.Comment, string, error) {
+	if q.Sort == "" {
+		q.Sort = "created_at"
+	}
+	return s.queryComments(ctx, "app_id = $1 AND version_id = $2", []interface{}{appID, versionID}, q)
+}
+
+func (s *CommentService) queryComments(ctx context.Context, where string, args []interface{}, q models.ListQuery) ([]models.Comment, string, error) {
+	plan, err := planList(q, commentListSpec, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get version comments: %w", err)
+		return nil, "", err
+	}
+
+	columns := commentColumns
+	if q.Summary {
+		columns = commentSummaryColumns
+	}
+	query := `SELECT ` + columns + ` FROM comments WHERE ` + where + plan.where() + plan.tail()
+
+	rows, err := s.DB.Query(ctx, query, plan.args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list comments: %w", err)
 	}
 	defer rows.Close()
 
-	var comments []models.Comment
+	comments := []models.Comment{}
 	for rows.Next() {
 		var comment models.Comment
-		err := rows.Scan(
-			&comment.ID, &comment.AppID, &comment.UserID, &comment.VersionID,
-			&comment.PagePath, &comment.ElementPath, &comment.Content,
-			&comment.Status, &comment.CreatedAt, &comment.SubmittedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, "", fmt.Errorf("failed to scan comment: %w", err)
 		}
 		comments = append(comments, comment)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating comments: %w", err)
+		return nil, "", fmt.Errorf("error iterating comments: %w", err)
 	}
 
-	return comments, nil
+	comments, next := page(plan, comments, func(c models.Comment, _ string) (string, string) {
+		return c.CreatedAt.Format(time.RFC3339Nano), c.ID
+	})
+	return comments, next, nil
 }
 
 // SubmitComments submits draft comments by binding them to a version
@@ -160,3 +223,12 @@ func (s *CommentService) DeleteComment(ctx context.Context, commentID, userID st
 
 	return nil
 }
+
+// scanComment scans a row selected with commentColumns into comment
+func scanComment(row db.Row, comment *models.Comment) error {
+	return row.Scan(
+		&comment.ID, &comment.AppID, &comment.UserID, &comment.VersionID,
+		&comment.PagePath, &comment.ElementPath, &comment.Content, &comment.Status,
+		&comment.AuthorName, &comment.ShareLinkID, &comment.CreatedAt, &comment.SubmittedAt,
+	)
+}
@@ -0,0 +1,512 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrDocumentNotFound   = errors.New("document not found")
+	ErrInvalidFilter      = errors.New("invalid filter")
+	ErrInvalidDocument    = errors.New("invalid document")
+)
+
+// Data browser audit actions
+const (
+	DataAuditInsert = "insert"
+	DataAuditUpdate = "update"
+	DataAuditDelete = "delete"
+)
+
+// Page size limits for FindDocuments
+const (
+	defaultDocumentLimit = 50
+	maxDocumentLimit     = 200
+)
+
+// mongoDocumentValidationFailure is the server error code for a write rejected by a collection validator
+const mongoDocumentValidationFailure = 121
+
+// forbiddenQueryOperators run server-side JavaScript and are never accepted in filters
+var forbiddenQueryOperators = map[string]bool{"$where": true, "$function": true, "$accumulator": true}
+
+// DataService lets app owners browse and edit the documents of their generated
+// app's database. Writes are checked against the stored schema and audited.
+type DataService struct {
+	DB        *db.PostgresClient
+	Databases *AppDatabaseService
+	Schemas   *SchemaService
+}
+
+func NewDataService(dbClient *db.PostgresClient, databases *AppDatabaseService, schemas *SchemaService) *DataService {
+	return &DataService{DB: dbClient, Databases: databases, Schemas: schemas}
+}
+
+// ListCollections lists an app database's collections with their document counts and schemas
+func (s *DataService) ListCollections(ctx context.Context, appID string) ([]models.DataCollection, error) {
+	database, err := s.database(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := userCollectionNames(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := s.schemas(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := make([]models.DataCollection, 0, len(names))
+	for _, name := range names {
+		count, err := database.Collection(name).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", name, err)
+		}
+
+		collection := models.DataCollection{Name: name, DocumentCount: count}
+		if schema, ok := schemas[name]; ok {
+			collection.Schema = &schema
+		}
+		collections = append(collections, collection)
+	}
+
+	return collections, nil
+}
+
+// FindDocuments returns a page of documents matching the query
+func (s *DataService) FindDocuments(ctx context.Context, appID, collectionName string, query models.DocumentQuery) (*models.DocumentPage, error) {
+	collection, err := s.collection(ctx, appID, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	if strings.TrimSpace(query.Filter) != "" {
+		if err := bson.UnmarshalExtJSON([]byte(query.Filter), false, &filter); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		if op := forbiddenOperator(filter); op != "" {
+			return nil, fmt.Errorf("%w: %s is not allowed", ErrInvalidFilter, op)
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultDocumentLimit
+	}
+	if limit > maxDocumentLimit {
+		limit = maxDocumentLimit
+	}
+	skip := query.Skip
+	if skip < 0 {
+		skip = 0
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, queryError(err)
+	}
+
+	opts := options.Find().SetLimit(limit).SetSkip(skip).SetSort(parseSort(query.Sort))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer cursor.Close(ctx)
+
+	page := &models.DocumentPage{Documents: []json.RawMessage{}, Total: total, Limit: limit, Skip: skip}
+	for cursor.Next(ctx) {
+		doc, err := bson.MarshalExtJSON(cursor.Current, false, false)
+		if err != nil {
+			return nil, err
+		}
+		page.Documents = append(page.Documents, doc)
+	}
+
+	return page, cursor.Err()
+}
+
+// GetDocument returns one document as relaxed extended JSON
+func (s *DataService) GetDocument(ctx context.Context, appID, collectionName, documentID string) (json.RawMessage, error) {
+	collection, err := s.collection(ctx, appID, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.M
+	if err := collection.FindOne(ctx, documentIDFilter(documentID)).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, err
+	}
+
+	return bson.MarshalExtJSON(doc, false, false)
+}
+
+// InsertDocument inserts a document given as extended JSON
+func (s *DataService) InsertDocument(ctx context.Context, appID, userID, collectionName string, body []byte) (json.RawMessage, error) {
+	collection, err := s.collection(ctx, appID, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseDocument(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, appID, collectionName, doc); err != nil {
+		return nil, err
+	}
+
+	result, err := collection.InsertOne(ctx, doc)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	doc["_id"] = result.InsertedID
+
+	after, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, appID, userID, collectionName, result.InsertedID, DataAuditInsert, nil, after)
+	return after, nil
+}
+
+// UpdateDocument sets the given top-level fields on a document. The merged
+// document is validated as a whole, but only the given fields are written so
+// concurrent changes to other fields are kept.
+func (s *DataService) UpdateDocument(ctx context.Context, appID, userID, collectionName, documentID string, body []byte) (json.RawMessage, error) {
+	collection, err := s.collection(ctx, appID, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := parseDocument(body)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fields["_id"]; ok {
+		return nil, fmt.Errorf("%w: _id cannot be changed", ErrInvalidDocument)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: no fields to update", ErrInvalidDocument)
+	}
+
+	var original bson.M
+	if err := collection.FindOne(ctx, documentIDFilter(documentID)).Decode(&original); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, err
+	}
+
+	before, err := bson.MarshalExtJSON(original, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := bson.M{}
+	for key, value := range original {
+		updated[key] = value
+	}
+	for key, value := range fields {
+		updated[key] = value
+	}
+
+	if err := s.validate(ctx, appID, collectionName, updated); err != nil {
+		return nil, err
+	}
+
+	var written bson.M
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": original["_id"]}, bson.M{"$set": fields}, opts).Decode(&written)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, writeError(err)
+	}
+
+	after, err := bson.MarshalExtJSON(written, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, appID, userID, collectionName, original["_id"], DataAuditUpdate, before, after)
+	return after, nil
+}
+
+// DeleteDocument deletes a document
+func (s *DataService) DeleteDocument(ctx context.Context, appID, userID, collectionName, documentID string) error {
+	collection, err := s.collection(ctx, appID, collectionName)
+	if err != nil {
+		return err
+	}
+
+	var deleted bson.M
+	if err := collection.FindOneAndDelete(ctx, documentIDFilter(documentID)).Decode(&deleted); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrDocumentNotFound
+		}
+		return err
+	}
+
+	before, err := bson.MarshalExtJSON(deleted, false, false)
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, appID, userID, collectionName, deleted["_id"], DataAuditDelete, before, nil)
+	return nil
+}
+
+// ListAuditLog returns the most recent data browser changes to an app's database
+func (s *DataService) ListAuditLog(ctx context.Context, appID string, limit int) ([]models.DataAuditEntry, error) {
+	if limit <= 0 || limit > maxDocumentLimit {
+		limit = defaultDocumentLimit
+	}
+
+	query := `
+		SELECT id, app_id, user_id, collection, document_id, action, before, after, created_at
+		FROM app_data_audit_log
+		WHERE app_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.DB.Query(ctx, query, appID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.DataAuditEntry{}
+	for rows.Next() {
+		var e models.DataAuditEntry
+		if err := rows.Scan(&e.ID, &e.AppID, &e.UserID, &e.Collection, &e.DocumentID, &e.Action, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// audit records a change. The write has already happened, so a failure to
+// record it is logged rather than reported to the caller.
+func (s *DataService) audit(ctx context.Context, appID, userID, collection string, documentID interface{}, action string, before, after json.RawMessage) {
+	query := `
+		INSERT INTO app_data_audit_log (id, app_id, user_id, collection, document_id, action, before, after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := s.DB.Exec(ctx, query,
+		uuid.New().String(), appID, userID, collection, documentIDString(documentID), action,
+		nullableJSON(before), nullableJSON(after), time.Now(),
+	)
+	if err != nil {
+		log.Printf("[Data] Failed to audit %s on %s/%s for app %s: %v\n", action, collection, documentIDString(documentID), appID, err)
+	}
+}
+
+// database returns the app's MongoDB database
+func (s *DataService) database(ctx context.Context, appID string) (*mongo.Database, error) {
+	appDB, err := s.Databases.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Databases.Mongo.Database(appDB.DatabaseName), nil
+}
+
+// collection returns one of the app's collections, or ErrCollectionNotFound
+func (s *DataService) collection(ctx context.Context, appID, name string) (*mongo.Collection, error) {
+	if name == "" || strings.HasPrefix(name, "system.") {
+		return nil, ErrCollectionNotFound
+	}
+
+	database, err := s.database(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := database.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, ErrCollectionNotFound
+	}
+
+	return database.Collection(name), nil
+}
+
+// schemas returns the collections of the app's last applied schema by name
+func (s *DataService) schemas(ctx context.Context, appID string) (map[string]models.CollectionSchema, error) {
+	migration, err := s.Schemas.LatestMigration(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := map[string]models.CollectionSchema{}
+	if migration != nil {
+		for _, schema := range migration.Schema {
+			schemas[schema.Name] = schema
+		}
+	}
+	return schemas, nil
+}
+
+// validate checks a document against the collection's stored schema, if it has one
+func (s *DataService) validate(ctx context.Context, appID, collection string, doc bson.M) error {
+	schemas, err := s.schemas(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	schema, ok := schemas[collection]
+	if !ok || schema.Validator == nil {
+		return nil
+	}
+	return validateDocument(schema.Validator, doc)
+}
+
+// userCollectionNames lists an app database's collections, excluding system collections
+func userCollectionNames(ctx context.Context, database *mongo.Database) ([]string, error) {
+	names, err := database.ListCollectionNames(ctx, bson.M{"name": bson.M{"$not": bson.M{"$regex": "^system\\."}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// parseDocument decodes a request body given as canonical or relaxed extended JSON
+func parseDocument(body []byte) (bson.M, error) {
+	var doc bson.M
+	if err := bson.UnmarshalExtJSON(body, false, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%w: expected a JSON object", ErrInvalidDocument)
+	}
+	return doc, nil
+}
+
+// parseSort turns "-createdAt,name" into a sort document; the default is newest first by _id
+func parseSort(sortSpec string) bson.D {
+	sortDoc := bson.D{}
+	for _, field := range strings.Split(sortSpec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || field == "-" {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			sortDoc = append(sortDoc, bson.E{Key: field[1:], Value: -1})
+		} else {
+			sortDoc = append(sortDoc, bson.E{Key: field, Value: 1})
+		}
+	}
+	if len(sortDoc) == 0 {
+		sortDoc = bson.D{{Key: "_id", Value: -1}}
+	}
+	return sortDoc
+}
+
+// forbiddenOperator returns the first operator in a filter that is not allowed, or ""
+func forbiddenOperator(value interface{}) string {
+	switch v := value.(type) {
+	case bson.M:
+		for key, nested := range v {
+			if forbiddenQueryOperators[key] {
+				return key
+			}
+			if op := forbiddenOperator(nested); op != "" {
+				return op
+			}
+		}
+	case bson.D:
+		for _, e := range v {
+			if forbiddenQueryOperators[e.Key] {
+				return e.Key
+			}
+			if op := forbiddenOperator(e.Value); op != "" {
+				return op
+			}
+		}
+	case bson.A:
+		for _, nested := range v {
+			if op := forbiddenOperator(nested); op != "" {
+				return op
+			}
+		}
+	}
+	return ""
+}
+
+// documentIDFilter matches a document by its _id given as a string, which may
+// be the hex form of an ObjectID
+func documentIDFilter(documentID string) bson.M {
+	if oid, err := primitive.ObjectIDFromHex(documentID); err == nil {
+		return bson.M{"_id": bson.M{"$in": bson.A{oid, documentID}}}
+	}
+	return bson.M{"_id": documentID}
+}
+
+func documentIDString(id interface{}) string {
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprint(id)
+}
+
+// queryError maps server rejections of a user supplied filter to ErrInvalidFilter
+func queryError(err error) error {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code != 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidFilter, cmdErr.Message)
+	}
+	return err
+}
+
+// writeError maps validator and duplicate key rejections to ErrInvalidDocument
+func writeError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: duplicate key", ErrInvalidDocument)
+	}
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == mongoDocumentValidationFailure {
+				return fmt.Errorf("%w: rejected by the collection validator", ErrInvalidDocument)
+			}
+		}
+	}
+	return err
+}
+
+func nullableJSON(data json.RawMessage) interface{} {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+var (
+	ErrInvalidDomain   = errors.New("invalid domain name")
+	ErrDomainTaken     = errors.New("domain is already attached to an app")
+	ErrNoVercelProject = errors.New("app has no Vercel project yet, deploy a version first")
+	ErrDomainNotFound  = errors.New("domain not found")
+)
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+const domainColumns = "id, app_id, domain, verified, verification, verified_at, created_at"
+
+type DomainService struct {
+	DB     *db.PostgresClient
+	Vercel *VercelService
+}
+
+func NewDomainService(dbClient *db.PostgresClient, vercelService *VercelService) *DomainService {
+	return &DomainService{DB: dbClient, Vercel: vercelService}
+}
+
+// normalizeDomain lowercases a domain and strips a scheme, path or trailing dot
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "https://")
+	domain = strings.TrimPrefix(domain, "http://")
+	if i := strings.Index(domain, "/"); i >= 0 {
+		domain = domain[:i]
+	}
+	domain = strings.TrimSuffix(domain, ".")
+
+	if len(domain) > 253 || !domainPattern.MatchString(domain) {
+		return "", ErrInvalidDomain
+	}
+	// Vercel-owned hostnames are assigned automatically
+	if strings.HasSuffix(domain, ".vercel.app") {
+		return "", ErrInvalidDomain
+	}
+	return domain, nil
+}
+
+// AddDomain attaches a custom domain to the app's Vercel project
+func (s *DomainService) AddDomain(ctx context.Context, app *models.App, domain string) (*models.AppDomain, error) {
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.VercelProjectID == nil || *app.VercelProjectID == "" {
+		return nil, ErrNoVercelProject
+	}
+
+	var exists bool
+	if err := s.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM app_domains WHERE domain = $1)`, domain).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check domain: %w", err)
+	}
+	if exists {
+		return nil, ErrDomainTaken
+	}
+
+	projectDomain, err := s.Vercel.AddProjectDomain(*app.VercelProjectID, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	appDomain := models.AppDomain{
+		ID:           uuid.New().String(),
+		AppID:        app.ID,
+		Domain:       domain,
+		Verified:     projectDomain.Verified,
+		Verification: domainVerification(projectDomain),
+		CreatedAt:    time.Now(),
+	}
+	if appDomain.Verified {
+		now := time.Now()
+		appDomain.VerifiedAt = &now
+	}
+
+	verificationJSON, err := json.Marshal(appDomain.Verification)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO app_domains (id, app_id, domain, verified, verification, verified_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + domainColumns
+
+	err = scanDomain(s.DB.QueryRow(ctx, query,
+		appDomain.ID, appDomain.AppID, appDomain.Domain, appDomain.Verified,
+		string(verificationJSON), appDomain.VerifiedAt, appDomain.CreatedAt,
+	), &appDomain)
+	if err != nil {
+		// Don't leave the domain attached on Vercel without a record of it
+		if removeErr := s.Vercel.RemoveProjectDomain(*app.VercelProjectID, domain); removeErr != nil {
+			log.Printf("[Domains] Warning: Failed to detach %s after insert error: %v\n", domain, removeErr)
+		}
+		return nil, fmt.Errorf("failed to save domain: %w", err)
+	}
+
+	if appDomain.Verified {
+		s.aliasToProduction(ctx, app, domain)
+	}
+
+	return &appDomain, nil
+}
+
+// ListDomains returns an app's custom domains
+func (s *DomainService) ListDomains(ctx context.Context, appID string) ([]models.AppDomain, error) {
+	query := `SELECT ` + domainColumns + ` FROM app_domains WHERE app_id = $1 ORDER BY created_at ASC`
+
+	rows, err := s.DB.Query(ctx, query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := []models.AppDomain{}
+	for rows.Next() {
+		var domain models.AppDomain
+		if err := scanDomain(rows, &domain); err != nil {
+			return nil, fmt.Errorf("failed to scan domain: %w", err)
+		}
+		domains = append(domains, domain)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating domains: %w", err)
+	}
+
+	return domains, nil
+}
+
+// GetDomain retrieves one of an app's domains
+func (s *DomainService) GetDomain(ctx context.Context, appID, domainID string) (*models.AppDomain, error) {
+	query := `SELECT ` + domainColumns + ` FROM app_domains WHERE id = $1 AND app_id = $2`
+
+	var domain models.AppDomain
+	if err := scanDomain(s.DB.QueryRow(ctx, query, domainID, appID), &domain); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDomainNotFound, err)
+	}
+
+	return &domain, nil
+}
+
+// VerifyDomain re-checks a domain's DNS with Vercel and stores the result. Once
+// verified, the domain is pointed at the app's production deployment.
+func (s *DomainService) VerifyDomain(ctx context.Context, app *models.App, domainID string) (*models.AppDomain, error) {
+	domain, err := s.GetDomain(ctx, app.ID, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.VercelProjectID == nil || *app.VercelProjectID == "" {
+		return nil, ErrNoVercelProject
+	}
+	projectID := *app.VercelProjectID
+
+	// Vercel answers 400 while the records are missing, so fall back to reading
+	// the domain to get the records still required
+	projectDomain, err := s.Vercel.VerifyProjectDomain(projectID, domain.Domain)
+	if err != nil {
+		log.Printf("[Domains] Verification of %s not complete: %v\n", domain.Domain, err)
+		projectDomain, err = s.Vercel.GetProjectDomain(projectID, domain.Domain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get domain status: %w", err)
+		}
+	}
+
+	wasVerified := domain.Verified
+	domain.Verified = projectDomain.Verified
+	domain.Verification = domainVerification(projectDomain)
+	if domain.Verified && domain.VerifiedAt == nil {
+		now := time.Now()
+		domain.VerifiedAt = &now
+	}
+	if !domain.Verified {
+		domain.VerifiedAt = nil
+	}
+
+	verificationJSON, err := json.Marshal(domain.Verification)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE app_domains SET verified = $1, verification = $2, verified_at = $3
+		WHERE id = $4
+		RETURNING ` + domainColumns
+
+	err = scanDomain(s.DB.QueryRow(ctx, query,
+		domain.Verified, string(verificationJSON), domain.VerifiedAt, domain.ID,
+	), domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update domain: %w", err)
+	}
+
+	if domain.Verified && !wasVerified {
+		s.aliasToProduction(ctx, app, domain.Domain)
+	}
+
+	return domain, nil
+}
+
+// RemoveDomain detaches a domain from Vercel and deletes it
+func (s *DomainService) RemoveDomain(ctx context.Context, app *models.App, domainID string) error {
+	domain, err := s.GetDomain(ctx, app.ID, domainID)
+	if err != nil {
+		return err
+	}
+
+	if app.VercelProjectID != nil && *app.VercelProjectID != "" {
+		if err := s.Vercel.RemoveProjectDomain(*app.VercelProjectID, domain.Domain); err != nil && !IsVercelNotFound(err) {
+			return err
+		}
+	}
+
+	if _, err := s.DB.Exec(ctx, `DELETE FROM app_domains WHERE id = $1`, domain.ID); err != nil {
+		return fmt.Errorf("failed to delete domain: %w", err)
+	}
+
+	return nil
+}
+
+// AliasVerifiedDomains points every verified domain of an app at a deployment,
+// so custom domains follow promotions
+func (s *DomainService) AliasVerifiedDomains(ctx context.Context, appID, deploymentID string) {
+	domains, err := s.ListDomains(ctx, appID)
+	if err != nil {
+		log.Printf("[Domains] Warning: Failed to list domains for app %s: %v\n", appID, err)
+		return
+	}
+
+	for _, domain := range domains {
+		if !domain.Verified {
+			continue
+		}
+		if err := s.Vercel.AssignAlias(deploymentID, domain.Domain); err != nil {
+			log.Printf("[Domains] Warning: %v\n", err)
+			continue
+		}
+		log.Printf("[Domains] %s now serves deployment %s\n", domain.Domain, deploymentID)
+	}
+}
+
+// aliasToProduction points a newly verified domain at the current production deployment
+func (s *DomainService) aliasToProduction(ctx context.Context, app *models.App, domain string) {
+	if app.ProdVersion == nil {
+		return
+	}
+
+	version, err := NewVersionService(s.DB, s.Vercel, nil).GetVersionByNumber(ctx, app.ID, *app.ProdVersion)
+	if err != nil || productionDeployID(version) == nil {
+		return
+	}
+
+	if err := s.Vercel.AssignAlias(*productionDeployID(version), domain); err != nil {
+		log.Printf("[Domains] Warning: %v\n", err)
+	}
+}
+
+// domainVerification converts Vercel's pending verification records
+func domainVerification(projectDomain *VercelProjectDomain) []models.DomainVerification {
+	records := []models.DomainVerification{}
+	for _, v := range projectDomain.Verification {
+		records = append(records, models.DomainVerification{
+			Type:   v.Type,
+			Domain: v.Domain,
+			Value:  v.Value,
+			Reason: v.Reason,
+		})
+	}
+	return records
+}
+
+// scanDomain scans a row selected with domainColumns into domain
+func scanDomain(row db.Row, domain *models.AppDomain) error {
+	return row.Scan(
+		&domain.ID, &domain.AppID, &domain.Domain, &domain.Verified,
+		&domain.Verification, &domain.VerifiedAt, &domain.CreatedAt,
+	)
+}
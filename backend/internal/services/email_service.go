@@ -80,6 +80,58 @@ func (s *EmailService) SendPasswordResetEmail(email, fullName, token string) err
 	return s.sendEmail(email, subject, body)
 }
 
+// SendAppInviteEmail invites an end user to a generated app
+func (s *EmailService) SendAppInviteEmail(email, appName, inviteURL string) error {
+	subject := fmt.Sprintf("You're invited to %s", appName)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #3B82F6;">You're invited to %s</h2>
+				<p>Hi,</p>
+				<p>You have been invited to join %s. Click the button below to set your password and sign in:</p>
+				<div style="margin: 30px 0;">
+					<a href="%s" style="background-color: #3B82F6; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">
+						Accept Invitation
+					</a>
+				</div>
+				<p>Or copy and paste this link into your browser:</p>
+				<p style="color: #666; word-break: break-all;">%s</p>
+				<p>This link will expire in 7 days.</p>
+			</div>
+		</body>
+		</html>
+	`, appName, appName, inviteURL, inviteURL)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendAppPasswordResetEmail sends an end user of a generated app a password reset link
+func (s *EmailService) SendAppPasswordResetEmail(email, appName, resetURL string) error {
+	subject := fmt.Sprintf("Reset your %s password", appName)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #3B82F6;">Password Reset</h2>
+				<p>Hi,</p>
+				<p>The owner of %s has reset your password. Click the button below to choose a new one:</p>
+				<div style="margin: 30px 0;">
+					<a href="%s" style="background-color: #3B82F6; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">
+						Reset Password
+					</a>
+				</div>
+				<p>Or copy and paste this link into your browser:</p>
+				<p style="color: #666; word-break: break-all;">%s</p>
+				<p>This link will expire in 1 hour.</p>
+			</div>
+		</body>
+		</html>
+	`, appName, resetURL, resetURL)
+
+	return s.sendEmail(email, subject, body)
+}
+
 // sendEmail sends an email via SMTP
 func (s *EmailService) sendEmail(to, subject, htmlBody string) error {
 	from := s.Config.SMTPFrom
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Event types raised by the platform
+const (
+	EventDeploymentBroken    = "deployment.broken"
+	EventDeploymentRecovered = "deployment.recovered"
+	EventVersionBuilding     = "version.building"
+	EventVersionCompleted    = "version.completed"
+	EventVersionFailed       = "version.failed"
+	EventVersionPromoted     = "version.promoted"
+	EventAppDeleted          = "app.deleted"
+	EventPing                = "ping" // sent to a webhook on request, never subscribed to
+)
+
+type EventService struct {
+	Redis *redis.Client
+	DB    *db.PostgresClient
+}
+
+func NewEventService(redisClient *redis.Client, dbClient *db.PostgresClient) *EventService {
+	return &EventService{Redis: redisClient, DB: dbClient}
+}
+
+// Publish raises an app event on the app's Redis channel (app:events:{appID})
+// and queues it for the webhooks subscribed to it
+func (s *EventService) Publish(ctx context.Context, event models.AppEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	log.Printf("[Events] %s for app %s: %s\n", event.Type, event.AppID, event.Message)
+
+	if s.DB != nil {
+		if err := queueWebhooks(ctx, NewJobService(s.DB), s.DB, event); err != nil {
+			log.Printf("[Events] Failed to queue webhooks: %v\n", err)
+		}
+	}
+
+	if s.Redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[Events] Failed to marshal event: %v\n", err)
+		return
+	}
+
+	channel := fmt.Sprintf("app:events:%s", event.AppID)
+	if err := s.Redis.Publish(ctx, channel, data).Err(); err != nil {
+		log.Printf("[Events] Failed to publish event: %v\n", err)
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// JobGitExportPush pushes a version's code snapshot to the app's Git remote
+const JobGitExportPush = "git_export.push" // payload: app_id, version_id, event
+
+// Git export events, the reason a version is pushed
+const (
+	GitExportEventCompleted = "completed"
+	GitExportEventPromoted  = "promoted"
+)
+
+const defaultGitExportBranch = "main"
+
+var (
+	ErrGitExportNotFound = errors.New("git export is not configured for this app")
+	ErrInvalidGitExport  = errors.New("invalid git export settings")
+)
+
+var gitBranchPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,99}$`)
+
+const gitExportColumns = "app_id, remote_url, branch, username, encrypted_token, enabled, last_pushed_version, last_commit, last_pushed_at, last_error, created_at, updated_at"
+
+type GitExportService struct {
+	DB      *db.PostgresClient
+	Config  *config.Config
+	Secrets *SecretService
+	Jobs    *JobService
+}
+
+func NewGitExportService(dbClient *db.PostgresClient, cfg *config.Config, secretService *SecretService) *GitExportService {
+	return &GitExportService{
+		DB:      dbClient,
+		Config:  cfg,
+		Secrets: secretService,
+		Jobs:    NewJobService(dbClient),
+	}
+}
+
+// GetGitExport returns an app's Git export settings, without the token
+func (s *GitExportService) GetGitExport(ctx context.Context, appID string) (*models.GitExport, error) {
+	export, _, err := s.getGitExport(ctx, appID)
+	return export, err
+}
+
+// GetGitExportWithToken returns an app's Git export settings with the token
+// decrypted, for pushing
+func (s *GitExportService) GetGitExportWithToken(ctx context.Context, appID string) (*models.GitExport, error) {
+	export, encryptedToken, err := s.getGitExport(ctx, appID)
+	if err != nil || encryptedToken == nil {
+		return export, err
+	}
+
+	if s.Secrets.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+	token, err := s.Secrets.decrypt(appID, "git_export", "token", *encryptedToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt git export token: %w", err)
+	}
+	export.Token = token
+	return export, nil
+}
+
+func (s *GitExportService) getGitExport(ctx context.Context, appID string) (*models.GitExport, *string, error) {
+	query := `SELECT ` + gitExportColumns + ` FROM git_exports WHERE app_id = $1`
+
+	export, encryptedToken, err := scanGitExport(s.DB.QueryRow(ctx, query, appID))
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, nil, ErrGitExportNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get git export: %w", err)
+	}
+	return export, encryptedToken, nil
+}
+
+// SetGitExport creates or replaces an app's Git export settings. Changing the
+// remote or branch forgets what was pushed before.
+func (s *GitExportService) SetGitExport(ctx context.Context, appID string, req models.SetGitExportRequest) (*models.GitExport, error) {
+	req.RemoteURL = strings.TrimSpace(req.RemoteURL)
+	if _, err := s.ResolveRemote(req.RemoteURL); err != nil {
+		return nil, err
+	}
+
+	req.Branch = strings.TrimSpace(req.Branch)
+	if req.Branch == "" {
+		req.Branch = defaultGitExportBranch
+	}
+	if !validGitBranch(req.Branch) {
+		return nil, fmt.Errorf("%w: invalid branch name %q", ErrInvalidGitExport, req.Branch)
+	}
+
+	var username *string
+	if req.Username != nil && strings.TrimSpace(*req.Username) != "" {
+		trimmed := strings.TrimSpace(*req.Username)
+		username = &trimmed
+	}
+
+	// A nil token keeps the stored one
+	keepToken := req.Token == nil
+	var encryptedToken *string
+	if req.Token != nil && *req.Token != "" {
+		if s.Secrets.aead == nil {
+			return nil, ErrSecretsDisabled
+		}
+		encrypted, err := s.Secrets.encrypt(appID, "git_export", "token", *req.Token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt git export token: %w", err)
+		}
+		encryptedToken = &encrypted
+	}
+
+	query := `
+		INSERT INTO git_exports (app_id, remote_url, branch, username, encrypted_token, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, TRUE), NOW(), NOW())
+		ON CONFLICT (app_id) DO UPDATE SET
+			remote_url = EXCLUDED.remote_url,
+			branch = EXCLUDED.branch,
+			username = EXCLUDED.username,
+			encrypted_token = CASE WHEN $7 THEN git_exports.encrypted_token ELSE EXCLUDED.encrypted_token END,
+			enabled = COALESCE($6, git_exports.enabled),
+			last_pushed_version = CASE WHEN git_exports.remote_url = EXCLUDED.remote_url AND git_exports.branch = EXCLUDED.branch
+				THEN git_exports.last_pushed_version END,
+			last_commit = CASE WHEN git_exports.remote_url = EXCLUDED.remote_url AND git_exports.branch = EXCLUDED.branch
+				THEN git_exports.last_commit END,
+			last_error = NULL,
+			updated_at = NOW()
+		RETURNING ` + gitExportColumns
+
+	export, _, err := scanGitExport(s.DB.QueryRow(ctx, query,
+		appID, req.RemoteURL, req.Branch, username, encryptedToken, req.Enabled, keepToken,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save git export: %w", err)
+	}
+	return export, nil
+}
+
+// DeleteGitExport stops pushing an app's versions and forgets its credentials
+func (s *GitExportService) DeleteGitExport(ctx context.Context, appID string) error {
+	rowsAffected, err := s.DB.Exec(ctx, `DELETE FROM git_exports WHERE app_id = $1`, appID)
+	if err != nil {
+		return fmt.Errorf("failed to delete git export: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrGitExportNotFound
+	}
+	return nil
+}
+
+// QueueVersion schedules a push of a version if the app has an enabled Git
+// export. Failures are logged, never returned: exporting must not break builds
+// or promotions.
+func (s *GitExportService) QueueVersion(ctx context.Context, appID, versionID, event string) {
+	queueGitExport(ctx, s.DB, appID, versionID, event)
+}
+
+func queueGitExport(ctx context.Context, dbClient *db.PostgresClient, appID, versionID, event string) {
+	var enabled bool
+	err := dbClient.QueryRow(ctx, `SELECT enabled FROM git_exports WHERE app_id = $1`, appID).Scan(&enabled)
+	if errors.Is(err, db.ErrNoRows) || (err == nil && !enabled) {
+		return
+	}
+	if err == nil {
+		err = NewJobService(dbClient).Enqueue(ctx, JobGitExportPush, gitExportPayload(appID, versionID, event))
+	}
+	if err != nil {
+		log.Printf("[GitExport] Warning: Failed to queue push of version %s: %v\n", versionID, err)
+	}
+}
+
+// QueuePush schedules a push of a version regardless of whether the export is enabled
+func (s *GitExportService) QueuePush(ctx context.Context, appID, versionID, event string) error {
+	return s.Jobs.Enqueue(ctx, JobGitExportPush, gitExportPayload(appID, versionID, event))
+}
+
+func gitExportPayload(appID, versionID, event string) map[string]string {
+	return map[string]string{
+		"app_id":     appID,
+		"version_id": versionID,
+		"event":      event,
+	}
+}
+
+// RecordPush stores the outcome of a push; pushErr is nil on success. The last
+// pushed version never goes backwards.
+func (s *GitExportService) RecordPush(ctx context.Context, appID string, versionNumber int, commit string, pushErr error) error {
+	var query string
+	var args []interface{}
+	if pushErr != nil {
+		query = `UPDATE git_exports SET last_error = $1, updated_at = NOW() WHERE app_id = $2`
+		args = []interface{}{pushErr.Error(), appID}
+	} else {
+		query = `
+			UPDATE git_exports SET last_pushed_version = $1, last_commit = $2, last_pushed_at = NOW(), last_error = NULL, updated_at = NOW()
+			WHERE app_id = $3 AND (last_pushed_version IS NULL OR last_pushed_version <= $1)
+		`
+		args = []interface{}{versionNumber, commit, appID}
+	}
+
+	if _, err := s.DB.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record git export push: %w", err)
+	}
+	return nil
+}
+
+// ResolveRemote checks a remote URL and returns what to pass to git. Remotes
+// are http(s) URLs without embedded credentials or, when GitExportLocalRoot is
+// set, local repositories under it given as a path or file:// URL.
+func (s *GitExportService) ResolveRemote(remote string) (string, error) {
+	if remote == "" {
+		return "", fmt.Errorf("%w: remote_url is required", ErrInvalidGitExport)
+	}
+
+	if strings.HasPrefix(remote, "https://") || strings.HasPrefix(remote, "http://") {
+		u, err := url.Parse(remote)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("%w: invalid remote URL", ErrInvalidGitExport)
+		}
+		if u.User != nil {
+			return "", fmt.Errorf("%w: put credentials in username and token, not in the URL", ErrInvalidGitExport)
+		}
+		return remote, nil
+	}
+
+	if s.Config.GitExportLocalRoot == "" {
+		return "", fmt.Errorf("%w: remote_url must be an https:// URL", ErrInvalidGitExport)
+	}
+
+	path := strings.TrimPrefix(remote, "file://")
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: local remotes must be absolute paths", ErrInvalidGitExport)
+	}
+	root, err := filepath.EvalSymlinks(s.Config.GitExportLocalRoot)
+	if err != nil {
+		return "", fmt.Errorf("git export root is unavailable: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: local repository not found", ErrInvalidGitExport)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: local remotes must be inside the git export root", ErrInvalidGitExport)
+	}
+	return resolved, nil
+}
+
+// validGitBranch approximates git check-ref-format for a branch name
+func validGitBranch(branch string) bool {
+	return gitBranchPattern.MatchString(branch) &&
+		!strings.Contains(branch, "..") &&
+		!strings.Contains(branch, "//") &&
+		!strings.HasSuffix(branch, "/") &&
+		!strings.HasSuffix(branch, ".") &&
+		!strings.HasSuffix(branch, ".lock")
+}
+
+// scanGitExport scans a row selected with gitExportColumns, returning the
+// still encrypted token separately
+func scanGitExport(row db.Row) (*models.GitExport, *string, error) {
+	var export models.GitExport
+	var encryptedToken *string
+	err := row.Scan(
+		&export.AppID, &export.RemoteURL, &export.Branch, &export.Username, &encryptedToken, &export.Enabled,
+		&export.LastPushedVersion, &export.LastCommit, &export.LastPushedAt, &export.LastError,
+		&export.CreatedAt, &export.UpdatedAt,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	export.HasToken = encryptedToken != nil
+	return &export, encryptedToken, nil
+}
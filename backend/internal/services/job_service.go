@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// Cleanup job types enqueued when apps and versions are deleted
+const (
+	JobCleanupS3Prefix         = "cleanup.s3_prefix"         // payload: prefix
+	JobCleanupVercelProject    = "cleanup.vercel_project"    // payload: project (ID or name)
+	JobCleanupVercelDeployment = "cleanup.vercel_deployment" // payload: deployment_id
+	JobCleanupAppDatabase      = "cleanup.app_database"      // payload: app_id
+	JobCleanupAppHistory       = "cleanup.app_history"       // payload: app_id
+)
+
+const defaultJobMaxAttempts = 8
+
+const jobColumns = "id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at, updated_at"
+
+// execer is satisfied by both the client and a transaction, so jobs can be
+// enqueued atomically with the change that needs them
+type execer interface {
+	Exec(ctx context.Context, query string, args ...interface{}) (int64, error)
+}
+
+type JobService struct {
+	DB *db.PostgresClient
+}
+
+func NewJobService(dbClient *db.PostgresClient) *JobService {
+	return &JobService{DB: dbClient}
+}
+
+// Enqueue schedules a job to run as soon as possible
+func (s *JobService) Enqueue(ctx context.Context, jobType string, payload map[string]string) error {
+	return s.EnqueueTx(ctx, s.DB, jobType, payload)
+}
+
+// EnqueueTx schedules a job through q, typically a transaction
+func (s *JobService) EnqueueTx(ctx context.Context, q execer, jobType string, payload map[string]string) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO jobs (id, type, payload, status, max_attempts, run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, 'pending', $4, NOW(), NOW(), NOW())
+	`
+	if _, err := q.Exec(ctx, query, uuid.New().String(), jobType, string(payloadJSON), defaultJobMaxAttempts); err != nil {
+		return fmt.Errorf("failed to enqueue %s job: %w", jobType, err)
+	}
+
+	return nil
+}
+
+// ClaimNext locks the next due job and marks it running. It returns nil when no job is due.
+func (s *JobService) ClaimNext(ctx context.Context) (*models.Job, error) {
+	query := `
+		UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_at <= NOW()
+			ORDER BY run_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	var job models.Job
+	err := s.DB.QueryRow(ctx, query).Scan(
+		&job.ID, &job.Type, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.LastError, &job.RunAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// Complete marks a job as done
+func (s *JobService) Complete(ctx context.Context, jobID string) error {
+	query := `UPDATE jobs SET status = 'completed', last_error = NULL, locked_at = NULL, updated_at = NOW() WHERE id = $1`
+	if _, err := s.DB.Exec(ctx, query, jobID); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return nil
+}
+
+// Fail records a failed attempt and reschedules the job after backoff, or marks
+// it failed for good once it is out of attempts
+func (s *JobService) Fail(ctx context.Context, job *models.Job, jobErr error, backoff time.Duration) error {
+	status := "pending"
+	if job.Attempts >= job.MaxAttempts {
+		status = "failed"
+	}
+
+	query := `
+		UPDATE jobs SET status = $1, last_error = $2, run_at = $3, locked_at = NULL, updated_at = NOW()
+		WHERE id = $4
+	`
+	if _, err := s.DB.Exec(ctx, query, status, jobErr.Error(), time.Now().Add(backoff), job.ID); err != nil {
+		return fmt.Errorf("failed to record job failure: %w", err)
+	}
+	return nil
+}
+
+// RequeueStale returns jobs stuck in running (e.g. after a crash) to pending
+func (s *JobService) RequeueStale(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		UPDATE jobs SET status = 'pending', locked_at = NULL, updated_at = NOW()
+		WHERE status = 'running' AND locked_at < $1
+	`
+	rowsAffected, err := s.DB.Exec(ctx, query, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale jobs: %w", err)
+	}
+	return rowsAffected, nil
+}
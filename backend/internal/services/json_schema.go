@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DocumentValidationError lists the ways a document violates its collection's $jsonSchema
+type DocumentValidationError struct {
+	Problems []string
+}
+
+func (e *DocumentValidationError) Error() string {
+	return "document does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+// validateDocument checks a document against the subset of MongoDB's $jsonSchema
+// used by generated apps: bsonType/type, required, properties,
+// additionalProperties, enum, numeric and length bounds, pattern and items.
+// MongoDB enforces the full validator on write; this gives owners readable errors first.
+func validateDocument(schema map[string]interface{}, doc bson.M) error {
+	var problems []string
+	validateValue(schema, doc, "", &problems)
+	if len(problems) > 0 {
+		return &DocumentValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		field := path
+		if field == "" {
+			field = "document"
+		}
+		*problems = append(*problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		actual := bsonTypeOf(value)
+		if !typeAllowed(types, actual) {
+			fail("expected %s, got %s", strings.Join(types, " or "), actual)
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if valuesEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the allowed values")
+		}
+	}
+
+	if number, ok := toFloat(value); ok {
+		if min, ok := toFloat(schema["minimum"]); ok {
+			if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && number <= min {
+				fail("must be greater than %v", min)
+			} else if number < min {
+				fail("must be at least %v", min)
+			}
+		}
+		if max, ok := toFloat(schema["maximum"]); ok {
+			if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && number >= max {
+				fail("must be less than %v", max)
+			} else if number > max {
+				fail("must be at most %v", max)
+			}
+		}
+	}
+
+	if str, ok := value.(string); ok {
+		length := float64(len([]rune(str)))
+		if min, ok := toFloat(schema["minLength"]); ok && length < min {
+			fail("must be at least %v characters", min)
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && length > max {
+			fail("must be at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(str) {
+				fail("does not match pattern %s", pattern)
+			}
+		}
+	}
+
+	if items := toSlice(value); items != nil {
+		if min, ok := toFloat(schema["minItems"]); ok && float64(len(items)) < min {
+			fail("must have at least %v items", min)
+		}
+		if max, ok := toFloat(schema["maxItems"]); ok && float64(len(items)) > max {
+			fail("must have at most %v items", max)
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				validateValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+
+	if fields := toFields(value); fields != nil {
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if field, ok := name.(string); ok {
+					if _, present := fields[field]; !present {
+						fail("missing required field %s", field)
+					}
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			if propSchema, ok := properties[name].(map[string]interface{}); ok {
+				validateValue(propSchema, fields[name], fieldPath, problems)
+			} else if additional, ok := schema["additionalProperties"].(bool); ok && !additional && name != "_id" {
+				*problems = append(*problems, fieldPath+": field is not allowed")
+			}
+		}
+	}
+}
+
+// schemaTypes returns the bsonType or type names a schema allows
+func schemaTypes(schema map[string]interface{}) []string {
+	for _, key := range []string{"bsonType", "type"} {
+		switch t := schema[key].(type) {
+		case string:
+			return []string{t}
+		case []interface{}:
+			var types []string
+			for _, name := range t {
+				if s, ok := name.(string); ok {
+					types = append(types, s)
+				}
+			}
+			return types
+		}
+	}
+	return nil
+}
+
+// typeAllowed reports whether a value of the given BSON type satisfies one of the
+// allowed bsonType or JSON Schema type names
+func typeAllowed(allowed []string, actual string) bool {
+	for _, name := range allowed {
+		switch {
+		case name == actual:
+			return true
+		case name == "number" && (actual == "int" || actual == "long" || actual == "double" || actual == "decimal"):
+			return true
+		case name == "integer" && (actual == "int" || actual == "long"):
+			return true
+		case name == "boolean" && actual == "bool":
+			return true
+		}
+	}
+	return false
+}
+
+// bsonTypeOf names the BSON type of a decoded value
+func bsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float64:
+		return "double"
+	case primitive.Decimal128:
+		return "decimal"
+	case primitive.ObjectID:
+		return "objectId"
+	case primitive.DateTime:
+		return "date"
+	case primitive.Timestamp:
+		return "timestamp"
+	case primitive.Binary:
+		return "binData"
+	case primitive.Regex:
+		return "regex"
+	case bson.M, bson.D, map[string]interface{}:
+		return "object"
+	case bson.A, []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case bson.A:
+		return v
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+func toFields(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		return v
+	case map[string]interface{}:
+		return v
+	case bson.D:
+		fields := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			fields[e.Key] = e.Value
+		}
+		return fields
+	}
+	return nil
+}
+
+// valuesEqual compares a schema enum entry (decoded from JSON) with a document value
+func valuesEqual(allowed, value interface{}) bool {
+	if a, ok := toFloat(allowed); ok {
+		b, ok := toFloat(value)
+		return ok && a == b
+	}
+	return fmt.Sprint(allowed) == fmt.Sprint(value)
+}
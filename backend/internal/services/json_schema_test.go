@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateDocument(t *testing.T) {
+	// Schemas are stored as JSON, so numbers decode to float64 and lists to []interface{}
+	const schema = `{
+		"bsonType": "object",
+		"required": ["title", "status"],
+		"additionalProperties": false,
+		"properties": {
+			"title": {"bsonType": "string", "minLength": 1, "maxLength": 10},
+			"status": {"enum": ["open", "closed"]},
+			"priority": {"bsonType": ["int", "long"], "minimum": 1, "maximum": 5},
+			"score": {"type": "number", "minimum": 0, "exclusiveMinimum": true},
+			"done": {"type": "boolean"},
+			"ownerId": {"bsonType": "objectId"},
+			"tags": {"bsonType": "array", "maxItems": 2, "items": {"bsonType": "string"}},
+			"meta": {"bsonType": "object", "properties": {"views": {"bsonType": "int"}}}
+		}
+	}`
+
+	tests := []struct {
+		name string
+		doc  bson.M
+		want []string
+	}{
+		{
+			name: "valid",
+			doc: bson.M{
+				"_id": primitive.NewObjectID(), "title": "Ship it", "status": "open",
+				"priority": int32(3), "score": 0.5, "done": false, "ownerId": primitive.NewObjectID(),
+				"tags": bson.A{"a", "b"}, "meta": bson.M{"views": int32(2)},
+			},
+		},
+		{
+			name: "missing required fields",
+			doc:  bson.M{},
+			want: []string{"document: missing required field title", "document: missing required field status"},
+		},
+		{
+			name: "bsonType mismatch",
+			doc:  bson.M{"title": int32(5), "status": "open"},
+			want: []string{"title: expected string, got int"},
+		},
+		{
+			name: "bsonType list accepts long",
+			doc:  bson.M{"title": "a", "status": "open", "priority": int64(2)},
+		},
+		{
+			name: "bsonType list rejects double",
+			doc:  bson.M{"title": "a", "status": "open", "priority": 2.5},
+			want: []string{"priority: expected int or long, got double"},
+		},
+		{
+			name: "type number accepts int",
+			doc:  bson.M{"title": "a", "status": "open", "score": int32(4)},
+		},
+		{
+			name: "type boolean alias",
+			doc:  bson.M{"title": "a", "status": "open", "done": "yes"},
+			want: []string{"done: expected boolean, got string"},
+		},
+		{
+			name: "enum",
+			doc:  bson.M{"title": "a", "status": "archived"},
+			want: []string{"status: value is not one of the allowed values"},
+		},
+		{
+			name: "numeric bounds",
+			doc:  bson.M{"title": "a", "status": "open", "priority": int32(9), "score": 0.0},
+			want: []string{"priority: must be at most 5", "score: must be greater than 0"},
+		},
+		{
+			name: "length bounds",
+			doc:  bson.M{"title": "", "status": "open"},
+			want: []string{"title: must be at least 1 characters"},
+		},
+		{
+			name: "length counts characters",
+			doc:  bson.M{"title": "ééééééééé", "status": "open"},
+		},
+		{
+			name: "array items and bounds",
+			doc:  bson.M{"title": "a", "status": "open", "tags": bson.A{"a", int32(1), "c"}},
+			want: []string{"tags: must have at most 2 items", "tags[1]: expected string, got int"},
+		},
+		{
+			name: "nested object",
+			doc:  bson.M{"title": "a", "status": "open", "meta": bson.M{"views": "many"}},
+			want: []string{"meta.views: expected int, got string"},
+		},
+		{
+			name: "additional properties",
+			doc:  bson.M{"_id": primitive.NewObjectID(), "title": "a", "status": "open", "color": "red"},
+			want: []string{"color: field is not allowed"},
+		},
+	}
+
+	var validator map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &validator); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDocument(validator, tt.doc)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("validateDocument() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *DocumentValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("validateDocument() error = %v, want a DocumentValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Problems, tt.want) {
+				t.Errorf("validateDocument() problems = %q, want %q", validationErr.Problems, tt.want)
+			}
+		})
+	}
+}
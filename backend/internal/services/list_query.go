@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// List page sizes
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// sortColumn is a column a list can be sorted by, with the SQL type its cursor
+// value is cast to
+type sortColumn struct {
+	column string
+	cast   string
+}
+
+// listSpec describes how a ListQuery applies to one table
+type listSpec struct {
+	sorts         map[string]sortColumn // by API field name
+	defaultSort   string
+	searchColumns []string
+}
+
+// listCursor points just past the last row of a page. It carries the sort it
+// was made for, since a cursor is meaningless under another order.
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// listPlan is a ListQuery translated into SQL clauses for a listSpec
+type listPlan struct {
+	conditions []string
+	args       []interface{}
+	orderBy    string
+	limit      int
+	sort       string
+	sortField  string
+}
+
+// planList validates q against spec and builds its WHERE conditions, ORDER BY
+// and LIMIT. Placeholders continue after args, the arguments of the caller's
+// own conditions.
+func planList(q models.ListQuery, spec listSpec, args []interface{}) (*listPlan, error) {
+	plan := &listPlan{args: args, limit: q.Limit}
+	if plan.limit == 0 {
+		plan.limit = defaultListLimit
+	}
+	if plan.limit < 0 || plan.limit > maxListLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, maxListLimit)
+	}
+
+	plan.sort = q.Sort
+	if plan.sort == "" {
+		plan.sort = spec.defaultSort
+	}
+	plan.sortField = strings.TrimPrefix(plan.sort, "-")
+	sortCol, ok := spec.sorts[plan.sortField]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidListQuery, plan.sortField)
+	}
+	direction, comparison := "ASC", ">"
+	if strings.HasPrefix(plan.sort, "-") {
+		direction, comparison = "DESC", "<"
+	}
+	plan.orderBy = fmt.Sprintf("%s %s, id %s", sortCol.column, direction, direction)
+
+	if len(q.Status) > 0 {
+		plan.add("status = ANY($%d)", q.Status)
+	}
+	if q.Since != nil {
+		plan.add("created_at >= $%d", *q.Since)
+	}
+	if q.Until != nil {
+		plan.add("created_at < $%d", *q.Until)
+	}
+
+	if q.Search != "" {
+		if len(spec.searchColumns) == 0 {
+			return nil, fmt.Errorf("%w: search is not supported here", ErrInvalidListQuery)
+		}
+		plan.args = append(plan.args, "%"+escapeLike(q.Search)+"%")
+		matches := make([]string, len(spec.searchColumns))
+		for i, column := range spec.searchColumns {
+			matches[i] = fmt.Sprintf("%s ILIKE $%d", column, len(plan.args))
+		}
+		plan.conditions = append(plan.conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodeListCursor(q.Cursor)
+		if err != nil || cursor.Sort != plan.sort {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidListQuery)
+		}
+		plan.args = append(plan.args, cursor.Value, cursor.ID)
+		plan.conditions = append(plan.conditions, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d::uuid)",
+			sortCol.column, comparison, len(plan.args)-1, sortCol.cast, len(plan.args)))
+	}
+
+	return plan, nil
+}
+
+func (p *listPlan) add(condition string, arg interface{}) {
+	p.args = append(p.args, arg)
+	p.conditions = append(p.conditions, fmt.Sprintf(condition, len(p.args)))
+}
+
+// where returns the plan's conditions to AND onto a query's own WHERE clause
+func (p *listPlan) where() string {
+	if len(p.conditions) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(p.conditions, " AND ")
+}
+
+// tail returns the ORDER BY and LIMIT clauses. One extra row is fetched to
+// tell whether there is a next page.
+func (p *listPlan) tail() string {
+	return fmt.Sprintf(" ORDER BY %s LIMIT %d", p.orderBy, p.limit+1)
+}
+
+// page trims the extra row fetched by tail and returns the cursor for the next
+// page, or "" on the last page. key returns an item's sort value and ID.
+func page[T any](p *listPlan, items []T, key func(item T, field string) (string, string)) ([]T, string) {
+	if len(items) <= p.limit {
+		return items, ""
+	}
+
+	items = items[:p.limit]
+	value, id := key(items[len(items)-1], p.sortField)
+	raw, _ := json.Marshal(listCursor{Sort: p.sort, Value: value, ID: id})
+	return items, base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeListCursor(encoded string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a search term
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+var testListSpec = listSpec{
+	sorts: map[string]sortColumn{
+		"created_at": {"created_at", "timestamptz"},
+		"name":       {"name", "text"},
+	},
+	defaultSort:   "-created_at",
+	searchColumns: []string{"name", "description"},
+}
+
+func testCursor(t *testing.T, sort, value, id string) string {
+	t.Helper()
+	raw, err := json.Marshal(listCursor{Sort: sort, Value: value, ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func TestPlanList(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		query      models.ListQuery
+		args       []interface{}
+		conditions []string
+		wantArgs   []interface{}
+		orderBy    string
+		limit      int
+	}{
+		{
+			name:    "defaults",
+			query:   models.ListQuery{},
+			orderBy: "created_at DESC, id DESC",
+			limit:   defaultListLimit,
+		},
+		{
+			name:       "placeholders continue after the caller's args",
+			query:      models.ListQuery{Limit: 10, Sort: "name", Status: []string{"active"}, Since: &since},
+			args:       []interface{}{"user-1"},
+			conditions: []string{"status = ANY($2)", "created_at >= $3"},
+			wantArgs:   []interface{}{"user-1", []string{"active"}, since},
+			orderBy:    "name ASC, id ASC",
+			limit:      10,
+		},
+		{
+			name:       "search uses one placeholder for every column",
+			query:      models.ListQuery{Search: "50%_off"},
+			args:       []interface{}{"user-1", "app-1"},
+			conditions: []string{"(name ILIKE $3 OR description ILIKE $3)"},
+			wantArgs:   []interface{}{"user-1", "app-1", `%50\%\_off%`},
+			orderBy:    "created_at DESC, id DESC",
+			limit:      defaultListLimit,
+		},
+		{
+			name:       "descending cursor",
+			query:      models.ListQuery{Cursor: testCursor(t, "-created_at", "2026-01-02T00:00:00Z", "id-9")},
+			args:       []interface{}{"user-1"},
+			conditions: []string{"(created_at, id) < ($2::timestamptz, $3::uuid)"},
+			wantArgs:   []interface{}{"user-1", "2026-01-02T00:00:00Z", "id-9"},
+			orderBy:    "created_at DESC, id DESC",
+			limit:      defaultListLimit,
+		},
+		{
+			name:       "ascending cursor after filters",
+			query:      models.ListQuery{Sort: "name", Status: []string{"draft"}, Cursor: testCursor(t, "name", "Todo", "id-3")},
+			conditions: []string{"status = ANY($1)", "(name, id) > ($2::text, $3::uuid)"},
+			wantArgs:   []interface{}{[]string{"draft"}, "Todo", "id-3"},
+			orderBy:    "name ASC, id ASC",
+			limit:      defaultListLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planList(tt.query, testListSpec, tt.args)
+			if err != nil {
+				t.Fatalf("planList() error = %v", err)
+			}
+			if !reflect.DeepEqual(plan.conditions, tt.conditions) {
+				t.Errorf("conditions = %q, want %q", plan.conditions, tt.conditions)
+			}
+			if !reflect.DeepEqual(plan.args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", plan.args, tt.wantArgs)
+			}
+			if plan.orderBy != tt.orderBy {
+				t.Errorf("orderBy = %q, want %q", plan.orderBy, tt.orderBy)
+			}
+			if plan.limit != tt.limit {
+				t.Errorf("limit = %d, want %d", plan.limit, tt.limit)
+			}
+		})
+	}
+}
+
+func TestPlanListInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query models.ListQuery
+		spec  listSpec
+	}{
+		{"negative limit", models.ListQuery{Limit: -1}, testListSpec},
+		{"limit too large", models.ListQuery{Limit: maxListLimit + 1}, testListSpec},
+		{"unknown sort", models.ListQuery{Sort: "-status"}, testListSpec},
+		{"search not supported", models.ListQuery{Search: "x"}, listSpec{sorts: testListSpec.sorts, defaultSort: "name"}},
+		{"malformed cursor", models.ListQuery{Cursor: "not base64!"}, testListSpec},
+		{"cursor for another sort", models.ListQuery{Sort: "name", Cursor: testCursor(t, "-created_at", "x", "id")}, testListSpec},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := planList(tt.query, tt.spec, nil); !errors.Is(err, ErrInvalidListQuery) {
+				t.Errorf("planList() error = %v, want ErrInvalidListQuery", err)
+			}
+		})
+	}
+}
+
+func TestListPage(t *testing.T) {
+	plan := &listPlan{limit: 2, sort: "-name", sortField: "name"}
+	key := func(item string, field string) (string, string) { return item, "id-" + item }
+
+	items, next := page(plan, []string{"c", "b"}, key)
+	if len(items) != 2 || next != "" {
+		t.Errorf("last page = %v, %q, want 2 items and no cursor", items, next)
+	}
+
+	items, next = page(plan, []string{"c", "b", "a"}, key)
+	if !reflect.DeepEqual(items, []string{"c", "b"}) {
+		t.Errorf("items = %v, want [c b]", items)
+	}
+	cursor, err := decodeListCursor(next)
+	if err != nil {
+		t.Fatalf("decodeListCursor() error = %v", err)
+	}
+	if *cursor != (listCursor{Sort: "-name", Value: "b", ID: "id-b"}) {
+		t.Errorf("cursor = %+v, want the last item of the page", *cursor)
+	}
+}
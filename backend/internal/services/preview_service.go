@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+var (
+	ErrInvalidPreviewTTL   = errors.New("ttl_seconds must be between 60 and 3600")
+	ErrPreviewUserDisabled = errors.New("user is disabled")
+	ErrInvalidPreviewToken = errors.New("invalid or expired preview token")
+)
+
+// Preview token lifetimes
+const (
+	defaultPreviewTTL = 5 * time.Minute
+	minPreviewTTL     = time.Minute
+	maxPreviewTTL     = time.Hour
+
+	// previewRefreshGrace lets a token that just expired still be refreshed
+	previewRefreshGrace = 5 * time.Minute
+)
+
+// previewClaims are the claims of a preview token. They match the end-user
+// tokens generated apps issue themselves, plus preview metadata.
+type previewClaims struct {
+	UserID    string   `json:"userId"`
+	Email     string   `json:"email"`
+	AppID     string   `json:"appId"`
+	Roles     []string `json:"roles"`
+	Preview   bool     `json:"preview"`
+	VersionID string   `json:"versionId,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// PreviewService mints tokens that let an app owner use a generated app as
+// themselves, with a single role, or as one of the app's end users
+type PreviewService struct {
+	Databases *AppDatabaseService
+	Users     *AppUserService
+	Versions  *VersionService
+}
+
+func NewPreviewService(databases *AppDatabaseService, users *AppUserService, versions *VersionService) *PreviewService {
+	return &PreviewService{Databases: databases, Users: users, Versions: versions}
+}
+
+// IssueToken creates a preview token for a version of an app
+func (s *PreviewService) IssueToken(ctx context.Context, app *models.App, ownerEmail string, req models.PreviewTokenRequest) (*models.PreviewToken, error) {
+	ttl := defaultPreviewTTL
+	if req.TTLSeconds != 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+		if ttl < minPreviewTTL || ttl > maxPreviewTTL {
+			return nil, ErrInvalidPreviewTTL
+		}
+	}
+
+	if req.Role != "" {
+		if err := validateRoles([]string{req.Role}); err != nil {
+			return nil, err
+		}
+	}
+
+	versionID, previewURL, err := s.Versions.ResolveDeployment(ctx, app, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.AppUser
+	if req.UserID != "" {
+		user, err = s.Users.GetUser(ctx, app.ID, req.UserID)
+	} else {
+		user, err = s.Users.GetUserByEmail(ctx, app.ID, ownerEmail)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == AppUserDisabled {
+		return nil, ErrPreviewUserDisabled
+	}
+
+	roles := user.Roles
+	if req.Role != "" {
+		roles = []string{req.Role}
+	}
+
+	return s.sign(ctx, app.ID, user, roles, versionID, previewURL, ttl)
+}
+
+// RefreshToken issues a new token with the same identity, roles, version and
+// lifetime as a current or just expired preview token
+func (s *PreviewService) RefreshToken(ctx context.Context, app *models.App, tokenString string) (*models.PreviewToken, error) {
+	appDB, err := s.Databases.GetApp(ctx, app.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims previewClaims
+	_, err = jwt.ParseWithClaims(tokenString, &claims, appDB.JWT.Keyfunc, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithLeeway(previewRefreshGrace))
+	if err != nil || !claims.Preview || claims.AppID != app.ID || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, ErrInvalidPreviewToken
+	}
+
+	user, err := s.Users.GetUser(ctx, app.ID, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == AppUserDisabled {
+		return nil, ErrPreviewUserDisabled
+	}
+
+	version := claims.VersionID
+	if version == "" {
+		version = "latest"
+	}
+	versionID, previewURL, err := s.Versions.ResolveDeployment(ctx, app, version)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time)
+	if ttl < minPreviewTTL || ttl > maxPreviewTTL {
+		ttl = defaultPreviewTTL
+	}
+
+	return s.sign(ctx, app.ID, user, claims.Roles, versionID, previewURL, ttl)
+}
+
+// sign mints a token with the app's active signing key
+func (s *PreviewService) sign(ctx context.Context, appID string, user *models.AppUser, roles []string, versionID, previewURL string, ttl time.Duration) (*models.PreviewToken, error) {
+	appDB, err := s.Databases.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := previewClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		AppID:     appID,
+		Roles:     roles,
+		Preview:   true,
+		VersionID: versionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if appDB.JWT.KID != "" {
+		token.Header["kid"] = appDB.JWT.KID
+	}
+	signed, err := token.SignedString([]byte(appDB.JWT.Secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &models.PreviewToken{
+		Token:      signed,
+		PreviewURL: previewURL,
+		VersionID:  versionID,
+		UserID:     user.ID,
+		Email:      user.Email,
+		Roles:      roles,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration step actions
+const (
+	MigrationCreateCollection = "create_collection"
+	MigrationUpdateValidator  = "update_validator"
+	MigrationCreateIndex      = "create_index"
+	MigrationDropIndex        = "drop_index"
+)
+
+// mongoIndexNotFound is the server error code for dropping a missing index
+const mongoIndexNotFound = 27
+
+// SchemaService tracks and applies schema migrations for generated app databases
+type SchemaService struct {
+	DB    *db.PostgresClient
+	Mongo *mongo.Client
+}
+
+func NewSchemaService(dbClient *db.PostgresClient, mongoClient *mongo.Client) *SchemaService {
+	return &SchemaService{DB: dbClient, Mongo: mongoClient}
+}
+
+// LatestMigration returns the last schema migration applied to an app, or nil if there is none
+func (s *SchemaService) LatestMigration(ctx context.Context, appID string) (*models.SchemaMigration, error) {
+	query := `
+		SELECT id, app_id, version_id, schema_version, schema, steps, backup_path, applied_at
+		FROM app_schema_migrations
+		WHERE app_id = $1
+		ORDER BY schema_version DESC
+		LIMIT 1
+	`
+
+	var m models.SchemaMigration
+	err := s.DB.QueryRow(ctx, query, appID).Scan(
+		&m.ID, &m.AppID, &m.VersionID, &m.SchemaVersion, &m.Schema, &m.Steps, &m.BackupPath, &m.AppliedAt,
+	)
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema migration: %w", err)
+	}
+
+	return &m, nil
+}
+
+// RecordMigration stores an applied migration and bumps the app's schema version
+func (s *SchemaService) RecordMigration(ctx context.Context, m *models.SchemaMigration) error {
+	schemaJSON, err := json.Marshal(m.Schema)
+	if err != nil {
+		return err
+	}
+	stepsJSON, err := json.Marshal(m.Steps)
+	if err != nil {
+		return err
+	}
+
+	m.ID = uuid.New().String()
+	m.AppliedAt = time.Now()
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO app_schema_migrations (id, app_id, version_id, schema_version, schema, steps, backup_path, applied_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	if _, err := tx.Exec(ctx, query,
+		m.ID, m.AppID, m.VersionID, m.SchemaVersion, string(schemaJSON), string(stepsJSON), m.BackupPath, m.AppliedAt,
+	); err != nil {
+		return fmt.Errorf("failed to record schema migration: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE apps SET schema_version = $1, updated_at = NOW() WHERE id = $2`, m.SchemaVersion, m.AppID); err != nil {
+		return fmt.Errorf("failed to update app schema version: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DatabaseName returns the MongoDB database registered for an app in system_db,
+// or "" when the app has no database yet
+func (s *SchemaService) DatabaseName(ctx context.Context, appID string) (string, error) {
+	var appDoc struct {
+		DatabaseName string `bson:"databaseName"`
+	}
+
+	err := s.Mongo.Database("system_db").Collection("apps").FindOne(ctx, bson.M{"_id": appID}).Decode(&appDoc)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up app database: %w", err)
+	}
+
+	return appDoc.DatabaseName, nil
+}
+
+// CollectionNames lists the collections that exist in an app database
+func (s *SchemaService) CollectionNames(ctx context.Context, dbName string) (map[string]bool, error) {
+	names, err := s.Mongo.Database(dbName).ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	existing := map[string]bool{}
+	for _, name := range names {
+		existing[name] = true
+	}
+	return existing, nil
+}
+
+// BackupCollection writes every document of a collection to w as one extended JSON document per line
+func (s *SchemaService) BackupCollection(ctx context.Context, dbName, collection string, w io.Writer) (int, error) {
+	cursor, err := s.Mongo.Database(dbName).Collection(collection).Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return count, err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, cursor.Err()
+}
+
+// ApplyStep applies one migration step to an app database
+func (s *SchemaService) ApplyStep(ctx context.Context, dbName string, step models.MigrationStep) error {
+	database := s.Mongo.Database(dbName)
+
+	switch step.Action {
+	case MigrationCreateCollection:
+		opts := options.CreateCollection()
+		if step.Validator != nil {
+			opts.SetValidator(bson.M{"$jsonSchema": step.Validator})
+		}
+		return database.CreateCollection(ctx, step.Collection, opts)
+
+	case MigrationUpdateValidator:
+		validator := bson.M{}
+		if step.Validator != nil {
+			validator = bson.M{"$jsonSchema": step.Validator}
+		}
+		// moderate keeps existing documents that predate the new schema updatable
+		return database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: step.Collection},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: "moderate"},
+		}).Err()
+
+	case MigrationCreateIndex:
+		if step.Index == nil {
+			return fmt.Errorf("create_index step for %s has no index", step.Collection)
+		}
+		_, err := database.Collection(step.Collection).Indexes().CreateOne(ctx, indexModel(*step.Index))
+		return err
+
+	case MigrationDropIndex:
+		_, err := database.Collection(step.Collection).Indexes().DropOne(ctx, step.IndexName)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == mongoIndexNotFound {
+			return nil
+		}
+		return err
+	}
+
+	return fmt.Errorf("unknown migration action %s", step.Action)
+}
+
+// indexModel converts an index spec to the driver's model
+func indexModel(spec models.IndexSpec) mongo.IndexModel {
+	keys := bson.D{}
+	for _, key := range spec.Keys {
+		if order, err := strconv.Atoi(key.Value); err == nil {
+			keys = append(keys, bson.E{Key: key.Field, Value: int32(order)})
+		} else {
+			keys = append(keys, bson.E{Key: key.Field, Value: key.Value})
+		}
+	}
+
+	opts := options.Index().SetName(spec.Name)
+	if spec.Unique {
+		opts.SetUnique(true)
+	}
+	if spec.Sparse {
+		opts.SetSparse(true)
+	}
+	if spec.ExpireAfterSeconds != nil {
+		opts.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+	}
+
+	return mongo.IndexModel{Keys: keys, Options: opts}
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// Secret scopes. Preview secrets are used for every build; production secrets
+// override them in the build made when a version is promoted and on Vercel's
+// production environment. A key without a production value falls back to its
+// preview value in production.
+const (
+	SecretScopePreview    = "preview"
+	SecretScopeProduction = "production"
+)
+
+// maxSecretValueSize matches Vercel's per-variable limit
+const maxSecretValueSize = 64 * 1024
+
+var (
+	ErrSecretsDisabled    = errors.New("secrets are not configured on this server (SECRETS_MASTER_KEY)")
+	ErrInvalidSecretKey   = errors.New("secret keys must be letters, digits and underscores, not starting with a digit or VERCEL_")
+	ErrInvalidSecretScope = errors.New("secret scope must be preview or production")
+	ErrSecretTooLarge     = errors.New("secret value exceeds 64KB")
+	ErrSecretNotFound     = errors.New("secret not found")
+)
+
+var secretKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,127}$`)
+
+const secretColumns = "id, app_id, key, scope, created_at, updated_at"
+
+type SecretService struct {
+	DB     *db.PostgresClient
+	Vercel *VercelService
+	aead   cipher.AEAD
+}
+
+func NewSecretService(dbClient *db.PostgresClient, vercelService *VercelService, cfg *config.Config) *SecretService {
+	s := &SecretService{DB: dbClient, Vercel: vercelService}
+
+	if cfg.SecretsMasterKey == "" {
+		log.Println("Warning: SECRETS_MASTER_KEY not configured, app secrets are disabled")
+		return s
+	}
+
+	aead, err := newSecretsCipher(cfg.SecretsMasterKey)
+	if err != nil {
+		log.Printf("Warning: Invalid SECRETS_MASTER_KEY, app secrets are disabled: %v\n", err)
+		return s
+	}
+	s.aead = aead
+	return s
+}
+
+// newSecretsCipher builds AES-256-GCM from a base64 or hex encoded 32-byte key
+func newSecretsCipher(encodedKey string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != 32 {
+		key, err = hex.DecodeString(encodedKey)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("key must decode to 32 bytes")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretAAD binds a ciphertext to its row so values cannot be swapped between apps or keys
+func secretAAD(appID, key, scope string) []byte {
+	return []byte(appID + "/" + key + "/" + scope)
+}
+
+func (s *SecretService) encrypt(appID, key, scope, value string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(value), secretAAD(appID, key, scope))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *SecretService) decrypt(appID, key, scope, encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, secretAAD(appID, key, scope))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// vercelTargets maps a secret scope to Vercel environments
+func vercelTargets(scope string) []string {
+	if scope == SecretScopeProduction {
+		return []string{"production"}
+	}
+	return []string{"preview", "development"}
+}
+
+// vercelEnv is one project env entry for a secret key
+type vercelEnv struct {
+	Value   string
+	Targets []string
+}
+
+// vercelEnvs plans the project env entries for an app's secrets. A preview value
+// also targets production when the key has no production value.
+func vercelEnvs(preview, production map[string]string) map[string][]vercelEnv {
+	envs := map[string][]vercelEnv{}
+	for key, value := range preview {
+		targets := vercelTargets(SecretScopePreview)
+		if _, ok := production[key]; !ok {
+			targets = append(targets, vercelTargets(SecretScopeProduction)...)
+		}
+		envs[key] = append(envs[key], vercelEnv{Value: value, Targets: targets})
+	}
+	for key, value := range production {
+		envs[key] = append(envs[key], vercelEnv{Value: value, Targets: vercelTargets(SecretScopeProduction)})
+	}
+	return envs
+}
+
+// mergeSecretValues overrides preview values with production ones
+func mergeSecretValues(preview, production map[string]string) map[string]string {
+	merged := make(map[string]string, len(preview)+len(production))
+	for key, value := range preview {
+		merged[key] = value
+	}
+	for key, value := range production {
+		merged[key] = value
+	}
+	return merged
+}
+
+func validateSecret(key, scope string) error {
+	if !secretKeyPattern.MatchString(key) || strings.HasPrefix(strings.ToUpper(key), "VERCEL_") {
+		return ErrInvalidSecretKey
+	}
+	if scope != SecretScopePreview && scope != SecretScopeProduction {
+		return ErrInvalidSecretScope
+	}
+	return nil
+}
+
+// ListSecrets lists an app's secrets without their values
+func (s *SecretService) ListSecrets(ctx context.Context, appID string) ([]models.AppSecret, error) {
+	query := `SELECT ` + secretColumns + ` FROM app_secrets WHERE app_id = $1 ORDER BY key ASC, scope ASC`
+
+	rows, err := s.DB.Query(ctx, query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	defer rows.Close()
+
+	secrets := []models.AppSecret{}
+	for rows.Next() {
+		var secret models.AppSecret
+		if err := rows.Scan(
+			&secret.ID, &secret.AppID, &secret.Key, &secret.Scope, &secret.CreatedAt, &secret.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating secrets: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// SetSecret creates or replaces a secret and pushes it to the app's Vercel project
+func (s *SecretService) SetSecret(ctx context.Context, app *models.App, key string, req models.SetSecretRequest) (*models.AppSecret, error) {
+	if s.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = SecretScopePreview
+	}
+	if err := validateSecret(key, scope); err != nil {
+		return nil, err
+	}
+	if len(req.Value) > maxSecretValueSize {
+		return nil, ErrSecretTooLarge
+	}
+
+	encrypted, err := s.encrypt(app.ID, key, scope, req.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO app_secrets (id, app_id, key, scope, encrypted_value, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (app_id, key, scope) DO UPDATE SET encrypted_value = EXCLUDED.encrypted_value, updated_at = EXCLUDED.updated_at
+		RETURNING ` + secretColumns
+
+	var secret models.AppSecret
+	err = s.DB.QueryRow(ctx, query, uuid.New().String(), app.ID, key, scope, encrypted, now).Scan(
+		&secret.ID, &secret.AppID, &secret.Key, &secret.Scope, &secret.CreatedAt, &secret.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save secret: %w", err)
+	}
+
+	// The next deploy syncs everything again, so a failed push is not fatal
+	if s.Vercel != nil && app.VercelProjectID != nil && *app.VercelProjectID != "" {
+		if err := s.syncKeys(ctx, app.ID, *app.VercelProjectID, []string{key}); err != nil {
+			log.Printf("[Secrets] Warning: Failed to sync %s to Vercel for app %s: %v\n", key, app.ID, err)
+		}
+	}
+
+	return &secret, nil
+}
+
+// DeleteSecret removes a secret from one scope, or from both when scope is empty
+func (s *SecretService) DeleteSecret(ctx context.Context, app *models.App, key, scope string) error {
+	scopes := []string{SecretScopePreview, SecretScopeProduction}
+	if scope != "" {
+		if err := validateSecret(key, scope); err != nil {
+			return err
+		}
+		scopes = []string{scope}
+	}
+
+	rowsAffected, err := s.DB.Exec(ctx, `DELETE FROM app_secrets WHERE app_id = $1 AND key = $2 AND scope = ANY($3)`, app.ID, key, scopes)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrSecretNotFound
+	}
+
+	// Deleting a production value brings the preview value back to production
+	if s.Vercel != nil && app.VercelProjectID != nil && *app.VercelProjectID != "" {
+		if err := s.syncKeys(ctx, app.ID, *app.VercelProjectID, []string{key}); err != nil {
+			log.Printf("[Secrets] Warning: Failed to remove %s from Vercel for app %s: %v\n", key, app.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// syncKeys replaces the project env entries of the given keys, or of every
+// secret when keys is nil, with the ones planned by vercelEnvs. Entries are
+// recreated rather than upserted since a key's targets change when a
+// production value is added or removed.
+func (s *SecretService) syncKeys(ctx context.Context, appID, projectID string, keys []string) error {
+	preview, err := s.GetSecretValues(ctx, appID, SecretScopePreview)
+	if err != nil {
+		return err
+	}
+	production, err := s.GetSecretValues(ctx, appID, SecretScopeProduction)
+	if err != nil {
+		return err
+	}
+	planned := vercelEnvs(preview, production)
+
+	synced := map[string]bool{}
+	for _, key := range keys {
+		synced[key] = true
+	}
+	if keys == nil {
+		for key := range planned {
+			synced[key] = true
+		}
+	}
+
+	existing, err := s.Vercel.ListProjectEnv(projectID)
+	if err != nil {
+		return err
+	}
+	for _, env := range existing {
+		if synced[env.Key] {
+			if err := s.Vercel.DeleteProjectEnv(projectID, env.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key := range synced {
+		for _, env := range planned[key] {
+			if err := s.Vercel.UpsertProjectEnv(projectID, key, env.Value, env.Targets); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetSecretValues decrypts an app's secrets for one scope
+func (s *SecretService) GetSecretValues(ctx context.Context, appID, scope string) (map[string]string, error) {
+	values := map[string]string{}
+	if s.aead == nil {
+		return values, nil
+	}
+
+	rows, err := s.DB.Query(ctx, `SELECT key, encrypted_value FROM app_secrets WHERE app_id = $1 AND scope = $2`, appID, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, encrypted string
+		if err := rows.Scan(&key, &encrypted); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+
+		value, err := s.decrypt(appID, key, scope, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", key, err)
+		}
+		values[key] = value
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating secrets: %w", err)
+	}
+
+	return values, nil
+}
+
+// CopySecrets copies every secret of one app to another, re-encrypting them for
+// the target app. Existing secrets of the target are overwritten.
+func (s *SecretService) CopySecrets(ctx context.Context, fromAppID, toAppID string) error {
+	if s.aead == nil {
+		return ErrSecretsDisabled
+	}
+
+	rows, err := s.DB.Query(ctx, `SELECT key, scope, encrypted_value FROM app_secrets WHERE app_id = $1`, fromAppID)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	type copiedSecret struct{ key, scope, encrypted string }
+	var copies []copiedSecret
+	for rows.Next() {
+		var key, scope, encrypted string
+		if err := rows.Scan(&key, &scope, &encrypted); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan secret: %w", err)
+		}
+
+		value, err := s.decrypt(fromAppID, key, scope, encrypted)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decrypt secret %s: %w", key, err)
+		}
+		reencrypted, err := s.encrypt(toAppID, key, scope, value)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to encrypt secret %s: %w", key, err)
+		}
+		copies = append(copies, copiedSecret{key, scope, reencrypted})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating secrets: %w", err)
+	}
+
+	now := time.Now()
+	for _, c := range copies {
+		_, err := s.DB.Exec(ctx, `
+			INSERT INTO app_secrets (id, app_id, key, scope, encrypted_value, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6)
+			ON CONFLICT (app_id, key, scope) DO UPDATE SET encrypted_value = EXCLUDED.encrypted_value, updated_at = EXCLUDED.updated_at
+		`, uuid.New().String(), toAppID, c.key, c.scope, c.encrypted, now)
+		if err != nil {
+			return fmt.Errorf("failed to copy secret %s: %w", c.key, err)
+		}
+	}
+
+	return nil
+}
+
+// SyncToVercel pushes every secret of an app to its Vercel project, e.g. after
+// the project was created by the first deploy
+func (s *SecretService) SyncToVercel(ctx context.Context, appID, projectID string) error {
+	if s.aead == nil || s.Vercel == nil {
+		return nil
+	}
+	return s.syncKeys(ctx, appID, projectID, nil)
+}
+
+// HasProductionSecrets reports whether any of an app's secrets has a production value
+func (s *SecretService) HasProductionSecrets(ctx context.Context, appID string) (bool, error) {
+	if s.aead == nil {
+		return false, nil
+	}
+
+	var exists bool
+	err := s.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM app_secrets WHERE app_id = $1 AND scope = $2)`, appID, SecretScopeProduction).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check production secrets: %w", err)
+	}
+	return exists, nil
+}
+
+// GetBuildValues returns the values a build for scope runs with. Production
+// builds get the preview values overridden by the production ones.
+func (s *SecretService) GetBuildValues(ctx context.Context, appID, scope string) (map[string]string, error) {
+	preview, err := s.GetSecretValues(ctx, appID, SecretScopePreview)
+	if err != nil || scope != SecretScopeProduction {
+		return preview, err
+	}
+
+	production, err := s.GetSecretValues(ctx, appID, SecretScopeProduction)
+	if err != nil {
+		return nil, err
+	}
+	return mergeSecretValues(preview, production), nil
+}
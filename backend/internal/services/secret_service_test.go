@@ -0,0 +1,66 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVercelEnvs(t *testing.T) {
+	tests := []struct {
+		name       string
+		preview    map[string]string
+		production map[string]string
+		want       map[string][]vercelEnv
+	}{
+		{
+			name:    "preview only falls back in production",
+			preview: map[string]string{"API_KEY": "test"},
+			want: map[string][]vercelEnv{
+				"API_KEY": {{Value: "test", Targets: []string{"preview", "development", "production"}}},
+			},
+		},
+		{
+			name:       "production value overrides",
+			preview:    map[string]string{"API_KEY": "test"},
+			production: map[string]string{"API_KEY": "live"},
+			want: map[string][]vercelEnv{
+				"API_KEY": {
+					{Value: "test", Targets: []string{"preview", "development"}},
+					{Value: "live", Targets: []string{"production"}},
+				},
+			},
+		},
+		{
+			name:       "production only",
+			production: map[string]string{"STRIPE_KEY": "live"},
+			want: map[string][]vercelEnv{
+				"STRIPE_KEY": {{Value: "live", Targets: []string{"production"}}},
+			},
+		},
+		{
+			name: "no secrets",
+			want: map[string][]vercelEnv{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vercelEnvs(tt.preview, tt.production); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("vercelEnvs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeSecretValues(t *testing.T) {
+	preview := map[string]string{"API_KEY": "test", "APP_NAME": "demo"}
+	production := map[string]string{"API_KEY": "live", "STRIPE_KEY": "sk_live"}
+
+	want := map[string]string{"API_KEY": "live", "APP_NAME": "demo", "STRIPE_KEY": "sk_live"}
+	if got := mergeSecretValues(preview, production); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSecretValues() = %v, want %v", got, want)
+	}
+	if preview["API_KEY"] != "test" {
+		t.Errorf("mergeSecretValues() modified the preview values")
+	}
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrShareLinkNotFound     = errors.New("share link not found")
+	ErrShareLinkExpired      = errors.New("share link has expired")
+	ErrSharePasswordRequired = errors.New("share link requires a password")
+	ErrSharePasswordInvalid  = errors.New("incorrect password")
+	ErrShareCommentsDisabled = errors.New("comments are not enabled for this share link")
+	ErrInvalidShareLink      = errors.New("expires_at must be in the future and max_views positive")
+	ErrInvalidShareComment   = errors.New("comment must have between 1 and 5000 characters")
+	ErrShareRateLimited      = errors.New("too many incorrect passwords, try again later")
+	ErrShareDisabled         = errors.New("link sharing is not configured")
+	ErrInvalidShareSession   = errors.New("invalid or expired share session")
+)
+
+const maxShareCommentLength = 5000
+
+// Failed password attempts allowed per window, for one link and for one client IP
+const (
+	sharePasswordWindow      = 15 * time.Minute
+	maxSharePasswordFailures = 10
+	maxShareIPFailures       = 30
+)
+
+// shareSessionAudience keeps share session tokens apart from other tokens
+const shareSessionAudience = "share"
+
+// shareSessionClaims identify the link and version a share session may view.
+// The deployment URL is looked up per request so it never reaches the visitor.
+type shareSessionClaims struct {
+	LinkID    string `json:"lid"`
+	VersionID string `json:"vid"`
+	jwt.RegisteredClaims
+}
+
+// shareLinkColumns is the column list scanned by scanShareLink
+const shareLinkColumns = "id, app_id, version_id, slug, password_hash, expires_at, max_views, view_count, allow_comments, revoked_at, created_by, created_at"
+
+// ShareService manages public share links that show an app or version to
+// people without a RapidBuild account
+type ShareService struct {
+	DB       *db.PostgresClient
+	Config   *config.Config
+	Versions *VersionService
+}
+
+func NewShareService(dbClient *db.PostgresClient, cfg *config.Config, versionService *VersionService) *ShareService {
+	return &ShareService{DB: dbClient, Config: cfg, Versions: versionService}
+}
+
+// CreateShareLink creates a share link for an app, or one of its versions
+func (s *ShareService) CreateShareLink(ctx context.Context, app *models.App, userID string, req models.CreateShareLinkRequest) (*models.ShareLink, error) {
+	if (req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now())) || (req.MaxViews != nil && *req.MaxViews <= 0) {
+		return nil, ErrInvalidShareLink
+	}
+
+	if req.VersionID != nil {
+		version, err := s.Versions.GetVersion(ctx, *req.VersionID)
+		if err != nil || version.AppID != app.ID {
+			return nil, ErrVersionNotFound
+		}
+	}
+
+	slug, err := generateSlug()
+	if err != nil {
+		return nil, err
+	}
+
+	var passwordHash *string
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		hashed := string(hash)
+		passwordHash = &hashed
+	}
+
+	query := `
+		INSERT INTO share_links (id, app_id, version_id, slug, password_hash, expires_at, max_views, allow_comments, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + shareLinkColumns
+
+	link := &models.ShareLink{}
+	err = scanShareLink(s.DB.QueryRow(ctx, query,
+		uuid.New().String(), app.ID, req.VersionID, slug, passwordHash,
+		req.ExpiresAt, req.MaxViews, req.AllowComments, userID, time.Now(),
+	), link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	return s.withURL(link), nil
+}
+
+// ListShareLinks lists an app's share links, including revoked and expired ones
+func (s *ShareService) ListShareLinks(ctx context.Context, appID string) ([]models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE app_id = $1 ORDER BY created_at DESC`
+
+	rows, err := s.DB.Query(ctx, query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		var link models.ShareLink
+		if err := scanShareLink(rows, &link); err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, *s.withURL(&link))
+	}
+
+	return links, rows.Err()
+}
+
+// RevokeShareLink disables a share link immediately
+func (s *ShareService) RevokeShareLink(ctx context.Context, appID, linkID string) error {
+	query := `UPDATE share_links SET revoked_at = NOW() WHERE id = $1 AND app_id = $2 AND revoked_at IS NULL`
+	rowsAffected, err := s.DB.Exec(ctx, query, linkID, appID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrShareLinkNotFound
+	}
+	return nil
+}
+
+// GetInfo returns what a visitor sees before opening a link, without counting a view
+func (s *ShareService) GetInfo(ctx context.Context, slug string) (*models.ShareLinkInfo, error) {
+	link, app, err := s.activeLink(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ShareLinkInfo{
+		AppName:           app.Name,
+		PasswordProtected: link.PasswordProtected,
+		AllowComments:     link.AllowComments,
+		ExpiresAt:         link.ExpiresAt,
+	}, nil
+}
+
+// Open checks the password, counts a view and returns a URL on the share host
+// that starts a short-lived session viewing the deployment the link points at
+func (s *ShareService) Open(ctx context.Context, slug, password, clientIP string) (*models.ShareLinkTarget, error) {
+	if s.Config.ShareBaseURL == "" {
+		return nil, ErrShareDisabled
+	}
+
+	link, app, err := s.activeLink(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPassword(ctx, link, password, clientIP); err != nil {
+		return nil, err
+	}
+
+	version := "latest"
+	if link.VersionID != nil {
+		version = *link.VersionID
+	} else if app.ProdURL != nil && *app.ProdURL != "" {
+		version = "production"
+	}
+
+	versionID, _, err := s.Versions.ResolveDeployment(ctx, app, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// The limits are checked again here so concurrent opens can't exceed max_views
+	query := `
+		UPDATE share_links SET view_count = view_count + 1
+		WHERE id = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND (max_views IS NULL OR view_count < max_views)
+	`
+	rowsAffected, err := s.DB.Exec(ctx, query, link.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record view: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrShareLinkExpired
+	}
+
+	token, expiresAt, err := s.sessionToken(link, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ShareLinkTarget{
+		URL:       fmt.Sprintf("%s%s%s", strings.TrimRight(s.Config.ShareBaseURL, "/"), ShareSessionPath, token),
+		VersionID: versionID,
+		AppName:   app.Name,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ShareSessionPath is where the share host starts a session from a token
+const ShareSessionPath = "/_share/"
+
+// sessionToken signs a session for a link, ending with the link if it expires sooner
+func (s *ShareService) sessionToken(link *models.ShareLink, versionID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.Config.ShareSessionTTL)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expiresAt) {
+		expiresAt = *link.ExpiresAt
+	}
+
+	claims := shareSessionClaims{
+		LinkID:    link.ID,
+		VersionID: versionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{shareSessionAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.sessionKey())
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign share session: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// sessionKey derives the session signing key from the JWT secret, so share
+// sessions can never pass as access tokens
+func (s *ShareService) sessionKey() []byte {
+	mac := hmac.New(sha256.New, []byte(s.Config.JWTSecret))
+	mac.Write([]byte("share-session"))
+	return mac.Sum(nil)
+}
+
+// ResolveSession checks a share session token and returns the deployment it
+// may view and when the session ends. The link is checked again, so revoking
+// or expiring it ends open sessions.
+func (s *ShareService) ResolveSession(ctx context.Context, token string) (*url.URL, time.Time, error) {
+	var claims shareSessionClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return s.sessionKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(shareSessionAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, time.Time{}, ErrInvalidShareSession
+	}
+
+	link := &models.ShareLink{}
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE id = $1`
+	if err := scanShareLink(s.DB.QueryRow(ctx, query, claims.LinkID), link); err != nil {
+		if errors.Is(err, db.ErrNoRows) {
+			return nil, time.Time{}, ErrShareLinkNotFound
+		}
+		return nil, time.Time{}, fmt.Errorf("failed to get share link: %w", err)
+	}
+	if link.RevokedAt != nil {
+		return nil, time.Time{}, ErrShareLinkNotFound
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return nil, time.Time{}, ErrShareLinkExpired
+	}
+
+	version, err := s.Versions.GetVersion(ctx, claims.VersionID)
+	if err != nil || version.AppID != link.AppID {
+		return nil, time.Time{}, ErrVersionNotFound
+	}
+	if version.VercelURL == nil || *version.VercelURL == "" {
+		return nil, time.Time{}, ErrVersionNotDeployed
+	}
+
+	rawURL := *version.VercelURL
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid deployment URL: %w", err)
+	}
+	return target, claims.ExpiresAt.Time, nil
+}
+
+// AddComment leaves an anonymous comment through a link that allows comments
+func (s *ShareService) AddComment(ctx context.Context, slug string, req models.AddShareCommentRequest, clientIP string) (*models.Comment, error) {
+	link, _, err := s.activeLink(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if !link.AllowComments {
+		return nil, ErrShareCommentsDisabled
+	}
+	if err := s.checkPassword(ctx, link, req.Password, clientIP); err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" || len(content) > maxShareCommentLength || len(req.AuthorName) > 100 {
+		return nil, ErrInvalidShareComment
+	}
+	req.Content = content
+
+	return NewCommentService(s.DB).AddShareComment(ctx, link, req)
+}
+
+// activeLink loads a link by slug with its app, failing if it is revoked, expired or used up
+func (s *ShareService) activeLink(ctx context.Context, slug string) (*models.ShareLink, *models.App, error) {
+	link := &models.ShareLink{}
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE slug = $1`
+	if err := scanShareLink(s.DB.QueryRow(ctx, query, slug), link); err != nil {
+		if errors.Is(err, db.ErrNoRows) {
+			return nil, nil, ErrShareLinkNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	if link.RevokedAt != nil {
+		return nil, nil, ErrShareLinkNotFound
+	}
+	if (link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt)) ||
+		(link.MaxViews != nil && link.ViewCount >= *link.MaxViews) {
+		return nil, nil, ErrShareLinkExpired
+	}
+
+	app, err := NewAppService(s.DB).GetAppByID(ctx, link.AppID)
+	if err != nil {
+		return nil, nil, ErrShareLinkNotFound
+	}
+
+	return link, app, nil
+}
+
+// PageURL is the frontend's share page for a slug, which asks for the password if needed
+func (s *ShareService) PageURL(slug string) string {
+	return fmt.Sprintf("%s/share/%s", strings.TrimRight(s.Config.FrontendURL, "/"), slug)
+}
+
+func (s *ShareService) withURL(link *models.ShareLink) *models.ShareLink {
+	link.URL = s.PageURL(link.Slug)
+	return link
+}
+
+// checkPassword checks a link's password, refusing to try once the link or the
+// client IP has had too many recent failures
+func (s *ShareService) checkPassword(ctx context.Context, link *models.ShareLink, password, clientIP string) error {
+	if link.PasswordHash == nil {
+		return nil
+	}
+	if password == "" {
+		return ErrSharePasswordRequired
+	}
+
+	var linkFailures, ipFailures int
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE share_link_id = $1),
+			COUNT(*) FILTER (WHERE ip = $2)
+		FROM share_password_failures
+		WHERE (share_link_id = $1 OR ip = $2) AND created_at > $3
+	`
+	if err := s.DB.QueryRow(ctx, query, link.ID, clientIP, time.Now().Add(-sharePasswordWindow)).Scan(&linkFailures, &ipFailures); err != nil {
+		return fmt.Errorf("failed to check password attempts: %w", err)
+	}
+	if linkFailures >= maxSharePasswordFailures || ipFailures >= maxShareIPFailures {
+		return ErrShareRateLimited
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(*link.PasswordHash), []byte(password)) == nil {
+		return nil
+	}
+
+	// Failures older than the window are no longer needed
+	if _, err := s.DB.Exec(ctx, `DELETE FROM share_password_failures WHERE share_link_id = $1 AND created_at < $2`, link.ID, time.Now().Add(-sharePasswordWindow)); err != nil {
+		log.Printf("[Share] Warning: Failed to prune password attempts: %v\n", err)
+	}
+	if _, err := s.DB.Exec(ctx, `INSERT INTO share_password_failures (share_link_id, ip, created_at) VALUES ($1, $2, NOW())`, link.ID, clientIP); err != nil {
+		return fmt.Errorf("failed to record password attempt: %w", err)
+	}
+	return ErrSharePasswordInvalid
+}
+
+// generateSlug returns a random, URL safe, 16 character slug
+func generateSlug() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate slug: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// scanShareLink scans a row selected with shareLinkColumns into link
+func scanShareLink(row db.Row, link *models.ShareLink) error {
+	err := row.Scan(
+		&link.ID, &link.AppID, &link.VersionID, &link.Slug, &link.PasswordHash, &link.ExpiresAt,
+		&link.MaxViews, &link.ViewCount, &link.AllowComments, &link.RevokedAt, &link.CreatedBy, &link.CreatedAt,
+	)
+	link.PasswordProtected = link.PasswordHash != nil
+	return err
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+// DefaultTemplateID is the starter in Config.StarterCodeDir, used when an app names no template
+const DefaultTemplateID = "default"
+
+// TemplateManifest is the template.json file that describes a starter template
+const TemplateManifest = "template.json"
+
+var templateIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// templateManifest is the content of a template.json
+type templateManifest struct {
+	Name         string `json:"name"`
+	Framework    string `json:"framework"`
+	Description  string `json:"description"`
+	BuildCommand string `json:"build_command"`
+	PreviewImage string `json:"preview_image"`
+	Archive      string `json:"archive"` // S3 key of the template's files, instead of the directory
+}
+
+// TemplateService is the registry of starter templates apps can be created
+// from. Templates are loaded once at startup: the default starter from
+// Config.StarterCodeDir and one template per subdirectory of Config.TemplatesDir.
+type TemplateService struct {
+	templates map[string]*models.Template
+}
+
+func NewTemplateService(cfg *config.Config) *TemplateService {
+	s := &TemplateService{templates: map[string]*models.Template{}}
+
+	s.templates[DefaultTemplateID] = &models.Template{
+		ID:          DefaultTemplateID,
+		Name:        "Blank app",
+		Description: "The standard React starter",
+		Dir:         cfg.StarterCodeDir,
+	}
+	// The starter can describe itself with a manifest too
+	if manifest, err := readTemplateManifest(cfg.StarterCodeDir); err == nil {
+		s.templates[DefaultTemplateID] = templateFromManifest(DefaultTemplateID, cfg.StarterCodeDir, manifest)
+	}
+
+	if cfg.TemplatesDir == "" {
+		return s
+	}
+
+	entries, err := os.ReadDir(cfg.TemplatesDir)
+	if err != nil {
+		log.Printf("Warning: Failed to read templates from %s: %v\n", cfg.TemplatesDir, err)
+		return s
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		if !templateIDPattern.MatchString(id) {
+			log.Printf("Warning: Skipping template %q, IDs must be lowercase letters, digits or -\n", id)
+			continue
+		}
+
+		dir := filepath.Join(cfg.TemplatesDir, id)
+		manifest, err := readTemplateManifest(dir)
+		if err != nil {
+			log.Printf("Warning: Skipping template %s: %v\n", id, err)
+			continue
+		}
+		s.templates[id] = templateFromManifest(id, dir, manifest)
+	}
+
+	log.Printf("Loaded %d starter templates\n", len(s.templates))
+	return s
+}
+
+// ListTemplates returns the templates, the default starter first and the rest by name
+func (s *TemplateService) ListTemplates() []models.Template {
+	templates := make([]models.Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, *t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if (templates[i].ID == DefaultTemplateID) != (templates[j].ID == DefaultTemplateID) {
+			return templates[i].ID == DefaultTemplateID
+		}
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
+
+// GetTemplate returns a template by ID; "" is the default starter
+func (s *TemplateService) GetTemplate(id string) (*models.Template, error) {
+	if id == "" {
+		id = DefaultTemplateID
+	}
+	t, ok := s.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+func readTemplateManifest(dir string) (*templateManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, TemplateManifest))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest templateManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", TemplateManifest, err)
+	}
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("%s has no name", TemplateManifest)
+	}
+	return &manifest, nil
+}
+
+func templateFromManifest(id, dir string, manifest *templateManifest) *models.Template {
+	t := &models.Template{
+		ID:           id,
+		Name:         manifest.Name,
+		Framework:    manifest.Framework,
+		Description:  manifest.Description,
+		BuildCommand: manifest.BuildCommand,
+		PreviewImage: manifest.PreviewImage,
+		Archive:      manifest.Archive,
+	}
+	if t.Archive == "" {
+		t.Dir = dir
+	}
+	return t
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"path"
 	"path/filepath"
 	"time"
 
@@ -89,6 +90,58 @@ func (s *UploadService) UploadRequirementFile(
 	return &reqFile, nil
 }
 
+// CopyRequirementFiles copies the requirement files uploaded for an app's
+// versions up to and including throughVersion onto another app's version
+func (s *UploadService) CopyRequirementFiles(ctx context.Context, fromAppID string, throughVersion int, toAppID, toVersionID string) error {
+	query := `
+		SELECT rf.file_name, rf.file_type, rf.s3_path
+		FROM requirement_files rf
+		JOIN versions v ON v.id = rf.version_id
+		WHERE rf.app_id = $1 AND v.version_number <= $2
+		ORDER BY rf.created_at
+	`
+	rows, err := s.DB.Query(ctx, query, fromAppID, throughVersion)
+	if err != nil {
+		return fmt.Errorf("failed to list requirement files: %w", err)
+	}
+
+	var files []models.RequirementFile
+	for rows.Next() {
+		var f models.RequirementFile
+		if err := rows.Scan(&f.FileName, &f.FileType, &f.S3Path); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan requirement file: %w", err)
+		}
+		files = append(files, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating requirement files: %w", err)
+	}
+
+	for _, f := range files {
+		s3Path := fmt.Sprintf("apps/%s/versions/%s/requirements/%s", toAppID, toVersionID, path.Base(f.S3Path))
+		_, err := s.S3Client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(s.Config.S3Bucket),
+			Key:        aws.String(s3Path),
+			CopySource: aws.String(s.Config.S3Bucket + "/" + f.S3Path), // generated keys need no escaping
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", f.FileName, err)
+		}
+
+		_, err = s.DB.Exec(ctx, `
+			INSERT INTO requirement_files (id, app_id, version_id, file_name, file_type, s3_path, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, uuid.New().String(), toAppID, toVersionID, f.FileName, f.FileType, s3Path, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to save file metadata: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // DownloadFile downloads a file from S3
 func (s *UploadService) DownloadFile(ctx context.Context, s3Path string) (io.ReadCloser, error) {
 	result, err := s.S3Client.GetObject(ctx, &s3.GetObjectInput{
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// deployPollInterval is how often Deploy checks whether a deployment is ready
+const deployPollInterval = 3 * time.Second
+
+// uploadConcurrency bounds parallel file uploads during a deploy
+const uploadConcurrency = 8
+
+// VercelProject is the subset of project fields needed to link and build a workspace
+type VercelProject struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	AccountID       string  `json:"accountId"`
+	Framework       *string `json:"framework"`
+	BuildCommand    *string `json:"buildCommand"`
+	DevCommand      *string `json:"devCommand"`
+	InstallCommand  *string `json:"installCommand"`
+	OutputDirectory *string `json:"outputDirectory"`
+	RootDirectory   *string `json:"rootDirectory"`
+	NodeVersion     string  `json:"nodeVersion,omitempty"`
+	CreatedAt       int64   `json:"createdAt"`
+}
+
+// deployFile references one file of a deployment by content hash
+type deployFile struct {
+	File string `json:"file"`
+	Sha  string `json:"sha"`
+	Size int64  `json:"size"`
+	Mode uint32 `json:"mode"`
+}
+
+// deploySource is where the bytes for a SHA come from when Vercel asks for them
+type deploySource struct {
+	path   string
+	isLink bool
+}
+
+// EnsureProject returns the project called name, creating it when it does not
+// exist yet. framework may be empty to let Vercel treat it as "Other".
+func (s *VercelService) EnsureProject(ctx context.Context, name, framework string) (*VercelProject, error) {
+	var project VercelProject
+	err := withRetry(ctx, func() error {
+		return s.requestContext(ctx, "GET", "/v9/projects/"+url.PathEscape(name), nil, &project)
+	})
+	if err == nil {
+		return &project, nil
+	}
+	if !IsVercelNotFound(err) {
+		return nil, fmt.Errorf("failed to get project %s: %w", name, err)
+	}
+
+	reqBody := map[string]interface{}{"name": name}
+	if framework != "" {
+		reqBody["framework"] = framework
+	}
+
+	if err := s.requestContext(ctx, "POST", "/v10/projects", reqBody, &project); err != nil {
+		return nil, fmt.Errorf("failed to create project %s: %w", name, err)
+	}
+
+	log.Printf("[Vercel] Created project %s (%s)\n", name, project.ID)
+	return &project, nil
+}
+
+// Deploy creates a deployment from a prebuilt .vercel/output directory and waits
+// until it is ready. Only files Vercel has not seen before are uploaded. A
+// production deployment is staged without taking over the project's domains;
+// PromoteDeployment switches production to it.
+func (s *VercelService) Deploy(ctx context.Context, projectID, name, outputDir string, production bool) (*VercelDeployment, error) {
+	files, sources, err := collectDeployFiles(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build output: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("build output %s is empty", outputDir)
+	}
+
+	reqBody := map[string]interface{}{
+		"name":    name,
+		"project": projectID,
+		"files":   files,
+	}
+	if production {
+		reqBody["target"] = "production"
+		reqBody["autoAssignCustomDomains"] = false
+	}
+
+	// The first attempt tells us which files are missing; upload those and retry
+	var deployment VercelDeployment
+	for round := 1; ; round++ {
+		err = withRetry(ctx, func() error {
+			return s.requestContext(ctx, "POST", "/v13/deployments?skipAutoDetectionConfirmation=1", reqBody, &deployment)
+		})
+		if err == nil {
+			break
+		}
+
+		var apiErr *VercelAPIError
+		if !errors.As(err, &apiErr) || apiErr.Code != "missing_files" || round == 3 {
+			return nil, fmt.Errorf("failed to create deployment: %w", err)
+		}
+
+		log.Printf("[Vercel] Uploading %d of %d files for %s\n", len(apiErr.Missing), len(files), name)
+		if err := s.uploadFiles(ctx, apiErr.Missing, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("[Vercel] Created deployment %s (%s), waiting until ready\n", deployment.ID, deployment.URL)
+	return s.waitForDeployment(ctx, deployment.ID)
+}
+
+// waitForDeployment polls a deployment until it is READY, fails, or ctx ends
+func (s *VercelService) waitForDeployment(ctx context.Context, deploymentID string) (*VercelDeployment, error) {
+	ticker := time.NewTicker(deployPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var deployment VercelDeployment
+		err := withRetry(ctx, func() error {
+			return s.requestContext(ctx, "GET", "/v13/deployments/"+deploymentID, nil, &deployment)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s: %w", deploymentID, err)
+		}
+
+		state := deployment.ReadyState
+		if state == "" {
+			state = deployment.State
+		}
+
+		switch state {
+		case "READY":
+			return &deployment, nil
+		case "ERROR", "CANCELED":
+			return nil, fmt.Errorf("deployment %s is %s: %s", deploymentID, state, deployment.ErrorMessage)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("deployment %s not ready (last state %s): %w", deploymentID, state, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// uploadFiles uploads the files with the given SHAs in parallel
+func (s *VercelService) uploadFiles(ctx context.Context, shas []string, sources map[string]deploySource) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, uploadConcurrency)
+
+	for _, sha := range shas {
+		source, ok := sources[sha]
+		if !ok {
+			// Uploads already started still have to finish before returning
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("vercel requested unknown file %s", sha)
+			}
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(sha string, source deploySource) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := s.uploadFile(ctx, sha, source); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(sha, source)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+func (s *VercelService) uploadFile(ctx context.Context, sha string, source deploySource) error {
+	data, err := source.read()
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type":    "application/octet-stream",
+		"x-vercel-digest": sha,
+	}
+
+	err = withRetry(ctx, func() error {
+		return s.do(ctx, s.UploadClient, "POST", "/v2/files", bytes.NewReader(data), headers, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", source.path, err)
+	}
+	return nil
+}
+
+// read returns the uploaded bytes: the file contents, or the target of a symlink
+func (d deploySource) read() ([]byte, error) {
+	if d.isLink {
+		target, err := os.Readlink(d.path)
+		return []byte(target), err
+	}
+	return os.ReadFile(d.path)
+}
+
+// collectDeployFiles hashes every file under outputDir. Paths are reported
+// relative to the project root (.vercel/output/...) as the CLI does for prebuilt deploys.
+func collectDeployFiles(outputDir string) ([]deployFile, map[string]deploySource, error) {
+	var files []deployFile
+	sources := map[string]deploySource{}
+
+	err := filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+
+		source := deploySource{path: path, isLink: info.Mode()&os.ModeSymlink != 0}
+		mode := uint32(info.Mode().Perm()) | 0o100000
+		if source.isLink {
+			mode = uint32(info.Mode().Perm()) | 0o120000
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		sha, size, err := hashDeployFile(source)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, deployFile{
+			File: ".vercel/output/" + filepath.ToSlash(relPath),
+			Sha:  sha,
+			Size: size,
+			Mode: mode,
+		})
+		sources[sha] = source
+		return nil
+	})
+
+	return files, sources, err
+}
+
+func hashDeployFile(source deploySource) (string, int64, error) {
+	hash := sha1.New()
+
+	if source.isLink {
+		data, err := source.read()
+		if err != nil {
+			return "", 0, err
+		}
+		hash.Write(data)
+		return hex.EncodeToString(hash.Sum(nil)), int64(len(data)), nil
+	}
+
+	file, err := os.Open(source.path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
@@ -2,192 +2,358 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/rapidbuildapp/rapidbuild/config"
 )
 
 type VercelService struct {
-	Config *Config
-	Client *http.Client
+	Config       *Config
+	Client       *http.Client
+	UploadClient *http.Client // file uploads can take longer than API calls
 }
 
 type Config struct {
-	Token string
+	Token   string
+	TeamID  string
+	BaseURL string
 }
 
 func NewVercelService(cfg *config.Config) *VercelService {
 	return &VercelService{
 		Config: &Config{
-			Token: cfg.VercelToken,
+			Token:   cfg.VercelToken,
+			TeamID:  cfg.VercelTeamID,
+			BaseURL: strings.TrimRight(cfg.VercelAPIURL, "/"),
 		},
-		Client: &http.Client{Timeout: 30 * time.Second},
+		Client:       &http.Client{Timeout: 30 * time.Second},
+		UploadClient: &http.Client{Timeout: 5 * time.Minute},
 	}
 }
 
 type VercelDeployment struct {
-	ID    string `json:"id"`
-	URL   string `json:"url"`
-	State string `json:"state"`
+	ID           string   `json:"id"`
+	URL          string   `json:"url"`
+	State        string   `json:"state"`
+	ReadyState   string   `json:"readyState"`
+	ProjectID    string   `json:"projectId"`
+	Alias        []string `json:"alias"`
+	ErrorCode    string   `json:"errorCode,omitempty"`
+	ErrorMessage string   `json:"errorMessage,omitempty"`
 }
 
-type VercelDeploymentRequest struct {
-	Name    string            `json:"name"`
-	Files   []VercelFile      `json:"files"`
-	Target  string            `json:"target,omitempty"`
-	GitMeta map[string]string `json:"gitMetadata,omitempty"`
+// VercelAPIError is returned when the Vercel API answers with a 4xx/5xx status
+type VercelAPIError struct {
+	StatusCode int
+	Body       string
+	Code       string   // error.code from the response, e.g. missing_files
+	Missing    []string // SHAs Vercel has not seen yet (missing_files only)
 }
 
-type VercelFile struct {
-	File string `json:"file"`
-	Data string `json:"data"` // base64 encoded
+func (e *VercelAPIError) Error() string {
+	return fmt.Sprintf("vercel API returned %d: %s", e.StatusCode, e.Body)
 }
 
-// Deploy creates a new Vercel deployment
-func (s *VercelService) Deploy(projectName, workspacePath string) (*VercelDeployment, error) {
-	// In a real implementation, you would:
-	// 1. Zip the workspace
-	// 2. Upload files to Vercel
-	// 3. Create deployment
+// IsVercelNotFound reports whether err is a Vercel 404 (e.g. a deleted deployment)
+func IsVercelNotFound(err error) bool {
+	var apiErr *VercelAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
 
-	// For now, this is a simplified version
-	url := "https://api.vercel.com/v13/deployments"
+// isVercelRetryable reports whether a failed call is worth retrying: network
+// errors, rate limits and server errors
+func isVercelRetryable(err error) bool {
+	var apiErr *VercelAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
 
-	reqBody := VercelDeploymentRequest{
-		Name:   projectName,
-		Target: "preview",
+// withRetry runs fn up to 4 times with exponential backoff while it fails with a retryable error
+func withRetry(ctx context.Context, fn func() error) error {
+	delay := time.Second
+	var err error
+	for attempt := 1; attempt <= 4; attempt++ {
+		if err = fn(); err == nil || !isVercelRetryable(err) || attempt == 4 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
 	}
+	return err
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
+// request sends an authenticated JSON request to the Vercel API and decodes the
+// response into out when it is not nil
+func (s *VercelService) request(method, path string, body interface{}, out interface{}) error {
+	return s.requestContext(context.Background(), method, path, body, out)
+}
+
+// requestContext is request bound to ctx
+func (s *VercelService) requestContext(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	headers := map[string]string{}
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(jsonData)
+		headers["Content-Type"] = "application/json"
+	}
+
+	return s.do(ctx, s.Client, method, path, reqBody, headers, out)
+}
+
+// do sends an authenticated request, scoped to the configured team
+func (s *VercelService) do(ctx context.Context, client *http.Client, method, path string, body io.Reader, headers map[string]string, out interface{}) error {
+	if s.Config.TeamID != "" {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path += separator + "teamId=" + url.QueryEscape(s.Config.TeamID)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, method, s.Config.BaseURL+path, body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.Config.Token)
-	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
-	resp, err := s.Client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("vercel deployment failed: %s", string(body))
+		apiErr := &VercelAPIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+		var errBody struct {
+			Error struct {
+				Code    string   `json:"code"`
+				Missing []string `json:"missing"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil {
+			apiErr.Code = errBody.Error.Code
+			apiErr.Missing = errBody.Error.Missing
+		}
+		return apiErr
+	}
+
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// PromoteDeployment points the project's production domains at an existing deployment
+func (s *VercelService) PromoteDeployment(projectID, deploymentID string) error {
+	path := fmt.Sprintf("/v10/projects/%s/promote/%s", projectID, deploymentID)
+	if err := s.request("POST", path, nil, nil); err != nil {
+		return fmt.Errorf("vercel promotion failed: %w", err)
 	}
 
+	return nil
+}
+
+// GetDeploymentStatus gets the status of a deployment by ID or URL
+func (s *VercelService) GetDeploymentStatus(deploymentID string) (*VercelDeployment, error) {
 	var deployment VercelDeployment
-	if err := json.Unmarshal(body, &deployment); err != nil {
+	if err := s.request("GET", "/v13/deployments/"+deploymentID, nil, &deployment); err != nil {
 		return nil, err
 	}
 
 	return &deployment, nil
 }
 
-// PromoteDeployment promotes a deployment to production
-func (s *VercelService) PromoteDeployment(deploymentID string) error {
-	url := fmt.Sprintf("https://api.vercel.com/v13/deployments/%s/promote", deploymentID)
-
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return err
+// GetProductionURL returns the stable production URL of a project
+func (s *VercelService) GetProductionURL(projectID string) (string, error) {
+	var project struct {
+		Name    string `json:"name"`
+		Targets struct {
+			Production *struct {
+				Alias []string `json:"alias"`
+			} `json:"production"`
+		} `json:"targets"`
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.Config.Token)
-
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return err
+	if err := s.request("GET", "/v9/projects/"+projectID, nil, &project); err != nil {
+		return "", fmt.Errorf("failed to get project: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("vercel promotion failed: %s", string(body))
+	if project.Targets.Production != nil && len(project.Targets.Production.Alias) > 0 {
+		return "https://" + project.Targets.Production.Alias[0], nil
 	}
 
-	return nil
+	// Every project gets {name}.vercel.app once it has a production deployment
+	return fmt.Sprintf("https://%s.vercel.app", project.Name), nil
 }
 
-// GetDeploymentStatus gets the status of a deployment
-func (s *VercelService) GetDeploymentStatus(deploymentID string) (*VercelDeployment, error) {
-	url := fmt.Sprintf("https://api.vercel.com/v13/deployments/%s", deploymentID)
+// VercelProjectDomain is a domain attached to a Vercel project
+type VercelProjectDomain struct {
+	Name         string `json:"name"`
+	Verified     bool   `json:"verified"`
+	Verification []struct {
+		Type   string `json:"type"`
+		Domain string `json:"domain"`
+		Value  string `json:"value"`
+		Reason string `json:"reason"`
+	} `json:"verification"`
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// AddProjectDomain attaches a domain to a project
+func (s *VercelService) AddProjectDomain(projectID, domain string) (*VercelProjectDomain, error) {
+	var projectDomain VercelProjectDomain
+	path := fmt.Sprintf("/v10/projects/%s/domains", projectID)
+	if err := s.request("POST", path, map[string]string{"name": domain}, &projectDomain); err != nil {
+		return nil, fmt.Errorf("failed to add domain: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.Config.Token)
+	return &projectDomain, nil
+}
 
-	resp, err := s.Client.Do(req)
-	if err != nil {
+// GetProjectDomain returns a project domain and its pending verification records
+func (s *VercelService) GetProjectDomain(projectID, domain string) (*VercelProjectDomain, error) {
+	var projectDomain VercelProjectDomain
+	path := fmt.Sprintf("/v9/projects/%s/domains/%s", projectID, domain)
+	if err := s.request("GET", path, nil, &projectDomain); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	return &projectDomain, nil
+}
+
+// VerifyProjectDomain asks Vercel to re-check a domain's DNS verification records
+func (s *VercelService) VerifyProjectDomain(projectID, domain string) (*VercelProjectDomain, error) {
+	var projectDomain VercelProjectDomain
+	path := fmt.Sprintf("/v9/projects/%s/domains/%s/verify", projectID, domain)
+	if err := s.request("POST", path, nil, &projectDomain); err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("failed to get deployment status: %s", string(body))
+	return &projectDomain, nil
+}
+
+// RemoveProjectDomain detaches a domain from a project
+func (s *VercelService) RemoveProjectDomain(projectID, domain string) error {
+	path := fmt.Sprintf("/v9/projects/%s/domains/%s", projectID, domain)
+	if err := s.request("DELETE", path, nil, nil); err != nil {
+		return fmt.Errorf("failed to remove domain: %w", err)
 	}
 
-	var deployment VercelDeployment
-	if err := json.Unmarshal(body, &deployment); err != nil {
-		return nil, err
+	return nil
+}
+
+// AssignAlias points a domain at a specific deployment
+func (s *VercelService) AssignAlias(deploymentID, domain string) error {
+	path := fmt.Sprintf("/v2/deployments/%s/aliases", deploymentID)
+	if err := s.request("POST", path, map[string]string{"alias": domain}, nil); err != nil {
+		return fmt.Errorf("failed to alias %s: %w", domain, err)
 	}
 
-	return &deployment, nil
+	return nil
 }
 
-// DisableDeploymentProtection disables SSO/password protection for a project
-func (s *VercelService) DisableDeploymentProtection(projectID string) error {
-	url := fmt.Sprintf("https://api.vercel.com/v9/projects/%s", projectID)
+// VercelEnvVar is a project environment variable
+type VercelEnvVar struct {
+	ID     string   `json:"id"`
+	Key    string   `json:"key"`
+	Target []string `json:"target"`
+}
 
+// UpsertProjectEnv creates or replaces an encrypted project environment variable
+func (s *VercelService) UpsertProjectEnv(projectID, key, value string, targets []string) error {
 	reqBody := map[string]interface{}{
-		"ssoProtection":      nil,
-		"passwordProtection": nil,
+		"key":    key,
+		"value":  value,
+		"type":   "encrypted",
+		"target": targets,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return err
+	path := fmt.Sprintf("/v10/projects/%s/env?upsert=true", projectID)
+	if err := s.request("POST", path, reqBody, nil); err != nil {
+		return fmt.Errorf("failed to set env %s: %w", key, err)
 	}
 
-	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
+	return nil
+}
+
+// ListProjectEnv lists a project's environment variables (values are not returned)
+func (s *VercelService) ListProjectEnv(projectID string) ([]VercelEnvVar, error) {
+	var result struct {
+		Envs []VercelEnvVar `json:"envs"`
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.Config.Token)
-	req.Header.Set("Content-Type", "application/json")
+	if err := s.request("GET", fmt.Sprintf("/v9/projects/%s/env", projectID), nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to list env: %w", err)
+	}
 
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return err
+	return result.Envs, nil
+}
+
+// DeleteProjectEnv removes a project environment variable by ID
+func (s *VercelService) DeleteProjectEnv(projectID, envID string) error {
+	path := fmt.Sprintf("/v9/projects/%s/env/%s", projectID, envID)
+	if err := s.request("DELETE", path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete env: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to disable protection: %s", string(body))
+	return nil
+}
+
+// DeleteDeployment deletes a deployment; an already deleted deployment is not an error
+func (s *VercelService) DeleteDeployment(ctx context.Context, deploymentID string) error {
+	err := s.requestContext(ctx, "DELETE", "/v13/deployments/"+deploymentID, nil, nil)
+	if err != nil && !IsVercelNotFound(err) {
+		return fmt.Errorf("failed to delete deployment %s: %w", deploymentID, err)
+	}
+	return nil
+}
+
+// DeleteProject deletes a project with all its deployments and domains; an
+// already deleted project is not an error
+func (s *VercelService) DeleteProject(ctx context.Context, idOrName string) error {
+	err := s.requestContext(ctx, "DELETE", "/v9/projects/"+url.PathEscape(idOrName), nil, nil)
+	if err != nil && !IsVercelNotFound(err) {
+		return fmt.Errorf("failed to delete project %s: %w", idOrName, err)
+	}
+	return nil
+}
+
+// DisableDeploymentProtection disables SSO/password protection for a project
+func (s *VercelService) DisableDeploymentProtection(projectID string) error {
+	reqBody := map[string]interface{}{
+		"ssoProtection":      nil,
+		"passwordProtection": nil,
+	}
+
+	if err := s.request("PATCH", "/v9/projects/"+projectID, reqBody, nil); err != nil {
+		return fmt.Errorf("failed to disable protection: %w", err)
 	}
 
 	return nil
@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,12 +15,42 @@ import (
 	"github.com/rapidbuildapp/rapidbuild/internal/models"
 )
 
+// versionColumns is the column list scanned by scanVersion
+const versionColumns = "id, app_id, version_number, status, s3_code_path, s3_output_path, vercel_url, vercel_deploy_id, s3_prod_output_path, prod_deploy_id, deployment_state, build_log, error_message, checks_report, smoke_report, instructions, commit_sha, created_at"
+
+// versionSummaryColumns is versionColumns without the build log and reports, for list summaries
+const versionSummaryColumns = "id, app_id, version_number, status, s3_code_path, s3_output_path, vercel_url, vercel_deploy_id, s3_prod_output_path, prod_deploy_id, deployment_state, NULL::text, error_message, NULL::jsonb, NULL::jsonb, LEFT(instructions, 200), commit_sha, created_at"
+
+// versionListSpec is how list queries apply to versions
+var versionListSpec = listSpec{
+	sorts: map[string]sortColumn{
+		"version_number": {"version_number", "int"},
+		"created_at":     {"created_at", "timestamptz"},
+	},
+	defaultSort: "-version_number",
+}
+
+var (
+	// ErrVersionPromoted is returned when deleting the version serving production
+	ErrVersionPromoted     = errors.New("cannot delete the promoted version, promote another version first")
+	ErrVersionNotFound     = errors.New("version not found")
+	ErrVersionNotDeployed  = errors.New("version has no deployment to preview")
+	ErrNoProductionVersion = errors.New("app has no production version")
+)
+
 type VersionService struct {
-	DB *db.PostgresClient
+	DB     *db.PostgresClient
+	Vercel *VercelService
+	Events *EventService
 }
 
-func NewVersionService(dbClient *db.PostgresClient) *VersionService {
-	return &VersionService{DB: dbClient}
+// NewVersionService creates the version service. Version events go through
+// eventService; without one they only reach webhooks.
+func NewVersionService(dbClient *db.PostgresClient, vercelService *VercelService, eventService *EventService) *VersionService {
+	if eventService == nil {
+		eventService = NewEventService(nil, dbClient)
+	}
+	return &VersionService{DB: dbClient, Vercel: vercelService, Events: eventService}
 }
 
 // CreateVersion creates a new version for an app
@@ -40,16 +74,11 @@ func (s *VersionService) CreateVersion(ctx context.Context, appID string) (*mode
 	insertQuery := `
 		INSERT INTO versions (id, app_id, version_number, status, created_at)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, app_id, version_number, status, s3_code_path, vercel_url, vercel_deploy_id, build_log, error_message, created_at
-	`
+		RETURNING ` + versionColumns
 
-	err = s.DB.QueryRow(ctx, insertQuery,
+	err = scanVersion(s.DB.QueryRow(ctx, insertQuery,
 		version.ID, version.AppID, version.VersionNumber, version.Status, version.CreatedAt,
-	).Scan(
-		&version.ID, &version.AppID, &version.VersionNumber, &version.Status,
-		&version.S3CodePath, &version.VercelURL, &version.VercelDeployID,
-		&version.BuildLog, &version.ErrorMessage, &version.CreatedAt,
-	)
+	), &version)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create version: %w", err)
@@ -61,17 +90,9 @@ func (s *VersionService) CreateVersion(ctx context.Context, appID string) (*mode
 // GetVersion retrieves a version by ID
 func (s *VersionService) GetVersion(ctx context.Context, versionID string) (*models.Version, error) {
 	version := &models.Version{}
-	query := `
-		SELECT id, app_id, version_number, status, s3_code_path, vercel_url, vercel_deploy_id, build_log, error_message, created_at
-		FROM versions
-		WHERE id = $1
-	`
+	query := `SELECT ` + versionColumns + ` FROM versions WHERE id = $1`
 
-	err := s.DB.QueryRow(ctx, query, versionID).Scan(
-		&version.ID, &version.AppID, &version.VersionNumber, &version.Status,
-		&version.S3CodePath, &version.VercelURL, &version.VercelDeployID,
-		&version.BuildLog, &version.ErrorMessage, &version.CreatedAt,
-	)
+	err := scanVersion(s.DB.QueryRow(ctx, query, versionID), version)
 
 	if err != nil {
 		return nil, fmt.Errorf("version not found: %w", err)
@@ -80,10 +101,50 @@ func (s *VersionService) GetVersion(ctx context.Context, versionID string) (*mod
 	return version, nil
 }
 
+// QueryVersions retrieves a page of an app's versions, and the cursor of the next page
+func (s *VersionService) QueryVersions(ctx context.Context, appID string, q models.ListQuery) ([]models.Version, string, error) {
+	plan, err := planList(q, versionListSpec, []interface{}{appID})
+	if err != nil {
+		return nil, "", err
+	}
+
+	columns := versionColumns
+	if q.Summary {
+		columns = versionSummaryColumns
+	}
+	query := `SELECT ` + columns + ` FROM versions WHERE app_id = $1` + plan.where() + plan.tail()
+
+	rows, err := s.DB.Query(ctx, query, plan.args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []models.Version{}
+	for rows.Next() {
+		var version models.Version
+		if err := scanVersion(rows, &version); err != nil {
+			return nil, "", fmt.Errorf("failed to scan version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating versions: %w", err)
+	}
+
+	versions, next := page(plan, versions, func(v models.Version, field string) (string, string) {
+		if field == "created_at" {
+			return v.CreatedAt.Format(time.RFC3339Nano), v.ID
+		}
+		return strconv.Itoa(v.VersionNumber), v.ID
+	})
+	return versions, next, nil
+}
+
 // ListVersions retrieves all versions for an app
 func (s *VersionService) ListVersions(ctx context.Context, appID string) ([]models.Version, error) {
-	query := `
-		SELECT id, app_id, version_number, status, s3_code_path, vercel_url, vercel_deploy_id, build_log, error_message, created_at
+	query := `SELECT ` + versionColumns + `
 		FROM versions
 		WHERE app_id = $1
 		ORDER BY version_number DESC
@@ -98,12 +159,7 @@ func (s *VersionService) ListVersions(ctx context.Context, appID string) ([]mode
 	var versions []models.Version
 	for rows.Next() {
 		var version models.Version
-		err := rows.Scan(
-			&version.ID, &version.AppID, &version.VersionNumber, &version.Status,
-			&version.S3CodePath, &version.VercelURL, &version.VercelDeployID,
-			&version.BuildLog, &version.ErrorMessage, &version.CreatedAt,
-		)
-		if err != nil {
+		if err := scanVersion(rows, &version); err != nil {
 			return nil, fmt.Errorf("failed to scan version: %w", err)
 		}
 		versions = append(versions, version)
@@ -124,11 +180,28 @@ func (s *VersionService) UpdateVersion(ctx context.Context, versionID string, up
 	argCount := 1
 	setClauses := []string{}
 
+	// The previous status tells which status changes raise events, and a
+	// version that completed before (e.g. one being redeployed) raises no new
+	// building or completed events
+	var previousStatus string
+	var completedBefore bool
+	status, hasStatus := updates["status"].(string)
+	if hasStatus {
+		err := s.DB.QueryRow(ctx, `SELECT status, completed_at IS NOT NULL FROM versions WHERE id = $1`, versionID).Scan(&previousStatus, &completedBefore)
+		if err != nil && !errors.Is(err, db.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get version status: %w", err)
+		}
+	}
+
 	// Handle all possible update fields
-	if status, ok := updates["status"].(string); ok {
+	if hasStatus {
 		setClauses = append(setClauses, fmt.Sprintf("status = $%d", argCount))
 		args = append(args, status)
 		argCount++
+
+		if status == "completed" || status == "promoted" {
+			setClauses = append(setClauses, "completed_at = COALESCE(completed_at, NOW())")
+		}
 	}
 
 	if s3CodePath, ok := updates["s3_code_path"].(string); ok {
@@ -137,6 +210,12 @@ func (s *VersionService) UpdateVersion(ctx context.Context, versionID string, up
 		argCount++
 	}
 
+	if s3OutputPath, ok := updates["s3_output_path"].(string); ok {
+		setClauses = append(setClauses, fmt.Sprintf("s3_output_path = $%d", argCount))
+		args = append(args, s3OutputPath)
+		argCount++
+	}
+
 	if vercelURL, ok := updates["vercel_url"].(string); ok {
 		setClauses = append(setClauses, fmt.Sprintf("vercel_url = $%d", argCount))
 		args = append(args, vercelURL)
@@ -149,6 +228,25 @@ func (s *VersionService) UpdateVersion(ctx context.Context, versionID string, up
 		argCount++
 	}
 
+	// Production build fields are cleared with a nil pointer
+	if prodOutputPath, ok := updates["s3_prod_output_path"].(*string); ok {
+		setClauses = append(setClauses, fmt.Sprintf("s3_prod_output_path = $%d", argCount))
+		args = append(args, prodOutputPath)
+		argCount++
+	}
+
+	if prodDeployID, ok := updates["prod_deploy_id"].(*string); ok {
+		setClauses = append(setClauses, fmt.Sprintf("prod_deploy_id = $%d", argCount))
+		args = append(args, prodDeployID)
+		argCount++
+	}
+
+	if deploymentState, ok := updates["deployment_state"].(string); ok {
+		setClauses = append(setClauses, fmt.Sprintf("deployment_state = $%d", argCount))
+		args = append(args, deploymentState)
+		argCount++
+	}
+
 	if buildLog, ok := updates["build_log"].(string); ok {
 		setClauses = append(setClauses, fmt.Sprintf("build_log = $%d", argCount))
 		args = append(args, buildLog)
@@ -161,6 +259,38 @@ func (s *VersionService) UpdateVersion(ctx context.Context, versionID string, up
 		argCount++
 	}
 
+	if checksReport, ok := updates["checks_report"].(*models.ChecksReport); ok {
+		data, err := json.Marshal(checksReport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode checks report: %w", err)
+		}
+		setClauses = append(setClauses, fmt.Sprintf("checks_report = $%d", argCount))
+		args = append(args, string(data))
+		argCount++
+	}
+
+	if smokeReport, ok := updates["smoke_report"].(*models.SmokeReport); ok {
+		data, err := json.Marshal(smokeReport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode smoke report: %w", err)
+		}
+		setClauses = append(setClauses, fmt.Sprintf("smoke_report = $%d", argCount))
+		args = append(args, string(data))
+		argCount++
+	}
+
+	if instructions, ok := updates["instructions"].(string); ok {
+		setClauses = append(setClauses, fmt.Sprintf("instructions = $%d", argCount))
+		args = append(args, instructions)
+		argCount++
+	}
+
+	if commitSHA, ok := updates["commit_sha"].(string); ok {
+		setClauses = append(setClauses, fmt.Sprintf("commit_sha = $%d", argCount))
+		args = append(args, commitSHA)
+		argCount++
+	}
+
 	// Legacy fields for backwards compatibility
 	if deployURL, ok := updates["deploy_url"].(string); ok {
 		setClauses = append(setClauses, fmt.Sprintf("vercel_url = $%d", argCount))
@@ -183,26 +313,90 @@ func (s *VersionService) UpdateVersion(ctx context.Context, versionID string, up
 	query += fmt.Sprintf(" WHERE id = $%d", argCount)
 	args = append(args, versionID)
 
-	query += " RETURNING id, app_id, version_number, status, s3_code_path, vercel_url, vercel_deploy_id, build_log, error_message, created_at"
+	query += " RETURNING " + versionColumns
 
 	version := &models.Version{}
-	err := s.DB.QueryRow(ctx, query, args...).Scan(
-		&version.ID, &version.AppID, &version.VersionNumber, &version.Status,
-		&version.S3CodePath, &version.VercelURL, &version.VercelDeployID,
-		&version.BuildLog, &version.ErrorMessage, &version.CreatedAt,
-	)
+	err := scanVersion(s.DB.QueryRow(ctx, query, args...), version)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update version: %w", err)
 	}
 
+	if hasStatus {
+		if eventType := versionStatusEvent(previousStatus, version.Status, completedBefore); eventType != "" {
+			s.Events.Publish(ctx, versionEvent(eventType, version))
+		}
+	}
+
 	return version, nil
 }
 
+// versionStatusEvent returns the event raised by a version's status change, if
+// any. Demoting a promoted version back to completed raises none, and neither
+// does redeploying a version that completed before, unless it fails.
+func versionStatusEvent(previous, status string, completedBefore bool) string {
+	switch {
+	case status == previous:
+		return ""
+	case status == "building":
+		if completedBefore {
+			return ""
+		}
+		return EventVersionBuilding
+	case previous == "building" && (status == "completed" || status == "promoted"):
+		if completedBefore {
+			return ""
+		}
+		return EventVersionCompleted
+	case status == "failed" || status == "unhealthy":
+		return EventVersionFailed
+	}
+	return ""
+}
+
+func versionEvent(eventType string, version *models.Version) models.AppEvent {
+	data := map[string]interface{}{
+		"version_number": version.VersionNumber,
+		"status":         version.Status,
+	}
+	if version.VercelURL != nil {
+		data["url"] = *version.VercelURL
+	}
+	if version.ErrorMessage != nil && eventType == EventVersionFailed {
+		data["error"] = *version.ErrorMessage
+	}
+	return models.AppEvent{
+		Type:      eventType,
+		AppID:     version.AppID,
+		VersionID: version.ID,
+		Message:   fmt.Sprintf("Version %d is %s", version.VersionNumber, version.Status),
+		Data:      data,
+	}
+}
+
 // DeleteVersion deletes a version
 func (s *VersionService) DeleteVersion(ctx context.Context, versionID string) error {
+	version, err := s.GetVersion(ctx, versionID)
+	if err != nil {
+		return fmt.Errorf("version not found")
+	}
+
+	app, err := NewAppService(s.DB).GetAppByID(ctx, version.AppID)
+	if err != nil {
+		return err
+	}
+	if version.Status == "promoted" || (app.ProdVersion != nil && *app.ProdVersion == version.VersionNumber) {
+		return ErrVersionPromoted
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `DELETE FROM versions WHERE id = $1`
-	rowsAffected, err := s.DB.Exec(ctx, query, versionID)
+	rowsAffected, err := tx.Exec(ctx, query, versionID)
 	if err != nil {
 		return fmt.Errorf("failed to delete version: %w", err)
 	}
@@ -211,29 +405,289 @@ func (s *VersionService) DeleteVersion(ctx context.Context, versionID string) er
 		return fmt.Errorf("version not found")
 	}
 
+	// Code, build output and requirement uploads all live under the version prefix
+	jobs := NewJobService(s.DB)
+	if err := jobs.EnqueueTx(ctx, tx, JobCleanupS3Prefix, map[string]string{
+		"prefix": fmt.Sprintf("apps/%s/versions/%s/", version.AppID, version.ID),
+	}); err != nil {
+		return err
+	}
+	for _, deploymentID := range []*string{version.VercelDeployID, version.ProdDeployID} {
+		if deploymentID == nil || *deploymentID == "" {
+			continue
+		}
+		if err := jobs.EnqueueTx(ctx, tx, JobCleanupVercelDeployment, map[string]string{
+			"deployment_id": *deploymentID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to delete version: %w", err)
+	}
+
 	return nil
 }
 
-// PromoteVersion promotes a version to production
+// ListDeployedVersions retrieves versions with a Vercel deployment that were created
+// since the given time, plus every promoted version regardless of age
+func (s *VersionService) ListDeployedVersions(ctx context.Context, since time.Time) ([]models.Version, error) {
+	query := `SELECT ` + versionColumns + `
+		FROM versions
+		WHERE vercel_deploy_id IS NOT NULL
+		  AND (created_at >= $1 OR status = 'promoted')
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.DB.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployed versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []models.Version
+	for rows.Next() {
+		var version models.Version
+		if err := scanVersion(rows, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetVersionByNumber retrieves an app's version by its version number
+func (s *VersionService) GetVersionByNumber(ctx context.Context, appID string, versionNumber int) (*models.Version, error) {
+	version := &models.Version{}
+	query := `SELECT ` + versionColumns + ` FROM versions WHERE app_id = $1 AND version_number = $2`
+
+	if err := scanVersion(s.DB.QueryRow(ctx, query, appID, versionNumber), version); err != nil {
+		return nil, fmt.Errorf("version not found: %w", err)
+	}
+
+	return version, nil
+}
+
+// PromoteVersion promotes a version to production, both on Vercel and in the database.
+// If anything fails after Vercel switched production, it is rolled back to the
+// previous production deployment.
 func (s *VersionService) PromoteVersion(ctx context.Context, versionID string) error {
 	version, err := s.GetVersion(ctx, versionID)
 	if err != nil {
 		return err
 	}
 
-	// Update the app's prod_version
+	if version.Status != "completed" && version.Status != "promoted" {
+		return fmt.Errorf("only completed versions can be promoted (status: %s)", version.Status)
+	}
+
 	appService := NewAppService(s.DB)
-	_, err = appService.UpdateApp(ctx, version.AppID, "", map[string]interface{}{
-		"prod_version": version.VersionNumber,
-	})
+	app, err := appService.GetAppByID(ctx, version.AppID)
 	if err != nil {
 		return err
 	}
 
+	// The version currently in production, if any, is the rollback target
+	var previous *models.Version
+	if app.ProdVersion != nil && *app.ProdVersion != version.VersionNumber {
+		previous, err = s.GetVersionByNumber(ctx, app.ID, *app.ProdVersion)
+		if err != nil {
+			log.Printf("[Promote] Warning: Could not load previous production version %d: %v\n", *app.ProdVersion, err)
+		}
+	}
+
+	updates := map[string]interface{}{
+		"prod_version": version.VersionNumber,
+	}
+
+	deploymentID := productionDeployID(version)
+	promotedOnVercel := false
+	if s.Vercel != nil && app.VercelProjectID != nil && deploymentID != nil {
+		projectID := *app.VercelProjectID
+
+		if err := s.Vercel.PromoteDeployment(projectID, *deploymentID); err != nil {
+			s.rollbackProduction(projectID, previous)
+			return err
+		}
+		promotedOnVercel = true
+
+		prodURL, err := s.Vercel.GetProductionURL(projectID)
+		if err != nil {
+			log.Printf("[Promote] Warning: Could not read production URL for project %s: %v\n", projectID, err)
+		} else {
+			updates["prod_url"] = prodURL
+		}
+	} else {
+		log.Printf("[Promote] Warning: Version %s has no Vercel deployment/project, promoting in database only\n", versionID)
+	}
+
+	// Update the app's prod_version
+	if _, err := appService.UpdateApp(ctx, version.AppID, "", updates); err != nil {
+		if promotedOnVercel {
+			s.rollbackProduction(*app.VercelProjectID, previous)
+		}
+		return err
+	}
+
+	// Serve the new production deployment on the app's custom domains
+	if promotedOnVercel {
+		NewDomainService(s.DB, s.Vercel).AliasVerifiedDomains(ctx, app.ID, *deploymentID)
+	}
+
+	// Only one version is promoted at a time
+	if previous != nil && previous.Status == "promoted" {
+		if _, err := s.UpdateVersion(ctx, previous.ID, map[string]interface{}{
+			"status": "completed",
+		}); err != nil {
+			log.Printf("[Promote] Warning: Failed to demote version %s: %v\n", previous.ID, err)
+		}
+	}
+
 	// Update version status
-	_, err = s.UpdateVersion(ctx, versionID, map[string]interface{}{
+	if _, err := s.UpdateVersion(ctx, versionID, map[string]interface{}{
 		"status": "promoted",
-	})
+	}); err != nil {
+		return err
+	}
+
+	queueGitExport(ctx, s.DB, app.ID, versionID, GitExportEventPromoted)
+
+	version.Status = "promoted"
+	event := versionEvent(EventVersionPromoted, version)
+	if prodURL, ok := updates["prod_url"].(string); ok {
+		event.Data["production_url"] = prodURL
+	}
+	s.Events.Publish(ctx, event)
+	return nil
+}
+
+// RestoreProduction points production at the promoted version's deployment
+// again after the version was redeployed, e.g. into a recreated project.
+// Unlike PromoteVersion it leaves the version history and events alone.
+func (s *VersionService) RestoreProduction(ctx context.Context, versionID string) error {
+	version, err := s.GetVersion(ctx, versionID)
+	if err != nil {
+		return err
+	}
+
+	appService := NewAppService(s.DB)
+	app, err := appService.GetAppByID(ctx, version.AppID)
+	if err != nil {
+		return err
+	}
+	if app.ProdVersion == nil || *app.ProdVersion != version.VersionNumber {
+		return fmt.Errorf("version %d is not the production version", version.VersionNumber)
+	}
+
+	deploymentID := productionDeployID(version)
+	if s.Vercel == nil || app.VercelProjectID == nil || deploymentID == nil {
+		return fmt.Errorf("version %s has no Vercel deployment for production", versionID)
+	}
+	projectID := *app.VercelProjectID
+
+	if err := s.Vercel.PromoteDeployment(projectID, *deploymentID); err != nil {
+		return err
+	}
+
+	prodURL, err := s.Vercel.GetProductionURL(projectID)
+	if err != nil {
+		log.Printf("[Promote] Warning: Could not read production URL for project %s: %v\n", projectID, err)
+	} else if _, err := appService.UpdateApp(ctx, app.ID, "", map[string]interface{}{
+		"prod_url": prodURL,
+	}); err != nil {
+		return err
+	}
+
+	NewDomainService(s.DB, s.Vercel).AliasVerifiedDomains(ctx, app.ID, *deploymentID)
+	return nil
+}
+
+// rollbackProduction points production back at the previous production deployment
+func (s *VersionService) rollbackProduction(projectID string, previous *models.Version) {
+	if previous == nil || productionDeployID(previous) == nil {
+		log.Printf("[Promote] No previous production deployment to roll back to for project %s\n", projectID)
+		return
+	}
+
+	deploymentID := *productionDeployID(previous)
+	log.Printf("[Promote] Rolling back project %s to deployment %s\n", projectID, deploymentID)
+	if err := s.Vercel.PromoteDeployment(projectID, deploymentID); err != nil {
+		log.Printf("[Promote] ERROR: Rollback of project %s failed: %v\n", projectID, err)
+	}
+}
+
+// productionDeployID is the deployment production serves for a version: the one
+// built with production secrets if the version has one, else its preview deployment
+func productionDeployID(version *models.Version) *string {
+	if version.ProdDeployID != nil && *version.ProdDeployID != "" {
+		return version.ProdDeployID
+	}
+	return version.VercelDeployID
+}
+
+// scanVersion scans a row selected with versionColumns into version
+func scanVersion(row db.Row, version *models.Version) error {
+	return row.Scan(
+		&version.ID, &version.AppID, &version.VersionNumber, &version.Status,
+		&version.S3CodePath, &version.S3OutputPath, &version.VercelURL, &version.VercelDeployID,
+		&version.ProdOutputPath, &version.ProdDeployID,
+		&version.DeploymentState, &version.BuildLog, &version.ErrorMessage, &version.ChecksReport, &version.SmokeReport,
+		&version.Instructions, &version.CommitSHA, &version.CreatedAt,
+	)
+}
+
+// ResolveDeployment finds the deployment to show for an app: "latest" (or empty)
+// for the newest deployed version, "production" for the production URL, or a
+// version ID or number. Returns the version ID and URL.
+func (s *VersionService) ResolveDeployment(ctx context.Context, app *models.App, version string) (string, string, error) {
+	switch version {
+	case "", "latest":
+		versions, err := s.ListVersions(ctx, app.ID)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get versions: %w", err)
+		}
+		for _, v := range versions {
+			if isPreviewable(&v) {
+				return v.ID, *v.VercelURL, nil
+			}
+		}
+		return "", "", ErrVersionNotDeployed
+
+	case "production":
+		if app.ProdURL == nil || *app.ProdURL == "" || app.ProdVersion == nil {
+			return "", "", ErrNoProductionVersion
+		}
+		v, err := s.GetVersionByNumber(ctx, app.ID, *app.ProdVersion)
+		if err != nil {
+			return "", "", ErrNoProductionVersion
+		}
+		return v.ID, *app.ProdURL, nil
+	}
+
+	var v *models.Version
+	var err error
+	if number, convErr := strconv.Atoi(version); convErr == nil {
+		v, err = s.GetVersionByNumber(ctx, app.ID, number)
+	} else {
+		v, err = s.GetVersion(ctx, version)
+	}
+	if err != nil || v.AppID != app.ID {
+		return "", "", ErrVersionNotFound
+	}
+	if !isPreviewable(v) {
+		return "", "", ErrVersionNotDeployed
+	}
+
+	return v.ID, *v.VercelURL, nil
+}
 
-	return err
+// isPreviewable reports whether a version has a live deployment
+func isPreviewable(v *models.Version) bool {
+	return (v.Status == "completed" || v.Status == "promoted") && v.VercelURL != nil && *v.VercelURL != ""
 }
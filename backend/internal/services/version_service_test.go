@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestVersionStatusEvent(t *testing.T) {
+	tests := []struct {
+		name            string
+		previous        string
+		status          string
+		completedBefore bool
+		want            string
+	}{
+		{"build starts", "pending", "building", false, EventVersionBuilding},
+		{"build completes", "building", "completed", false, EventVersionCompleted},
+		{"build fails", "building", "failed", false, EventVersionFailed},
+		{"smoke tests fail", "building", "unhealthy", false, EventVersionFailed},
+		{"promotion", "completed", "promoted", true, ""},
+		{"demotion", "promoted", "completed", true, ""},
+		{"unchanged", "building", "building", false, ""},
+		{"redeploy starts", "completed", "building", true, ""},
+		{"redeploy completes", "building", "completed", true, ""},
+		{"redeploy fails", "building", "failed", true, EventVersionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionStatusEvent(tt.previous, tt.status, tt.completedBefore); got != tt.want {
+				t.Errorf("versionStatusEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,571 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/db"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// JobWebhookDeliver sends one webhook delivery
+const JobWebhookDeliver = "webhook.deliver" // payload: delivery_id
+
+// Webhook limits
+const (
+	maxWebhooksPerUser          = 20
+	maxWebhookURLLength         = 2048
+	maxWebhookResponseBody      = 4096
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 200
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-RapidBuild-Signature" // t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	WebhookEventHeader     = "X-RapidBuild-Event"
+	WebhookDeliveryHeader  = "X-RapidBuild-Delivery"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// webhookEvents are the event types webhooks can subscribe to
+var webhookEvents = []string{
+	EventVersionBuilding, EventVersionCompleted, EventVersionFailed, EventVersionPromoted,
+	EventAppDeleted, EventDeploymentBroken, EventDeploymentRecovered,
+}
+
+const webhookColumns = "id, user_id, app_id, url, events, enabled, created_at, updated_at"
+
+const webhookDeliveryColumns = "id, webhook_id, event_type, app_id, payload, status, attempts, response_status, response_body, error, duration_ms, redelivery_of, created_at, delivered_at"
+
+type WebhookService struct {
+	DB      *db.PostgresClient
+	Config  *config.Config
+	Secrets *SecretService
+	Jobs    *JobService
+	client  *http.Client
+}
+
+func NewWebhookService(dbClient *db.PostgresClient, cfg *config.Config, secretService *SecretService) *WebhookService {
+	client := &http.Client{
+		Timeout: cfg.WebhookTimeout,
+		// A redirect is a failed delivery; following it could reach anywhere
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if !cfg.WebhookAllowPrivate {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second, Control: webhookDialControl}).DialContext
+		client.Transport = transport
+	}
+
+	return &WebhookService{
+		DB:      dbClient,
+		Config:  cfg,
+		Secrets: secretService,
+		Jobs:    NewJobService(dbClient),
+		client:  client,
+	}
+}
+
+// ListWebhooks returns a user's webhooks, only those of one app if appID is set
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID, appID string) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = $1`
+	args := []interface{}{userID}
+	if appID != "" {
+		query += ` AND app_id = $2`
+		args = append(args, appID)
+	}
+	query += ` ORDER BY created_at ASC`
+
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook returns one of a user's webhooks
+func (s *WebhookService) GetWebhook(ctx context.Context, webhookID, userID string) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND user_id = $2`
+
+	webhook, err := scanWebhook(s.DB.QueryRow(ctx, query, webhookID, userID))
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// CreateWebhook subscribes a URL to a user's events. The caller checks that the
+// user owns req.AppID. The returned webhook carries its signing secret, which
+// is not shown again.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID string, req models.CreateWebhookRequest) (*models.Webhook, error) {
+	webhookURL, err := validateWebhookURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := validateWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	if s.Secrets.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	var count int
+	if err := s.DB.QueryRow(ctx, `SELECT COUNT(*) FROM webhooks WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("%w: at most %d webhooks", ErrInvalidWebhook, maxWebhooksPerUser)
+	}
+
+	var appID *string
+	if req.AppID != nil && *req.AppID != "" {
+		appID = req.AppID
+	}
+
+	id := uuid.New().String()
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	encryptedSecret, err := s.Secrets.encrypt(id, "webhook", "secret", secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhooks (id, user_id, app_id, url, events, encrypted_secret, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, TRUE), NOW(), NOW())
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(s.DB.QueryRow(ctx, query, id, userID, appID, webhookURL, events, encryptedSecret, req.Enabled))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+// UpdateWebhook changes a webhook's URL, events or enabled flag
+func (s *WebhookService) UpdateWebhook(ctx context.Context, webhookID, userID string, req models.UpdateWebhookRequest) (*models.Webhook, error) {
+	var webhookURL, events interface{}
+	if req.URL != nil {
+		validated, err := validateWebhookURL(*req.URL)
+		if err != nil {
+			return nil, err
+		}
+		webhookURL = validated
+	}
+	if req.Events != nil {
+		validated, err := validateWebhookEvents(*req.Events)
+		if err != nil {
+			return nil, err
+		}
+		events = validated
+	}
+
+	query := `
+		UPDATE webhooks SET
+			url = COALESCE($3, url),
+			events = COALESCE($4, events),
+			enabled = COALESCE($5, enabled),
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(s.DB.QueryRow(ctx, query, webhookID, userID, webhookURL, events, req.Enabled))
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID, userID string) error {
+	rowsAffected, err := s.DB.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID, userID string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultWebhookDeliveryLimit
+	}
+	if limit > maxWebhookDeliveryLimit {
+		limit = maxWebhookDeliveryLimit
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := s.DB.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver queues a new delivery of an earlier delivery's payload
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID, userID string) (*models.WebhookDelivery, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, app_id, payload, status, redelivery_of, created_at)
+		SELECT $1, d.webhook_id, d.event_type, d.app_id, d.payload, 'pending', d.id, NOW()
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $2 AND d.webhook_id = $3 AND w.user_id = $4
+		RETURNING ` + webhookDeliveryColumns
+
+	delivery, err := scanWebhookDelivery(tx.QueryRow(ctx, query, uuid.New().String(), deliveryID, webhookID, userID))
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+
+	if err := s.Jobs.EnqueueTx(ctx, tx, JobWebhookDeliver, map[string]string{"delivery_id": delivery.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+	return delivery, nil
+}
+
+// Ping sends a ping event to a webhook right away, enabled or not, and returns
+// the delivery with its outcome
+func (s *WebhookService) Ping(ctx context.Context, webhookID, userID string) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := models.AppEvent{
+		Type:      EventPing,
+		Message:   "Webhook ping",
+		Data:      map[string]interface{}{"webhook_id": webhook.ID, "events": webhook.Events},
+		Timestamp: time.Now(),
+	}
+	if webhook.AppID != nil {
+		event.AppID = *webhook.AppID
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, app_id, payload, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', NOW())
+	`
+	deliveryID := uuid.New().String()
+	if _, err := s.DB.Exec(ctx, query, deliveryID, webhook.ID, EventPing, webhook.AppID, string(payload)); err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	// A failed ping is reported in the delivery, not as an error
+	delivery, err := s.Deliver(ctx, deliveryID, false)
+	if delivery != nil {
+		return delivery, nil
+	}
+	return nil, err
+}
+
+// Deliver sends a delivery and records the outcome. A failed attempt is marked
+// retrying if the caller will try again, otherwise failed; either way the
+// error is returned along with the updated delivery.
+func (s *WebhookService) Deliver(ctx context.Context, deliveryID string, retrying bool) (*models.WebhookDelivery, error) {
+	var webhookID, webhookURL, encryptedSecret, eventType, payload string
+	query := `
+		SELECT d.webhook_id, w.url, w.encrypted_secret, d.event_type, d.payload
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1
+	`
+	err := s.DB.QueryRow(ctx, query, deliveryID).Scan(&webhookID, &webhookURL, &encryptedSecret, &eventType, &payload)
+	if errors.Is(err, db.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+
+	if s.Secrets.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+	secret, err := s.Secrets.decrypt(webhookID, "webhook", "secret", encryptedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	start := time.Now()
+	responseStatus, responseBody, sendErr := s.send(ctx, webhookURL, deliveryID, eventType, secret, []byte(payload))
+	durationMS := int(time.Since(start).Milliseconds())
+
+	status := "succeeded"
+	var errorMessage *string
+	if sendErr != nil {
+		msg := sendErr.Error()
+		errorMessage = &msg
+		status = "failed"
+		if retrying {
+			status = "retrying"
+		}
+	}
+
+	update := `
+		UPDATE webhook_deliveries SET
+			status = $2, attempts = attempts + 1, response_status = $3, response_body = $4,
+			error = $5, duration_ms = $6, delivered_at = NOW()
+		WHERE id = $1
+		RETURNING ` + webhookDeliveryColumns
+
+	delivery, err := scanWebhookDelivery(s.DB.QueryRow(ctx, update,
+		deliveryID, status, responseStatus, responseBody, errorMessage, durationMS,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return delivery, sendErr
+}
+
+// send POSTs a signed payload. Any response other than 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, webhookURL, deliveryID, eventType, secret string, payload []byte) (*int, *string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RapidBuild-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, time.Now(), payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	// Postgres text can't hold NUL bytes or invalid UTF-8
+	body := strings.ToValidUTF8(strings.ReplaceAll(string(data), "\x00", ""), "�")
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &resp.StatusCode, &body, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return &resp.StatusCode, &body, nil
+}
+
+// SignWebhookPayload returns the signature header value for a payload sent at
+// t: receivers recompute the HMAC of "<t>.<body>" with their secret and should
+// reject old timestamps to prevent replays
+func SignWebhookPayload(secret string, t time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookQueuer is satisfied by both the client and a transaction
+type webhookQueuer interface {
+	execer
+	Query(ctx context.Context, query string, args ...interface{}) (db.Rows, error)
+}
+
+// queueWebhooks records a delivery of an app event for each enabled webhook
+// subscribed to it, on the app or on all of the owner's apps, and queues the
+// sends. q is typically a transaction when the app is about to be deleted.
+func queueWebhooks(ctx context.Context, jobs *JobService, q webhookQueuer, event models.AppEvent) error {
+	if event.AppID == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, app_id, payload, status, created_at)
+		SELECT gen_random_uuid(), w.id, $1, $2, $3, 'pending', NOW()
+		FROM webhooks w
+		WHERE w.enabled
+			AND (w.app_id = $2 OR (w.app_id IS NULL AND w.user_id = (SELECT user_id FROM apps WHERE id = $2)))
+			AND (cardinality(w.events) = 0 OR $1 = ANY(w.events))
+		RETURNING id
+	`
+	rows, err := q.Query(ctx, query, event.Type, event.AppID, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to queue webhooks: %w", err)
+	}
+	var deliveryIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to queue webhooks: %w", err)
+		}
+		deliveryIDs = append(deliveryIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to queue webhooks: %w", err)
+	}
+
+	for _, id := range deliveryIDs {
+		if err := jobs.EnqueueTx(ctx, q, JobWebhookDeliver, map[string]string{"delivery_id": id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: url is required", ErrInvalidWebhook)
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		return "", fmt.Errorf("%w: url must be at most %d characters", ErrInvalidWebhook, maxWebhookURLLength)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("%w: url must not contain credentials, verify the signature instead", ErrInvalidWebhook)
+	}
+	return rawURL, nil
+}
+
+func validateWebhookEvents(events []string) ([]string, error) {
+	validated := []string{}
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !containsString(webhookEvents, event) {
+			return nil, fmt.Errorf("%w: unknown event %q, expected one of %s", ErrInvalidWebhook, event, strings.Join(webhookEvents, ", "))
+		}
+		if !containsString(validated, event) {
+			validated = append(validated, event)
+		}
+	}
+	return validated, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// webhookDialControl refuses connections to addresses that aren't public, so
+// webhooks can't reach the server's own network. It runs after DNS resolution.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+func scanWebhook(row db.Row) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(
+		&webhook.ID, &webhook.UserID, &webhook.AppID, &webhook.URL, &webhook.Events, &webhook.Enabled,
+		&webhook.CreatedAt, &webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	return &webhook, nil
+}
+
+func scanWebhookDelivery(row db.Row) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload string
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.EventType, &delivery.AppID, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.ResponseStatus, &delivery.ResponseBody, &delivery.Error, &delivery.DurationMS,
+		&delivery.RedeliveryOf, &delivery.CreatedAt, &delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = json.RawMessage(payload)
+	return &delivery, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	at := time.Unix(1760000000, 0)
+	payload := []byte(`{"type":"version.completed"}`)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1760000000." + string(payload)))
+	want := "t=1760000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name    string
+		secret  string
+		at      time.Time
+		payload []byte
+		same    bool
+	}{
+		{"same input", "whsec_test", at, payload, true},
+		{"sub-second time", "whsec_test", at.Add(900 * time.Millisecond), payload, true},
+		{"other secret", "whsec_other", at, payload, false},
+		{"other time", "whsec_test", at.Add(time.Second), payload, false},
+		{"other payload", "whsec_test", at, []byte(`{"type":"version.failed"}`), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SignWebhookPayload(tt.secret, tt.at, tt.payload)
+			if (got == want) != tt.same {
+				t.Errorf("SignWebhookPayload() = %s, want equal to %s: %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:443", false},
+		{"172.16.3.4:443", false},
+		{"192.168.1.1:443", false},
+		{"[fd00::1]:443", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"224.0.0.1:80", false},
+		{"[::ffff:10.0.0.1]:80", false},
+		{"example.com:443", false},
+		{"no-port", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := webhookDialControl("tcp", tt.address, nil)
+			if (err == nil) != tt.allowed {
+				t.Errorf("webhookDialControl(%s) error = %v, want allowed %v", tt.address, err, tt.allowed)
+			}
+		})
+	}
+}
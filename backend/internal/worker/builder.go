@@ -1,13 +1,10 @@
 package worker
 
 import (
-	"archive/tar"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -17,10 +14,10 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/redis/go-redis/v9"
 	"github.com/rapidbuildapp/rapidbuild/config"
 	"github.com/rapidbuildapp/rapidbuild/internal/models"
 	"github.com/rapidbuildapp/rapidbuild/internal/services"
+	"github.com/redis/go-redis/v9"
 )
 
 type Builder struct {
@@ -30,17 +27,34 @@ type Builder struct {
 	VercelService  *services.VercelService
 	S3Client       *s3.Client
 	RedisClient    *redis.Client
+	Secrets        *services.SecretService
+	Schemas        *services.SchemaService
+	Databases      *services.AppDatabaseService
+	Templates      *services.TemplateService
+	GitExports     *services.GitExportService
+	History        *History
+	Cache          *BuildCache
 }
 
-func NewBuilder(cfg *config.Config, appService *services.AppService, versionService *services.VersionService, vercelService *services.VercelService, s3Client *s3.Client, redisClient *redis.Client) *Builder {
-	return &Builder{
+func NewBuilder(cfg *config.Config, appService *services.AppService, versionService *services.VersionService, vercelService *services.VercelService, secretService *services.SecretService, schemaService *services.SchemaService, databaseService *services.AppDatabaseService, templateService *services.TemplateService, gitExportService *services.GitExportService, s3Client *s3.Client, redisClient *redis.Client) *Builder {
+	b := &Builder{
 		Config:         cfg,
 		AppService:     appService,
 		VersionService: versionService,
 		VercelService:  vercelService,
+		Secrets:        secretService,
+		Schemas:        schemaService,
+		Databases:      databaseService,
+		Templates:      templateService,
+		GitExports:     gitExportService,
 		S3Client:       s3Client,
 		RedisClient:    redisClient,
+		History:        NewHistory(cfg, s3Client, versionService, services.NewCommentService(versionService.DB)),
 	}
+	if cfg.BuildCacheEnabled {
+		b.Cache = NewBuildCache(cfg, s3Client)
+	}
+	return b
 }
 
 // findClaudePath attempts to locate the Claude CLI executable
@@ -84,10 +98,14 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 
 	log.Printf("[BuildApp] Starting build for version %s, app %s\n", versionID, appID)
 
-	// Update status to building immediately
-	_, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+	// Update status to building immediately, recording what the version was asked for
+	updates := map[string]interface{}{
 		"status": "building",
-	})
+	}
+	if requirements != "" {
+		updates["instructions"] = requirements
+	}
+	_, err := b.VersionService.UpdateVersion(ctx, versionID, updates)
 	if err != nil {
 		log.Printf("[BuildApp] Warning: Failed to update status to building: %v\n", err)
 	}
@@ -111,9 +129,15 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		return b.handleError(ctx, versionID, "Failed to setup workspace", err)
 	}
 
+	// Snapshot the previous version's schemas to diff against once Claude has run
+	previousSchemas, err := parseSchemas(filepath.Join(workspaceDir, "schemas"))
+	if err != nil {
+		log.Printf("[BuildApp] Warning: Could not read previous schemas for app %s: %v\n", appID, err)
+	}
+
 	// Link Vercel project before Claude runs
 	b.sendProgress(versionID, "building", "Linking Vercel project...")
-	if err := b.linkVercel(ctx, workspaceDir, versionID); err != nil {
+	if err := b.linkVercel(ctx, workspaceDir, appID, versionID); err != nil {
 		return b.handleError(ctx, versionID, "Failed to link Vercel project", err)
 	}
 
@@ -126,6 +150,9 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		return b.handleError(ctx, versionID, "AI code generation failed", err)
 	}
 
+	// Restore cached dependencies so vercel build doesn't reinstall from scratch
+	restoredHash := b.restoreDependencies(ctx, workspaceDir, appID, versionID)
+
 	// Build/fix retry loop (max 3 attempts)
 	var buildErr error
 	for attempt := 1; attempt <= 3; attempt++ {
@@ -136,8 +163,21 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 			b.sendProgress(versionID, "building", fmt.Sprintf("Retrying build (attempt %d/3)...", attempt))
 		}
 
+		// Secrets are only present while building, never while Claude runs
+		removeSecrets, err := b.writeSecretsEnv(ctx, workspaceDir, appID, services.SecretScopePreview)
+		if err != nil {
+			return b.handleError(ctx, versionID, "Failed to load app secrets", err)
+		}
+
 		// Run Vercel build
-		buildErr = b.buildForVercel(ctx, workspaceDir, versionID, attempt)
+		buildErr = b.buildForVercel(ctx, workspaceDir, versionID, "preview", attempt)
+
+		// Run quality gates once the build itself succeeds
+		if buildErr == nil && b.Config.QualityGatesEnabled {
+			buildErr = b.checkQualityGates(ctx, workspaceDir, versionID, attempt)
+		}
+
+		removeSecrets()
 
 		if buildErr == nil {
 			// Build successful!
@@ -163,16 +203,23 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		// Loop will retry the build
 	}
 
-	// Create app database and collections if schemas exist
+	b.saveDependencies(ctx, workspaceDir, appID, restoredHash)
+
+	// Create or migrate the app database if schemas exist
 	schemasDir := filepath.Join(workspaceDir, "schemas")
 	if _, err := os.Stat(schemasDir); err == nil {
 		b.sendProgress(versionID, "building", "Setting up database schema...")
-		if err := b.setupDatabase(ctx, schemasDir, appID, ownerEmail); err != nil {
-			// Log warning but don't fail the build - database setup is optional
-			log.Printf("[BuildApp] Warning: Failed to setup database for app %s: %v\n", appID, err)
+		// The generated app can't work against a missing or stale database
+		if err := b.migrateDatabase(ctx, workspaceDir, appID, versionID, ownerEmail, previousSchemas); err != nil {
+			return b.handleError(ctx, versionID, "Failed to set up app database", err)
 		}
 	}
 
+	return b.publishBuild(ctx, workspaceDir, appID, versionID)
+}
+
+// publishBuild stores a built workspace's code and output in S3 and deploys it
+func (b *Builder) publishBuild(ctx context.Context, workspaceDir, appID, versionID string) error {
 	// Package core code
 	b.sendProgress(versionID, "building", "Packaging code...")
 	tarPath, err := b.packageCode(workspaceDir)
@@ -195,9 +242,31 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		return b.handleError(ctx, versionID, "Failed to update S3 path", err)
 	}
 
+	// Keep the prebuilt output so the version can be redeployed without rebuilding
+	b.sendProgress(versionID, "building", "Uploading build output...")
+	if err := b.storeBuildOutput(ctx, workspaceDir, appID, versionID); err != nil {
+		log.Printf("[BuildApp] Warning: Failed to store build output for version %s: %v\n", versionID, err)
+	}
+
+	if err := b.deployVersion(ctx, workspaceDir, appID, versionID, "completed", "Build completed successfully!"); err != nil {
+		return err
+	}
+
+	b.recordHistory(ctx, versionID, tarPath)
+
+	// The push job skips the version if it ended up unhealthy
+	if b.GitExports != nil {
+		b.GitExports.QueueVersion(ctx, appID, versionID, services.GitExportEventCompleted)
+	}
+	return nil
+}
+
+// deployVersion deploys the prebuilt workspace, smoke tests the deployment and
+// marks the version with finalStatus
+func (b *Builder) deployVersion(ctx context.Context, workspaceDir, appID, versionID, finalStatus, doneMessage string) error {
 	// Deploy to Vercel (workspace is pre-built by Claude)
 	b.sendProgress(versionID, "building", "Deploying to Vercel...")
-	vercelURL, vercelDeployID, err := b.deployToVercel(ctx, workspaceDir, appID, versionID)
+	vercelURL, vercelDeployID, err := b.deployToVercel(ctx, workspaceDir, appID, versionID, false)
 	if err != nil {
 		return b.handleError(ctx, versionID, "Failed to deploy to Vercel", err)
 	}
@@ -208,6 +277,19 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		if err != nil {
 			log.Printf("[Vercel] Warning: Could not read project ID to disable protection: %v\n", err)
 		} else {
+			// Promotion needs the project ID later
+			if _, err := b.AppService.UpdateApp(ctx, appID, "", map[string]interface{}{
+				"vercel_project_id": projectID,
+			}); err != nil {
+				log.Printf("[Vercel] Warning: Failed to store project ID for app %s: %v\n", appID, err)
+			}
+
+			if b.Secrets != nil {
+				if err := b.Secrets.SyncToVercel(ctx, appID, projectID); err != nil {
+					log.Printf("[Secrets] Warning: Failed to sync secrets to project %s: %v\n", projectID, err)
+				}
+			}
+
 			log.Printf("[Vercel] Disabling deployment protection for project %s\n", projectID)
 			if err := b.VercelService.DisableDeploymentProtection(projectID); err != nil {
 				// Log but don't fail the build - this is not critical
@@ -227,11 +309,19 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		return b.handleError(ctx, versionID, "Failed to update Vercel URL", err)
 	}
 
-	b.sendProgress(versionID, "completed", "Build completed successfully!")
+	// Probe the deployment before declaring the version completed
+	if b.Config.SmokeTestEnabled {
+		b.sendProgress(versionID, "building", "Running smoke tests against deployment...")
+		if report := b.smokeTestDeployment(ctx, workspaceDir, appID, versionID, vercelURL); !report.Healthy {
+			return b.markUnhealthy(ctx, appID, versionID, report)
+		}
+	}
+
+	b.sendProgress(versionID, "completed", doneMessage)
 
 	// Mark version as completed
 	_, err = b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
-		"status": "completed",
+		"status": finalStatus,
 	})
 	if err != nil {
 		log.Printf("[BuildApp] ERROR updating completion status for version %s: %v\n", versionID, err)
@@ -247,7 +337,7 @@ func (b *Builder) BuildApp(ctx context.Context, versionID, appID, requirements s
 		// Don't fail the build if app status update fails
 	}
 
-	log.Printf("[BuildApp] ✅ Build completed successfully for version %s\n", versionID)
+	log.Printf("[BuildApp] ✅ Version %s deployed and marked %s\n", versionID, finalStatus)
 	return nil
 }
 
@@ -255,28 +345,70 @@ func (b *Builder) setupWorkspace(ctx context.Context, workspaceDir, appID string
 	// Try to get the latest version's code from S3
 	versions, err := b.VersionService.ListVersions(ctx, appID)
 	if err != nil || len(versions) == 0 {
-		// No previous version, copy starter code
-		return b.copyStarterCode(workspaceDir)
+		// No previous version, start from the app's template
+		return b.copyTemplate(ctx, workspaceDir, appID)
 	}
 
-	// Find the latest completed version
+	// Find the latest built version (versions are listed newest first)
 	var latestVersion *models.Version
-	for i := len(versions) - 1; i >= 0; i-- {
-		if versions[i].Status == "completed" && versions[i].S3CodePath != nil && *versions[i].S3CodePath != "" {
+	for i := range versions {
+		built := versions[i].Status == "completed" || versions[i].Status == "promoted"
+		if built && versions[i].S3CodePath != nil && *versions[i].S3CodePath != "" {
 			latestVersion = &versions[i]
 			break
 		}
 	}
 
+	// Imported code is stored before it is built, so iterate on it even if
+	// its build failed
+	if latestVersion == nil {
+		for i := range versions {
+			if versions[i].S3CodePath != nil && *versions[i].S3CodePath != "" {
+				latestVersion = &versions[i]
+				break
+			}
+		}
+	}
+
 	if latestVersion == nil {
-		return b.copyStarterCode(workspaceDir)
+		return b.copyTemplate(ctx, workspaceDir, appID)
 	}
 
 	// Download from S3 and extract
 	return b.downloadFromS3(ctx, *latestVersion.S3CodePath, workspaceDir)
 }
 
-func (b *Builder) copyStarterCode(workspaceDir string) error {
+// copyTemplate copies the starter template the app was created from into the
+// workspace, falling back to the default starter
+func (b *Builder) copyTemplate(ctx context.Context, workspaceDir, appID string) error {
+	templateID := ""
+	if app, err := b.AppService.GetAppByID(ctx, appID); err == nil && app.Template != nil {
+		templateID = *app.Template
+	}
+
+	template, err := b.Templates.GetTemplate(templateID)
+	if err != nil {
+		log.Printf("[BuildApp] Template %q not found, using the default starter\n", templateID)
+		if template, err = b.Templates.GetTemplate(services.DefaultTemplateID); err != nil {
+			return err
+		}
+	}
+
+	if template.Archive != "" {
+		if err := b.downloadFromS3(ctx, template.Archive, workspaceDir); err != nil {
+			return fmt.Errorf("failed to download template %s: %w", template.ID, err)
+		}
+	} else if err := copyStarterCode(template.Dir, workspaceDir); err != nil {
+		return err
+	}
+
+	if template.BuildCommand != "" {
+		return writeBuildCommand(workspaceDir, template.BuildCommand)
+	}
+	return nil
+}
+
+func copyStarterCode(sourceDir, workspaceDir string) error {
 	// Use rsync to exclude heavy directories like node_modules, .vercel, .agent-history
 	cmd := exec.Command("rsync", "-av",
 		"--exclude=node_modules",
@@ -285,7 +417,8 @@ func (b *Builder) copyStarterCode(workspaceDir string) error {
 		"--exclude=dist",
 		"--exclude=.git",
 		"--exclude=.next",
-		b.Config.StarterCodeDir+"/",
+		"--exclude="+services.TemplateManifest,
+		sourceDir+"/",
 		workspaceDir+"/",
 	)
 	output, err := cmd.CombinedOutput()
@@ -295,6 +428,21 @@ func (b *Builder) copyStarterCode(workspaceDir string) error {
 	return nil
 }
 
+// writeBuildCommand sets a template's build command in vercel.json, unless the
+// template ships its own
+func writeBuildCommand(workspaceDir, buildCommand string) error {
+	path := filepath.Join(workspaceDir, "vercel.json")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(map[string]string{"buildCommand": buildCommand}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
 func (b *Builder) buildPrompt(appID, requirements string, comments []models.Comment) string {
 	var sb strings.Builder
 
@@ -315,6 +463,9 @@ func (b *Builder) buildPrompt(appID, requirements string, comments []models.Comm
 		for _, comment := range comments {
 			sb.WriteString(fmt.Sprintf("Page: %s\n", comment.PagePath))
 			sb.WriteString(fmt.Sprintf("Element: %s\n", comment.ElementPath))
+			if comment.AuthorName != nil {
+				sb.WriteString(fmt.Sprintf("From: %s (shared link reviewer)\n", *comment.AuthorName))
+			}
 			sb.WriteString(fmt.Sprintf("Comment: %s\n\n", comment.Content))
 		}
 	}
@@ -383,7 +534,7 @@ func (b *Builder) runClaude(ctx context.Context, workspaceDir, prompt, versionID
 }
 
 // buildForVercel runs vercel build to create the prebuilt output
-func (b *Builder) buildForVercel(ctx context.Context, workspaceDir, versionID string, attempt int) error {
+func (b *Builder) buildForVercel(ctx context.Context, workspaceDir, versionID, target string, attempt int) error {
 	// Create context with timeout (10 minutes for build)
 	buildCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
@@ -391,8 +542,8 @@ func (b *Builder) buildForVercel(ctx context.Context, workspaceDir, versionID st
 	log.Printf("[Vercel Build] Building project for version %s (attempt %d/3)\n", versionID, attempt)
 
 	cmd := exec.CommandContext(buildCtx, "bash", "-c", fmt.Sprintf(
-		"cd %s && vercel build --target=preview -y",
-		workspaceDir,
+		"cd %s && vercel build --target=%s -y",
+		workspaceDir, target,
 	))
 
 	cmd.Env = append(os.Environ(),
@@ -428,6 +579,29 @@ func (b *Builder) buildForVercel(ctx context.Context, workspaceDir, versionID st
 	return nil
 }
 
+// checkQualityGates runs the app's quality gates and stores the checks report on the version.
+// A failed required gate is returned as an error so it goes through the same fix loop as build errors.
+func (b *Builder) checkQualityGates(ctx context.Context, workspaceDir, versionID string, attempt int) error {
+	b.sendProgress(versionID, "building", "Running quality checks...")
+
+	report, err := b.runQualityGates(ctx, workspaceDir, versionID, attempt)
+	if err != nil {
+		return fmt.Errorf("quality checks could not run: %w", err)
+	}
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"checks_report": report,
+	}); err != nil {
+		log.Printf("[Checks] Warning: Failed to store checks report for version %s: %v\n", versionID, err)
+	}
+
+	if !report.Passed {
+		return fmt.Errorf("%s", checksFailureMessage(report))
+	}
+
+	return nil
+}
+
 // fixBuildErrors runs Claude to fix build errors
 func (b *Builder) fixBuildErrors(ctx context.Context, workspaceDir, versionID string, buildError string, attempt int) error {
 	log.Printf("[Claude Fix] Asking Claude to fix build errors (attempt %d/3)\n", attempt)
@@ -450,6 +624,7 @@ Please analyze the errors above and fix them. Focus on:
 - Import/export issues
 - Missing dependencies
 - Build configuration issues
+- Lint errors and failing tests
 
 Fix the issues directly in the code.`, attempt, buildError)
 
@@ -512,12 +687,6 @@ func (b *Builder) packageCode(workspaceDir string) (string, error) {
 	}
 	defer file.Close()
 
-	gzw := gzip.NewWriter(file)
-	defer gzw.Close()
-
-	tw := tar.NewWriter(gzw)
-	defer tw.Close()
-
 	// Directories to exclude from packaging
 	excludeDirs := map[string]bool{
 		"node_modules":   true,
@@ -528,77 +697,32 @@ func (b *Builder) packageCode(workspaceDir string) (string, error) {
 		".next":          true,
 	}
 
-	// Walk the workspace and add files to tar
-	return tarPath, filepath.Walk(workspaceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip the workspace dir itself
-		if path == workspaceDir {
-			return nil
-		}
-
-		// Get relative path for checking
-		relPath, err := filepath.Rel(workspaceDir, path)
-		if err != nil {
-			return err
-		}
-
-		// Skip excluded directories
+	return tarPath, writeTarGz(file, workspaceDir, func(relPath string, info os.FileInfo) bool {
 		parts := strings.Split(relPath, string(filepath.Separator))
-		if len(parts) > 0 && excludeDirs[parts[0]] {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Create tar header
-		header, err := tar.FileInfoHeader(info, "")
-		if err != nil {
-			return err
-		}
-
-		// Set relative path (already calculated above)
-		header.Name = relPath
-
-		if err := tw.WriteHeader(header); err != nil {
-			return err
-		}
-
-		// If it's a file, write its contents
-		if !info.IsDir() {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
-
-			_, err = io.Copy(tw, file)
-			return err
-		}
-
-		return nil
+		// Never ship app secrets, even if a build was interrupted before cleanup
+		return excludeDirs[parts[0]] || relPath == secretsEnvFile
 	})
 }
 
 func (b *Builder) uploadToS3(ctx context.Context, tarPath, appID, versionID string) (string, error) {
-	file, err := os.Open(tarPath)
+	key := fmt.Sprintf("apps/%s/versions/%s/code.tar.gz", appID, versionID)
+	return key, b.uploadFileToS3(ctx, tarPath, key)
+}
+
+func (b *Builder) uploadFileToS3(ctx context.Context, path, key string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer file.Close()
 
-	key := fmt.Sprintf("apps/%s/versions/%s/code.tar.gz", appID, versionID)
-
 	_, err = b.S3Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(b.Config.S3Bucket),
 		Key:    aws.String(key),
 		Body:   file,
 	})
 
-	return key, err
+	return err
 }
 
 func (b *Builder) downloadFromS3(ctx context.Context, s3Path, workspaceDir string) error {
@@ -611,44 +735,13 @@ func (b *Builder) downloadFromS3(ctx context.Context, s3Path, workspaceDir strin
 	}
 	defer result.Body.Close()
 
-	// Extract tar.gz
-	gzr, err := gzip.NewReader(result.Body)
-	if err != nil {
-		return err
-	}
-	defer gzr.Close()
-
-	tr := tar.NewReader(gzr)
-
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		target := filepath.Join(workspaceDir, header.Name)
-
-		if header.FileInfo().IsDir() {
-			os.MkdirAll(target, 0755)
-		} else {
-			file, err := os.Create(target)
-			if err != nil {
-				return err
-			}
-			io.Copy(file, tr)
-			file.Close()
-		}
-	}
-
-	return nil
+	return extractTarGz(result.Body, workspaceDir)
 }
 
 func (b *Builder) cleanup(workspaceDir string) {
 	os.RemoveAll(workspaceDir)
 	os.Remove(workspaceDir + ".tar.gz")
+	os.Remove(workspaceDir + "-output.tar.gz")
 }
 
 // getVercelProjectID reads the project ID from .vercel/project.json
@@ -669,116 +762,126 @@ func (b *Builder) getVercelProjectID(workspaceDir string) (string, error) {
 	return projectData.ProjectID, nil
 }
 
-// linkVercel links the workspace to a Vercel project
-func (b *Builder) linkVercel(ctx context.Context, workspaceDir, versionID string) error {
-	// Create context with timeout (2 minutes for link)
+// linkVercel makes sure the app's Vercel project exists and writes
+// .vercel/project.json (including project settings) so vercel build runs
+// without a logged-in CLI
+func (b *Builder) linkVercel(ctx context.Context, workspaceDir, appID, versionID string) error {
+	if b.VercelService == nil {
+		return fmt.Errorf("Vercel service not configured")
+	}
+
 	linkCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
 	log.Printf("[Vercel] Linking project for version %s\n", versionID)
 
-	cmd := exec.CommandContext(linkCtx, "bash", "-c", fmt.Sprintf(
-		"cd %s && vercel link -y",
-		workspaceDir,
-	))
+	// Projects are named after the app ID, matching what vercel link used to create
+	framework := detectFramework(workspaceDir)
+	project, err := b.VercelService.EnsureProject(linkCtx, appID, framework)
+	if err != nil {
+		return fmt.Errorf("Vercel link failed: %w", err)
+	}
 
-	cmd.Env = append(os.Environ(),
-		"PATH=/home/ubuntu/.nvm/versions/node/v22.16.0/bin:/usr/bin:/usr/local/bin:/sbin:/bin",
-	)
+	if framework != "" {
+		if err := b.AppService.SetDetectedFramework(ctx, appID, framework); err != nil {
+			log.Printf("[Vercel] Failed to record framework for app %s: %v\n", appID, err)
+		}
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	projectData := map[string]interface{}{
+		"projectId": project.ID,
+		"orgId":     project.AccountID,
+		"settings": map[string]interface{}{
+			"createdAt":       project.CreatedAt,
+			"framework":       project.Framework,
+			"devCommand":      project.DevCommand,
+			"installCommand":  project.InstallCommand,
+			"buildCommand":    project.BuildCommand,
+			"outputDirectory": project.OutputDirectory,
+			"rootDirectory":   project.RootDirectory,
+			"nodeVersion":     project.NodeVersion,
+		},
+	}
+	data, err := json.MarshalIndent(projectData, "", "  ")
+	if err != nil {
+		return err
+	}
 
-	if err := cmd.Run(); err != nil {
-		if linkCtx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("Vercel link timed out after 2 minutes")
-		}
-		errorMsg := stderr.String()
-		if errorMsg == "" {
-			errorMsg = err.Error()
-		}
-		return fmt.Errorf("Vercel link failed: %s", strings.TrimSpace(errorMsg))
+	vercelDir := filepath.Join(workspaceDir, ".vercel")
+	if err := os.MkdirAll(vercelDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(vercelDir, "project.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write project.json: %w", err)
 	}
 
-	log.Printf("[Vercel] Link output: %s\n", stdout.String())
+	log.Printf("[Vercel] Linked workspace to project %s (%s)\n", project.Name, project.ID)
 	return nil
 }
 
-// deployToVercel deploys the pre-built workspace to Vercel
-func (b *Builder) deployToVercel(ctx context.Context, workspaceDir, appID, versionID string) (string, string, error) {
+// detectFramework guesses the Vercel framework preset from package.json, the
+// way vercel link does for new projects
+func detectFramework(workspaceDir string) string {
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "package.json"))
+	if err != nil {
+		return ""
+	}
+
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return ""
+	}
+
+	has := func(name string) bool {
+		_, dep := pkg.Dependencies[name]
+		_, devDep := pkg.DevDependencies[name]
+		return dep || devDep
+	}
+
+	switch {
+	case has("next"):
+		return "nextjs"
+	case has("vite"):
+		return "vite"
+	case has("react-scripts"):
+		return "create-react-app"
+	}
+	return ""
+}
+
+// deployToVercel uploads the prebuilt .vercel/output and waits for the
+// deployment. A production deployment is staged for PromoteVersion.
+func (b *Builder) deployToVercel(ctx context.Context, workspaceDir, appID, versionID string, production bool) (string, string, error) {
+	if b.VercelService == nil {
+		return "", "", fmt.Errorf("Vercel service not configured")
+	}
+
 	// Create context with timeout (10 minutes for deployment)
 	deployCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 
-	// Set environment variables for PATH
-	envVars := append(os.Environ(),
-		"PATH=/home/ubuntu/.nvm/versions/node/v22.16.0/bin:/usr/bin:/usr/local/bin:/sbin:/bin",
-	)
+	projectID, err := b.getVercelProjectID(workspaceDir)
+	if err != nil {
+		return "", "", err
+	}
 
-	// Deploy to Vercel with --prebuilt flag (workspace is already built by Claude)
 	log.Printf("[Vercel] Deploying version %s\n", versionID)
-	cmd := exec.CommandContext(deployCtx, "bash", "-c", fmt.Sprintf(
-		"cd %s && vercel --yes --prebuilt --target=preview",
-		workspaceDir,
-	))
-	cmd.Env = envVars
-
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Execute deployment
-	err := cmd.Run()
-
+	outputDir := filepath.Join(workspaceDir, ".vercel", "output")
+	deployment, err := b.VercelService.Deploy(deployCtx, projectID, appID, outputDir, production)
 	if err != nil {
-		// Check if context was cancelled
 		if deployCtx.Err() == context.DeadlineExceeded {
-			return "", "", fmt.Errorf("Vercel deployment timed out after 10 minutes")
-		}
-
-		// Extract error message
-		errorMsg := stderr.String()
-		if errorMsg == "" {
-			errorMsg = err.Error()
-		}
-		return "", "", fmt.Errorf("Vercel deployment failed: %s", strings.TrimSpace(errorMsg))
-	}
-
-	// Parse deployment URL from output
-	// Vercel typically outputs the URL in the format: https://project-name-xxx.vercel.app
-	deploymentURL := ""
-	outputLines := strings.Split(stdout.String(), "\n")
-	for _, line := range outputLines {
-		if strings.Contains(line, "https://") && strings.Contains(line, "vercel.app") {
-			// Extract URL from the line
-			parts := strings.Fields(line)
-			for _, part := range parts {
-				if strings.HasPrefix(part, "https://") && strings.Contains(part, "vercel.app") {
-					deploymentURL = strings.TrimSpace(part)
-					break
-				}
-			}
-			if deploymentURL != "" {
-				break
-			}
+			return "", "", fmt.Errorf("Vercel deployment timed out after 10 minutes: %w", err)
 		}
+		return "", "", fmt.Errorf("Vercel deployment failed: %w", err)
 	}
 
-	// Fallback to generating URL if parsing failed
-	if deploymentURL == "" {
-		folderName := filepath.Base(workspaceDir)
-		deploymentURL = fmt.Sprintf("https://%s.vercel.app", folderName)
-		log.Printf("[Vercel] Could not parse URL from output, using fallback: %s\n", deploymentURL)
-	}
-
+	deploymentURL := "https://" + deployment.URL
 	log.Printf("[Vercel] Deployment successful: %s\n", deploymentURL)
 
-	// For deployment ID, use the versionID
-	deploymentID := versionID
-
-	return deploymentURL, deploymentID, nil
+	return deploymentURL, deployment.ID, nil
 }
 
 func (b *Builder) sendProgress(versionID, status, message string) {
@@ -837,49 +940,3 @@ func (b *Builder) handleError(ctx context.Context, versionID, message string, er
 
 	return fmt.Errorf(fullMsg)
 }
-
-// setupDatabase creates app database and collections using app-manager CLI
-func (b *Builder) setupDatabase(ctx context.Context, schemasDir, appID, ownerEmail string) error {
-	log.Printf("[Database] Setting up database for app %s (owner: %s) with schemas from %s\n", appID, ownerEmail, schemasDir)
-
-	// Create context with timeout (2 minutes for database setup)
-	dbCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-	defer cancel()
-
-	// Run app-manager create command with owner email
-	// This creates both the database AND all collections AND admin user in one call
-	cmd := exec.CommandContext(dbCtx, "app-manager", "create", appID, "--schemas", schemasDir, "--owner-email", ownerEmail)
-
-	// Set environment variables (include pnpm path where app-manager is installed)
-	cmd.Env = append(os.Environ(),
-		"PATH=/home/ubuntu/.local/share/pnpm:/usr/local/bin:/usr/bin:/bin",
-	)
-
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Execute command
-	err := cmd.Run()
-
-	// Log output
-	if stdout.Len() > 0 {
-		log.Printf("[Database] Output: %s\n", stdout.String())
-	}
-
-	if err != nil {
-		if dbCtx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("database setup timed out after 2 minutes")
-		}
-
-		errorMsg := stderr.String()
-		if errorMsg == "" {
-			errorMsg = err.Error()
-		}
-		return fmt.Errorf("app-manager failed: %s", strings.TrimSpace(errorMsg))
-	}
-
-	log.Printf("[Database] ✅ Database setup completed for app %s\n", appID)
-	return nil
-}
@@ -0,0 +1,350 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rapidbuildapp/rapidbuild/config"
+)
+
+// lockfiles are checked in order; the first one found keys the cache
+var lockfiles = []string{"package-lock.json", "pnpm-lock.yaml", "yarn.lock", "bun.lockb"}
+
+// BuildCache stores node_modules tarballs keyed by lockfile hash, per app with a
+// global fallback to any entry with the same lockfile. Entries live on local disk
+// under an LRU size cap and are optionally mirrored to S3 so other build hosts
+// can share them.
+type BuildCache struct {
+	Dir      string
+	MaxBytes int64
+	S3Client *s3.Client
+	Bucket   string
+	UseS3    bool
+
+	mu sync.Mutex
+}
+
+func NewBuildCache(cfg *config.Config, s3Client *s3.Client) *BuildCache {
+	return &BuildCache{
+		Dir:      cfg.BuildCacheDir,
+		MaxBytes: cfg.BuildCacheMaxMB << 20,
+		S3Client: s3Client,
+		Bucket:   cfg.S3Bucket,
+		UseS3:    cfg.BuildCacheS3 && s3Client != nil,
+	}
+}
+
+// cacheKeys returns the per-app key followed by the global fallback key
+func cacheKeys(appID, hash string) []string {
+	return []string{
+		fmt.Sprintf("apps/%s/%s.tar.gz", appID, hash),
+		fmt.Sprintf("global/%s.tar.gz", hash),
+	}
+}
+
+// lockfileHash hashes the workspace lockfile, returning "" when there is none
+func lockfileHash(workspaceDir string) (string, error) {
+	for _, name := range lockfiles {
+		data, err := os.ReadFile(filepath.Join(workspaceDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(append([]byte(name+"\n"), data...))
+		return hex.EncodeToString(sum[:]), nil
+	}
+	return "", nil
+}
+
+// Restore extracts a cached node_modules into the workspace. It reports the
+// lockfile hash that was hit, or "" on a miss.
+func (c *BuildCache) Restore(ctx context.Context, appID, workspaceDir string) (string, error) {
+	hash, err := lockfileHash(workspaceDir)
+	if err != nil || hash == "" {
+		return "", err
+	}
+
+	path, source := c.findLocal(appID, hash), "local"
+	if path == "" {
+		for _, key := range cacheKeys(appID, hash) {
+			fetched, err := c.fetchFromS3(ctx, key)
+			if err != nil {
+				log.Printf("[Cache] Warning: Failed to fetch %s: %v\n", key, err)
+				continue
+			}
+			if fetched != "" {
+				path, source = fetched, "s3"
+				break
+			}
+		}
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	if err := c.extract(path, workspaceDir); err != nil {
+		os.RemoveAll(filepath.Join(workspaceDir, "node_modules"))
+		return "", fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+
+	// Touch the entry so LRU eviction keeps it
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	log.Printf("[Cache] Restored node_modules from %s (%s)\n", path, source)
+	return hash, nil
+}
+
+// findLocal returns the app's own entry for hash, falling back to any other
+// app's or a previously fetched global entry with the same lockfile
+func (c *BuildCache) findLocal(appID, hash string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	own := filepath.Join(c.Dir, cacheKeys(appID, hash)[0])
+	if _, err := os.Stat(own); err == nil {
+		return own
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(c.Dir, "apps", "*", hash+".tar.gz"))
+	matches = append(matches, filepath.Join(c.Dir, cacheKeys(appID, hash)[1]))
+	for _, path := range matches {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Save stores the workspace node_modules under the current lockfile hash for both
+// the app and the global fallback
+func (c *BuildCache) Save(ctx context.Context, appID, workspaceDir string) error {
+	hash, err := lockfileHash(workspaceDir)
+	if err != nil || hash == "" {
+		return err
+	}
+
+	modulesDir := filepath.Join(workspaceDir, "node_modules")
+	if _, err := os.Stat(modulesDir); err != nil {
+		return nil
+	}
+
+	keys := cacheKeys(appID, hash)
+	appPath := filepath.Join(c.Dir, keys[0])
+	if err := os.MkdirAll(filepath.Dir(appPath), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so concurrent builds never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(appPath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeTarGz(tmp, workspaceDir, func(relPath string, info os.FileInfo) bool {
+		// Only node_modules, minus tool caches that are rebuilt anyway
+		return (filepath.Dir(relPath) == "." && relPath != "node_modules") || filepath.Base(relPath) == ".cache"
+	}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to archive node_modules: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	err = os.Rename(tmp.Name(), appPath)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if c.UseS3 {
+		for _, key := range keys {
+			if err := c.upload(ctx, appPath, key); err != nil {
+				log.Printf("[Cache] Warning: Failed to upload %s: %v\n", key, err)
+			}
+		}
+	}
+
+	c.evict()
+	log.Printf("[Cache] Saved node_modules as %s\n", keys[0])
+	return nil
+}
+
+// fetchFromS3 downloads key into the local cache, returning "" when S3 is
+// disabled or does not have it
+func (c *BuildCache) fetchFromS3(ctx context.Context, key string) (string, error) {
+	if !c.UseS3 {
+		return "", nil
+	}
+
+	path := filepath.Join(c.Dir, key)
+	result, err := c.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.Bucket),
+		Key:    aws.String(c.s3Key(key)),
+	})
+	if err != nil {
+		// Treat any S3 miss as a cache miss
+		return "", nil
+	}
+	defer result.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, result.Body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (c *BuildCache) extract(path, workspaceDir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extractTarGz(file, workspaceDir)
+}
+
+func (c *BuildCache) upload(ctx context.Context, path, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = c.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(c.Bucket),
+		Key:    aws.String(c.s3Key(key)),
+		Body:   file,
+	})
+	return err
+}
+
+func (c *BuildCache) s3Key(key string) string {
+	return "build-cache/" + key
+}
+
+// evict removes least recently used entries until the cache fits under MaxBytes
+func (c *BuildCache) evict() {
+	if c.MaxBytes <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var entries []entry
+	var total int64
+
+	filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		// Skip directories and entries still being written
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	for _, e := range entries {
+		if total <= c.MaxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil {
+			continue
+		}
+		log.Printf("[Cache] Evicted %s\n", e.path)
+		total -= e.size
+	}
+}
+
+// restoreDependencies restores node_modules from the build cache and returns the
+// lockfile hash restored, or "" when nothing was restored
+func (b *Builder) restoreDependencies(ctx context.Context, workspaceDir, appID, versionID string) string {
+	if b.Cache == nil {
+		return ""
+	}
+
+	// Claude may already have installed dependencies while generating code
+	if _, err := os.Stat(filepath.Join(workspaceDir, "node_modules")); err == nil {
+		return ""
+	}
+
+	b.sendProgress(versionID, "building", "Restoring dependency cache...")
+	hash, err := b.Cache.Restore(ctx, appID, workspaceDir)
+	if err != nil {
+		log.Printf("[Cache] Warning: Failed to restore dependencies for app %s: %v\n", appID, err)
+		return ""
+	}
+	if hash == "" {
+		log.Printf("[Cache] Miss for app %s\n", appID)
+	}
+	return hash
+}
+
+// saveDependencies caches node_modules after a successful build unless the lockfile
+// is unchanged since the restore
+func (b *Builder) saveDependencies(ctx context.Context, workspaceDir, appID, restoredHash string) {
+	if b.Cache == nil {
+		return
+	}
+
+	hash, err := lockfileHash(workspaceDir)
+	if err != nil || hash == "" || hash == restoredHash {
+		return
+	}
+
+	if err := b.Cache.Save(ctx, appID, workspaceDir); err != nil {
+		log.Printf("[Cache] Warning: Failed to save dependencies for app %s: %v\n", appID, err)
+	}
+}
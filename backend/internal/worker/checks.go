@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// maxCheckOutput caps how much of a gate's output is stored and fed back to Claude
+const maxCheckOutput = 16 * 1024
+
+// QualityGate is a command that must succeed after the Vercel build for a version to pass
+type QualityGate struct {
+	Name     string `json:"name"`
+	Command  string `json:"command"`
+	Required bool   `json:"required"`
+}
+
+// appBuildConfig is the optional rapidbuild.json file at the root of an app
+type appBuildConfig struct {
+	QualityGates []QualityGate `json:"qualityGates"`
+}
+
+// defaultGateScripts maps package.json scripts to gate names, in run order
+var defaultGateScripts = []struct {
+	Name    string
+	Scripts []string
+}{
+	{Name: "typecheck", Scripts: []string{"typecheck", "type-check", "tsc"}},
+	{Name: "lint", Scripts: []string{"lint"}},
+	{Name: "test", Scripts: []string{"test"}},
+}
+
+// loadQualityGates returns the gates defined in rapidbuild.json, falling back to
+// typecheck/lint/test scripts found in package.json
+func loadQualityGates(workspaceDir string) ([]QualityGate, error) {
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "rapidbuild.json"))
+	if err == nil {
+		var cfg appBuildConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse rapidbuild.json: %w", err)
+		}
+		if cfg.QualityGates != nil {
+			for _, gate := range cfg.QualityGates {
+				if gate.Name == "" || gate.Command == "" {
+					return nil, fmt.Errorf("rapidbuild.json: quality gates need a name and a command")
+				}
+			}
+			return cfg.QualityGates, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read rapidbuild.json: %w", err)
+	}
+
+	data, err = os.ReadFile(filepath.Join(workspaceDir, "package.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	var gates []QualityGate
+	for _, def := range defaultGateScripts {
+		for _, script := range def.Scripts {
+			if _, ok := pkg.Scripts[script]; ok {
+				gates = append(gates, QualityGate{
+					Name:     def.Name,
+					Command:  "npm run " + script,
+					Required: true,
+				})
+				break
+			}
+		}
+	}
+
+	return gates, nil
+}
+
+// runQualityGates runs every gate in the workspace and returns the report
+func (b *Builder) runQualityGates(ctx context.Context, workspaceDir, versionID string, attempt int) (*models.ChecksReport, error) {
+	gates, err := loadQualityGates(workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ChecksReport{
+		Passed:    true,
+		Attempt:   attempt,
+		Checks:    []models.CheckResult{},
+		CreatedAt: time.Now(),
+	}
+
+	for _, gate := range gates {
+		b.sendProgress(versionID, "building", fmt.Sprintf("Running %s check...", gate.Name))
+		result := b.runQualityGate(ctx, workspaceDir, gate)
+		log.Printf("[Checks] %s for version %s: passed=%t (%dms)\n", gate.Name, versionID, result.Passed, result.DurationMs)
+
+		if !result.Passed && gate.Required {
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report, nil
+}
+
+func (b *Builder) runQualityGate(ctx context.Context, workspaceDir string, gate QualityGate) models.CheckResult {
+	gateCtx, cancel := context.WithTimeout(ctx, b.Config.QualityGateTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(gateCtx, "bash", "-c", fmt.Sprintf(
+		"cd %s && %s",
+		workspaceDir,
+		gate.Command,
+	))
+
+	// CI=true keeps test runners like vitest/jest from starting in watch mode
+	cmd.Env = append(os.Environ(),
+		"CI=true",
+		"PATH=/home/ubuntu/.nvm/versions/node/v22.16.0/bin:/usr/bin:/usr/local/bin:/sbin:/bin",
+	)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err := cmd.Run()
+
+	result := models.CheckResult{
+		Name:       gate.Name,
+		Command:    gate.Command,
+		Required:   gate.Required,
+		Passed:     err == nil,
+		DurationMs: time.Since(start).Milliseconds(),
+		Output:     truncateOutput(output.String(), maxCheckOutput),
+	}
+
+	if gateCtx.Err() == context.DeadlineExceeded {
+		result.Output += fmt.Sprintf("\n%s timed out after %s", gate.Name, b.Config.QualityGateTimeout)
+	}
+
+	return result
+}
+
+// checksFailureMessage formats the failed required gates for the fix prompt
+func checksFailureMessage(report *models.ChecksReport) string {
+	var sb strings.Builder
+	sb.WriteString("QUALITY CHECKS FAILED\n")
+	for _, check := range report.Checks {
+		if check.Passed || !check.Required {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n### %s (`%s`)\n", check.Name, check.Command))
+		sb.WriteString(strings.TrimSpace(check.Output))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// truncateOutput keeps the tail of long command output, where errors usually are
+func truncateOutput(output string, max int) string {
+	if len(output) <= max {
+		return output
+	}
+	return "...(truncated)...\n" + output[len(output)-max:]
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadQualityGates(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []QualityGate
+		wantErr bool
+	}{
+		{
+			name: "no config",
+			want: nil,
+		},
+		{
+			name: "package.json scripts in run order",
+			files: map[string]string{
+				"package.json": `{"scripts": {"test": "vitest run", "lint": "eslint .", "typecheck": "tsc --noEmit", "dev": "vite"}}`,
+			},
+			want: []QualityGate{
+				{Name: "typecheck", Command: "npm run typecheck", Required: true},
+				{Name: "lint", Command: "npm run lint", Required: true},
+				{Name: "test", Command: "npm run test", Required: true},
+			},
+		},
+		{
+			name: "first matching script alias",
+			files: map[string]string{
+				"package.json": `{"scripts": {"tsc": "tsc", "type-check": "tsc --noEmit"}}`,
+			},
+			want: []QualityGate{
+				{Name: "typecheck", Command: "npm run type-check", Required: true},
+			},
+		},
+		{
+			name: "package.json without gate scripts",
+			files: map[string]string{
+				"package.json": `{"scripts": {"build": "vite build"}}`,
+			},
+			want: nil,
+		},
+		{
+			name: "rapidbuild.json wins over package.json",
+			files: map[string]string{
+				"rapidbuild.json": `{"qualityGates": [{"name": "e2e", "command": "npm run e2e", "required": false}]}`,
+				"package.json":    `{"scripts": {"lint": "eslint ."}}`,
+			},
+			want: []QualityGate{
+				{Name: "e2e", Command: "npm run e2e", Required: false},
+			},
+		},
+		{
+			name: "empty gate list disables gates",
+			files: map[string]string{
+				"rapidbuild.json": `{"qualityGates": []}`,
+				"package.json":    `{"scripts": {"lint": "eslint ."}}`,
+			},
+			want: []QualityGate{},
+		},
+		{
+			name: "rapidbuild.json without gates falls back to package.json",
+			files: map[string]string{
+				"rapidbuild.json": `{}`,
+				"package.json":    `{"scripts": {"lint": "eslint ."}}`,
+			},
+			want: []QualityGate{
+				{Name: "lint", Command: "npm run lint", Required: true},
+			},
+		},
+		{
+			name: "gate without a command",
+			files: map[string]string{
+				"rapidbuild.json": `{"qualityGates": [{"name": "lint"}]}`,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid rapidbuild.json",
+			files:   map[string]string{"rapidbuild.json": `{`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid package.json",
+			files:   map[string]string{"package.json": `{"scripts": [`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := loadQualityGates(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadQualityGates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadQualityGates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Cleaner removes the external resources of deleted apps and versions. Every
+// handler is idempotent so a retried job finishes whatever an earlier attempt left.
+type Cleaner struct {
+	Config        *config.Config
+	S3Client      *s3.Client
+	VercelService *services.VercelService
+	MongoClient   *mongo.Client
+}
+
+func NewCleaner(cfg *config.Config, s3Client *s3.Client, vercelService *services.VercelService, mongoClient *mongo.Client) *Cleaner {
+	return &Cleaner{
+		Config:        cfg,
+		S3Client:      s3Client,
+		VercelService: vercelService,
+		MongoClient:   mongoClient,
+	}
+}
+
+// Register adds the cleanup handlers to a job runner
+func (c *Cleaner) Register(runner *JobRunner) {
+	runner.Register(services.JobCleanupS3Prefix, c.cleanupS3Prefix)
+	runner.Register(services.JobCleanupVercelProject, c.cleanupVercelProject)
+	runner.Register(services.JobCleanupVercelDeployment, c.cleanupVercelDeployment)
+	runner.Register(services.JobCleanupAppDatabase, c.cleanupAppDatabase)
+	runner.Register(services.JobCleanupAppHistory, c.cleanupAppHistory)
+}
+
+// cleanupS3Prefix deletes every object under payload["prefix"]
+func (c *Cleaner) cleanupS3Prefix(ctx context.Context, job *models.Job) error {
+	prefix := job.Payload["prefix"]
+	// Never wipe the bucket or a whole apps/ tree because of a bad payload
+	if len(prefix) < len("apps/x/") {
+		return fmt.Errorf("refusing to delete suspicious S3 prefix %q", prefix)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(c.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.Config.S3Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	deleted := 0
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		objects := make([]types.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, types.ObjectIdentifier{Key: object.Key})
+		}
+
+		result, err := c.S3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(c.Config.S3Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects under %s: %w", prefix, err)
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("failed to delete %d objects under %s: %s", len(result.Errors), prefix, aws.ToString(result.Errors[0].Message))
+		}
+		deleted += len(objects)
+	}
+
+	log.Printf("[Cleanup] Deleted %d S3 objects under %s\n", deleted, prefix)
+	return nil
+}
+
+// cleanupVercelProject deletes payload["project"] with its deployments and domains
+func (c *Cleaner) cleanupVercelProject(ctx context.Context, job *models.Job) error {
+	project := job.Payload["project"]
+	if project == "" {
+		return fmt.Errorf("missing project in payload")
+	}
+
+	if err := c.VercelService.DeleteProject(ctx, project); err != nil {
+		return err
+	}
+
+	log.Printf("[Cleanup] Deleted Vercel project %s\n", project)
+	return nil
+}
+
+// cleanupVercelDeployment deletes payload["deployment_id"]
+func (c *Cleaner) cleanupVercelDeployment(ctx context.Context, job *models.Job) error {
+	deploymentID := job.Payload["deployment_id"]
+	if deploymentID == "" {
+		return fmt.Errorf("missing deployment_id in payload")
+	}
+
+	if err := c.VercelService.DeleteDeployment(ctx, deploymentID); err != nil {
+		return err
+	}
+
+	log.Printf("[Cleanup] Deleted Vercel deployment %s\n", deploymentID)
+	return nil
+}
+
+// cleanupAppDatabase drops the app's MongoDB database and its system_db records
+func (c *Cleaner) cleanupAppDatabase(ctx context.Context, job *models.Job) error {
+	appID := job.Payload["app_id"]
+	if appID == "" {
+		return fmt.Errorf("missing app_id in payload")
+	}
+
+	systemDB := c.MongoClient.Database("system_db")
+
+	var appDoc struct {
+		DatabaseName string `bson:"databaseName"`
+	}
+	err := systemDB.Collection("apps").FindOne(ctx, bson.M{"_id": appID}).Decode(&appDoc)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to look up app database: %w", err)
+	}
+
+	if appDoc.DatabaseName != "" && appDoc.DatabaseName != "system_db" {
+		if err := c.MongoClient.Database(appDoc.DatabaseName).Drop(ctx); err != nil {
+			return fmt.Errorf("failed to drop database %s: %w", appDoc.DatabaseName, err)
+		}
+	}
+
+	if _, err := systemDB.Collection("app_users").DeleteMany(ctx, bson.M{"appId": appID}); err != nil {
+		return fmt.Errorf("failed to delete app users: %w", err)
+	}
+
+	// Removed last so a retry can still find the database name
+	if _, err := systemDB.Collection("apps").DeleteOne(ctx, bson.M{"_id": appID}); err != nil {
+		return fmt.Errorf("failed to delete app record: %w", err)
+	}
+
+	log.Printf("[Cleanup] Removed MongoDB data for app %s\n", appID)
+	return nil
+}
+
+// cleanupAppHistory removes the app's local history repository; its S3 bundle
+// goes with the app's S3 prefix
+func (c *Cleaner) cleanupAppHistory(ctx context.Context, job *models.Job) error {
+	appID := job.Payload["app_id"]
+	if appID == "" || strings.ContainsAny(appID, `/\.`) {
+		return fmt.Errorf("invalid app_id %q in payload", appID)
+	}
+	if c.Config.HistoryDir == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(filepath.Join(c.Config.HistoryDir, appID+".git")); err != nil {
+		return fmt.Errorf("failed to remove history of app %s: %w", appID, err)
+	}
+	return nil
+}
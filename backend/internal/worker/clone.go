@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// maxRewriteFileSize skips large (likely binary or generated) files when
+// replacing the app ID in cloned code
+const maxRewriteFileSize = 1 << 20
+
+// BuildFromSnapshot builds and deploys a version from another app's code
+// snapshot without running the agent, e.g. for a cloned app. The snapshot's
+// app ID is replaced with the new one, and the app gets its own Vercel project
+// and database.
+func (b *Builder) BuildFromSnapshot(ctx context.Context, versionID, appID, sourceAppID, s3CodePath, ownerEmail string) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("Build panic: %v", r)
+			log.Printf("[Clone] PANIC for version %s: %s\n", versionID, errMsg)
+			b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+				"status":        "failed",
+				"error_message": &errMsg,
+			})
+		}
+	}()
+
+	log.Printf("[Clone] Building version %s of app %s from %s\n", versionID, appID, s3CodePath)
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"status":       "building",
+		"instructions": fmt.Sprintf("Cloned from app %s", sourceAppID),
+	}); err != nil {
+		log.Printf("[Clone] Warning: Failed to update status to building: %v\n", err)
+	}
+
+	// Give SSE clients time to subscribe, as BuildApp does
+	time.Sleep(2 * time.Second)
+
+	workspaceDir := filepath.Join(b.Config.WorkspaceDir, appID)
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return b.handleError(ctx, versionID, "Failed to create workspace", err)
+	}
+	defer b.cleanup(workspaceDir)
+
+	b.sendProgress(versionID, "building", "Copying code snapshot...")
+	if err := b.downloadFromS3(ctx, s3CodePath, workspaceDir); err != nil {
+		return b.handleError(ctx, versionID, "Failed to download code snapshot", err)
+	}
+	if err := rewriteAppID(workspaceDir, sourceAppID, appID); err != nil {
+		return b.handleError(ctx, versionID, "Failed to update app ID in code", err)
+	}
+
+	return b.buildSnapshot(ctx, workspaceDir, appID, versionID, ownerEmail)
+}
+
+// buildSnapshot builds, checks and deploys code already in the workspace as it
+// is, for versions that don't run the agent (clones and imports)
+func (b *Builder) buildSnapshot(ctx context.Context, workspaceDir, appID, versionID, ownerEmail string) error {
+	b.sendProgress(versionID, "building", "Creating Vercel project...")
+	if err := b.linkVercel(ctx, workspaceDir, appID, versionID); err != nil {
+		return b.handleError(ctx, versionID, "Failed to link Vercel project", err)
+	}
+
+	restoredHash := b.restoreDependencies(ctx, workspaceDir, appID, versionID)
+
+	// There is no agent to fix errors, so the snapshot gets a single build
+	b.sendProgress(versionID, "building", "Building with Vercel...")
+	removeSecrets, err := b.writeSecretsEnv(ctx, workspaceDir, appID, services.SecretScopePreview)
+	if err != nil {
+		return b.handleError(ctx, versionID, "Failed to load app secrets", err)
+	}
+	buildErr := b.buildForVercel(ctx, workspaceDir, versionID, "preview", 1)
+	if buildErr == nil && b.Config.QualityGatesEnabled {
+		buildErr = b.checkQualityGates(ctx, workspaceDir, versionID, 1)
+	}
+	removeSecrets()
+	if buildErr != nil {
+		return b.handleError(ctx, versionID, "Build failed", buildErr)
+	}
+
+	b.saveDependencies(ctx, workspaceDir, appID, restoredHash)
+
+	// The app starts with an empty database of its own
+	if _, err := os.Stat(filepath.Join(workspaceDir, "schemas")); err == nil {
+		b.sendProgress(versionID, "building", "Setting up database schema...")
+		if err := b.migrateDatabase(ctx, workspaceDir, appID, versionID, ownerEmail, nil); err != nil {
+			return b.handleError(ctx, versionID, "Failed to set up app database", err)
+		}
+	}
+
+	return b.publishBuild(ctx, workspaceDir, appID, versionID)
+}
+
+// rewriteAppID replaces an app ID in the workspace's source files, such as the
+// RapidBuildProvider appId the agent writes into src/App.jsx
+func rewriteAppID(workspaceDir, oldID, newID string) error {
+	skipDirs := map[string]bool{
+		"node_modules":   true,
+		".vercel":        true,
+		".agent-history": true,
+		"dist":           true,
+		".git":           true,
+		".next":          true,
+	}
+
+	return filepath.WalkDir(workspaceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > maxRewriteFileSize {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(data, []byte(oldID)) {
+			return nil
+		}
+
+		return os.WriteFile(path, bytes.ReplaceAll(data, []byte(oldID), []byte(newID)), info.Mode().Perm())
+	})
+}
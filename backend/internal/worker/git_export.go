@@ -0,0 +1,284 @@
+package worker
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// Commit author of exported versions
+const (
+	gitExportAuthorName  = "RapidBuild"
+	gitExportAuthorEmail = "noreply@rapidbuild.app"
+)
+
+// defaultGitExportUsername is sent with a token when no username is set; most
+// hosts accept any username with an access token
+const defaultGitExportUsername = "x-access-token"
+
+// GitExporter commits version snapshots to apps' Git remotes and pushes them
+type GitExporter struct {
+	Config   *config.Config
+	S3Client *s3.Client
+	Exports  *services.GitExportService
+	Versions *services.VersionService
+	Comments *services.CommentService
+}
+
+func NewGitExporter(cfg *config.Config, s3Client *s3.Client, exportService *services.GitExportService, versionService *services.VersionService, commentService *services.CommentService) *GitExporter {
+	return &GitExporter{
+		Config:   cfg,
+		S3Client: s3Client,
+		Exports:  exportService,
+		Versions: versionService,
+		Comments: commentService,
+	}
+}
+
+// Register adds the push handler to a job runner
+func (g *GitExporter) Register(runner *JobRunner) {
+	runner.Register(services.JobGitExportPush, g.pushVersion)
+}
+
+// pushVersion commits a version's code snapshot onto the export branch and
+// pushes it. Settings that can't work are recorded and not retried; push
+// failures are retried by the job runner.
+func (g *GitExporter) pushVersion(ctx context.Context, job *models.Job) error {
+	appID, versionID, event := job.Payload["app_id"], job.Payload["version_id"], job.Payload["event"]
+
+	export, err := g.Exports.GetGitExportWithToken(ctx, appID)
+	if errors.Is(err, services.ErrGitExportNotFound) {
+		log.Printf("[GitExport] App %s no longer exports to git, skipping version %s\n", appID, versionID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := g.Versions.GetVersion(ctx, versionID)
+	if err != nil {
+		return err
+	}
+	if version.AppID != appID || version.S3CodePath == nil || *version.S3CodePath == "" ||
+		(version.Status != "completed" && version.Status != "promoted") {
+		log.Printf("[GitExport] Version %s is %s without a deployable snapshot, skipping\n", versionID, version.Status)
+		return nil
+	}
+	if supersededPush(export, version, event) {
+		log.Printf("[GitExport] Version %d of app %s is older than the pushed version %d, skipping\n", version.VersionNumber, appID, *export.LastPushedVersion)
+		return nil
+	}
+
+	remote, err := g.Exports.ResolveRemote(export.RemoteURL)
+	if err != nil {
+		g.recordPush(ctx, appID, version.VersionNumber, "", err)
+		return nil
+	}
+
+	comments, err := g.Comments.GetVersionComments(ctx, versionID)
+	if err != nil {
+		return err
+	}
+
+	commit, err := g.push(ctx, export, remote, version, gitExportMessage(version, comments, event))
+	g.recordPush(ctx, appID, version.VersionNumber, commit, err)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[GitExport] Pushed version %d of app %s to %s as %s\n", version.VersionNumber, appID, export.Branch, commit)
+	return nil
+}
+
+// supersededPush reports whether pushing version would move the export branch
+// backwards, e.g. a retried push of a version after a newer one went out. A
+// promotion of the pushed version still gets its commit.
+func supersededPush(export *models.GitExport, version *models.Version, event string) bool {
+	if export.LastPushedVersion == nil {
+		return false
+	}
+	last := *export.LastPushedVersion
+	return version.VersionNumber < last || (version.VersionNumber == last && event != services.GitExportEventPromoted)
+}
+
+func (g *GitExporter) recordPush(ctx context.Context, appID string, versionNumber int, commit string, pushErr error) {
+	if err := g.Exports.RecordPush(ctx, appID, versionNumber, commit, pushErr); err != nil {
+		log.Printf("[GitExport] Warning: %v\n", err)
+	}
+}
+
+// push checks out the export branch (or starts it), replaces its tree with the
+// version's snapshot, commits and pushes. It returns the new commit's SHA.
+func (g *GitExporter) push(ctx context.Context, export *models.GitExport, remote string, version *models.Version, message string) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "rapidbuild-git-export-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	repoDir := filepath.Join(tmpDir, "repo")
+	env := gitExportEnv(export)
+	git := func(dir string, args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	heads, err := git(tmpDir, "ls-remote", "--heads", remote, "refs/heads/"+export.Branch)
+	if err != nil {
+		return "", err
+	}
+	if heads != "" {
+		if _, err := git(tmpDir, "clone", "--quiet", "--depth", "1", "--single-branch", "--branch", export.Branch, remote, repoDir); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := git(tmpDir, "init", "--quiet", repoDir); err != nil {
+			return "", err
+		}
+		if _, err := git(repoDir, "symbolic-ref", "HEAD", "refs/heads/"+export.Branch); err != nil {
+			return "", err
+		}
+	}
+
+	// The snapshot replaces the whole tree, so deleted files are deleted in git too
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.Name() != ".git" {
+			if err := os.RemoveAll(filepath.Join(repoDir, entry.Name())); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := g.downloadSnapshot(ctx, *version.S3CodePath, repoDir); err != nil {
+		return "", fmt.Errorf("failed to download code snapshot: %w", err)
+	}
+
+	messageFile := filepath.Join(tmpDir, "COMMIT_MSG")
+	if err := os.WriteFile(messageFile, []byte(message), 0600); err != nil {
+		return "", err
+	}
+
+	// A promotion of an already pushed version has nothing new to commit, but
+	// still gets a commit recording it
+	steps := [][]string{
+		{"add", "--all"},
+		{"-c", "user.name=" + gitExportAuthorName, "-c", "user.email=" + gitExportAuthorEmail,
+			"commit", "--quiet", "--allow-empty", "--no-verify", "--file", messageFile},
+		{"push", "--quiet", remote, "HEAD:refs/heads/" + export.Branch},
+	}
+	for _, args := range steps {
+		if _, err := git(repoDir, args...); err != nil {
+			return "", err
+		}
+	}
+
+	return git(repoDir, "rev-parse", "HEAD")
+}
+
+func (g *GitExporter) downloadSnapshot(ctx context.Context, s3Path, destDir string) error {
+	result, err := g.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(g.Config.S3Bucket),
+		Key:    aws.String(s3Path),
+	})
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+
+	return extractTarGz(result.Body, destDir)
+}
+
+// gitExportEnv runs git non-interactively, limited to the transports remotes
+// may use. The token goes in an HTTP header set through the environment, so it
+// is neither in the remote URL nor in any command line.
+func gitExportEnv(export *models.GitExport) []string {
+	env := append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ALLOW_PROTOCOL=file:http:https",
+	)
+	if export.Token == "" {
+		return env
+	}
+
+	username := defaultGitExportUsername
+	if export.Username != nil {
+		username = *export.Username
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + export.Token))
+	return append(env,
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials,
+	)
+}
+
+// gitExportMessage describes a version as a commit message: what it was asked
+// to do, the comments it addressed and trailers identifying it
+func gitExportMessage(version *models.Version, comments []models.Comment, event string) string {
+	var sb strings.Builder
+
+	instructions := ""
+	if version.Instructions != nil {
+		instructions = strings.TrimSpace(*version.Instructions)
+	}
+
+	switch {
+	case event == services.GitExportEventPromoted:
+		fmt.Fprintf(&sb, "Promote version %d to production\n", version.VersionNumber)
+	case instructions != "":
+		fmt.Fprintf(&sb, "Version %d: %s\n", version.VersionNumber, commitSubject(instructions))
+	case len(comments) > 0:
+		fmt.Fprintf(&sb, "Version %d: %s\n", version.VersionNumber, commitSubject(comments[0].Content))
+	default:
+		fmt.Fprintf(&sb, "Version %d\n", version.VersionNumber)
+	}
+
+	if event != services.GitExportEventPromoted {
+		if instructions != "" {
+			sb.WriteString("\n" + instructions + "\n")
+		}
+		if len(comments) > 0 {
+			sb.WriteString("\nComments:\n")
+			for _, comment := range comments {
+				location := comment.PagePath
+				if comment.ElementPath != "" {
+					location += " " + comment.ElementPath
+				}
+				fmt.Fprintf(&sb, "- [%s] %s\n", strings.TrimSpace(location), strings.TrimSpace(comment.Content))
+			}
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nRapidBuild-Version: %d\nRapidBuild-Version-ID: %s\n", version.VersionNumber, version.ID)
+	return sb.String()
+}
+
+// commitSubject shortens the first line of s to fit a commit subject
+func commitSubject(s string) string {
+	subject := strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+	if runes := []rune(subject); len(runes) > 60 {
+		subject = strings.TrimSpace(string(runes[:57])) + "..."
+	}
+	return subject
+}
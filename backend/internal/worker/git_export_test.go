@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+func TestSupersededPush(t *testing.T) {
+	pushed := 5
+	tests := []struct {
+		name    string
+		last    *int
+		version int
+		event   string
+		want    bool
+	}{
+		{"nothing pushed yet", nil, 1, services.GitExportEventCompleted, false},
+		{"newer version", &pushed, 6, services.GitExportEventCompleted, false},
+		{"retry of an older version", &pushed, 4, services.GitExportEventCompleted, true},
+		{"promotion of an older version", &pushed, 4, services.GitExportEventPromoted, true},
+		{"same version pushed again", &pushed, 5, services.GitExportEventCompleted, true},
+		{"promotion of the pushed version", &pushed, 5, services.GitExportEventPromoted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := &models.GitExport{LastPushedVersion: tt.last}
+			version := &models.Version{VersionNumber: tt.version}
+			if got := supersededPush(export, version, tt.event); got != tt.want {
+				t.Errorf("supersededPush() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGitExportPushToBareRepo pushes two snapshots served by a fake S3 to a
+// local bare repository and checks the branch ends up with the second one
+func TestGitExportPushToBareRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	snapshots := map[string][]byte{
+		"/bucket/v1.tar.gz": snapshotArchive(t, map[string]string{"index.html": "v1", "old.txt": "removed in v2"}),
+		"/bucket/v2.tar.gz": snapshotArchive(t, map[string]string{"index.html": "v2"}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := snapshots[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, "", "init", "--quiet", "--bare", remote)
+
+	exporter := &GitExporter{
+		Config: &config.Config{S3Bucket: "bucket"},
+		S3Client: s3.New(s3.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			UsePathStyle: true,
+			Credentials:  aws.AnonymousCredentials{},
+		}),
+	}
+	export := &models.GitExport{Branch: "main"}
+
+	for i, key := range []string{"v1.tar.gz", "v2.tar.gz"} {
+		version := &models.Version{ID: key, VersionNumber: i + 1, S3CodePath: aws.String(key)}
+		commit, err := exporter.push(context.Background(), export, remote, version, gitExportMessage(version, nil, services.GitExportEventCompleted))
+		if err != nil {
+			t.Fatalf("push version %d: %v", version.VersionNumber, err)
+		}
+		if head := runGit(t, remote, "rev-parse", "refs/heads/main"); head != commit {
+			t.Fatalf("branch is at %s, want %s", head, commit)
+		}
+	}
+
+	if count := runGit(t, remote, "rev-list", "--count", "main"); count != "2" {
+		t.Errorf("branch has %s commits, want 2", count)
+	}
+	if files := runGit(t, remote, "ls-tree", "--name-only", "main"); files != "index.html" {
+		t.Errorf("branch tree is %q, want only index.html", files)
+	}
+	if content := runGit(t, remote, "show", "main:index.html"); content != "v2" {
+		t.Errorf("index.html is %q, want v2", content)
+	}
+	if message := runGit(t, remote, "log", "-1", "--format=%B", "main"); !strings.Contains(message, "RapidBuild-Version: 2") {
+		t.Errorf("last commit message %q has no version trailer", message)
+	}
+}
+
+func snapshotArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeTarGz(&buf, dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func runGit(t *testing.T, gitDir string, args ...string) string {
+	t.Helper()
+	if gitDir != "" {
+		args = append([]string{"--git-dir", gitDir}, args...)
+	}
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,712 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+var (
+	ErrHistoryNotFound   = errors.New("app has no version history yet")
+	ErrInvalidHistoryRef = errors.New("invalid history reference")
+)
+
+// historyBranch is the branch of an app's history repository
+const historyBranch = "refs/heads/main"
+
+// emptyTreeSHA is the tree of a commit without files, compared against for
+// the first commit
+const emptyTreeSHA = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// History list and diff limits
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+	maxHistoryPatchSize = 1 << 20
+)
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// History keeps a bare Git repository per app in which every completed version
+// is a commit of its code snapshot, with its instructions as the message.
+// Repositories live under Config.HistoryDir and are optionally mirrored to S3
+// as bundles so other hosts can pick them up.
+type History struct {
+	Dir      string
+	S3Client *s3.Client
+	Bucket   string
+	UseS3    bool
+	Versions *services.VersionService
+	Comments *services.CommentService
+
+	locks sync.Map // app ID -> *sync.Mutex, serializing commits
+}
+
+func NewHistory(cfg *config.Config, s3Client *s3.Client, versionService *services.VersionService, commentService *services.CommentService) *History {
+	return &History{
+		Dir:      cfg.HistoryDir,
+		S3Client: s3Client,
+		Bucket:   cfg.S3Bucket,
+		UseS3:    cfg.HistoryS3 && s3Client != nil,
+		Versions: versionService,
+		Comments: commentService,
+	}
+}
+
+func (h *History) repoDir(appID string) string {
+	return filepath.Join(h.Dir, appID+".git")
+}
+
+func historyBundleKey(appID string) string {
+	return fmt.Sprintf("apps/%s/history.bundle", appID)
+}
+
+func (h *History) lock(appID string) func() {
+	mu, _ := h.locks.LoadOrStore(appID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// git runs a git command against a repository. extraEnv is added to the
+// environment, e.g. to set the index or commit dates.
+func (h *History) git(ctx context.Context, repo string, stdin io.Reader, extraEnv []string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir=" + repo}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, extraEnv...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// Record commits a version's code snapshot (a tarball as made by packageCode)
+// to the app's history and stores the commit on the version. When the history
+// is new, earlier versions are committed first from their S3 snapshots.
+func (h *History) Record(ctx context.Context, version *models.Version, snapshotPath string) error {
+	unlock := h.lock(version.AppID)
+	defer unlock()
+
+	// Another host may have committed since this one last did
+	repo, found, err := h.find(ctx, version.AppID, true)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if err := h.create(ctx, repo); err != nil {
+			return err
+		}
+		h.backfill(ctx, repo, version)
+	}
+
+	file, err := os.Open(snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := h.commitSnapshot(ctx, repo, version, file); err != nil {
+		return err
+	}
+
+	h.saveBundle(ctx, version.AppID, repo)
+	return nil
+}
+
+// find locates an app's repository, restoring it from its S3 bundle if it isn't
+// on local disk. With latest set, a local repository is also brought up to date
+// with the bundle. found is false when the app has no history anywhere.
+// Callers hold the app's lock.
+func (h *History) find(ctx context.Context, appID string, latest bool) (repo string, found bool, err error) {
+	repo = h.repoDir(appID)
+	_, statErr := os.Stat(filepath.Join(repo, "HEAD"))
+	local := statErr == nil
+	if !h.UseS3 || (local && !latest) {
+		return repo, local, nil
+	}
+
+	bundle, ok, err := h.downloadBundle(ctx, appID)
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		return repo, local, nil
+	}
+	defer os.Remove(bundle)
+
+	if local {
+		if err := h.pullBundle(ctx, appID, repo, bundle); err != nil {
+			return "", false, err
+		}
+		return repo, true, nil
+	}
+
+	if err := h.create(ctx, repo); err != nil {
+		return "", false, err
+	}
+	if _, err := h.git(ctx, repo, nil, nil, "fetch", "--quiet", bundle, historyBranch+":"+historyBranch); err != nil {
+		os.RemoveAll(repo)
+		return "", false, err
+	}
+	log.Printf("[History] Restored history of app %s from S3\n", appID)
+	return repo, true, nil
+}
+
+// downloadBundle fetches an app's history bundle from S3 into a temporary file.
+// ok is false when there is no bundle.
+func (h *History) downloadBundle(ctx context.Context, appID string) (path string, ok bool, err error) {
+	result, err := h.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(h.Bucket),
+		Key:    aws.String(historyBundleKey(appID)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to download history bundle: %w", err)
+	}
+	defer result.Body.Close()
+
+	bundle, err := os.CreateTemp("", "rapidbuild-history-*.bundle")
+	if err != nil {
+		return "", false, err
+	}
+	_, err = io.Copy(bundle, result.Body)
+	bundle.Close()
+	if err != nil {
+		os.Remove(bundle.Name())
+		return "", false, err
+	}
+	return bundle.Name(), true, nil
+}
+
+// pullBundle moves the local branch to the bundle's when the bundle is ahead,
+// so new commits don't build on stale history and saveBundle doesn't overwrite
+// newer commits. A local branch that is ahead keeps its commits, since only its
+// upload failed; one that diverged is replaced, as other hosts built on the bundle.
+func (h *History) pullBundle(ctx context.Context, appID, repo, bundle string) error {
+	const bundleRef = "refs/bundle/main"
+	if _, err := h.git(ctx, repo, nil, nil, "fetch", "--quiet", bundle, "+"+historyBranch+":"+bundleRef); err != nil {
+		return err
+	}
+
+	if _, err := h.git(ctx, repo, nil, nil, "rev-parse", "--verify", "--quiet", historyBranch); err == nil {
+		ahead, err := h.isAncestor(ctx, repo, bundleRef, historyBranch)
+		if err != nil || ahead {
+			return err
+		}
+		behind, err := h.isAncestor(ctx, repo, historyBranch, bundleRef)
+		if err != nil {
+			return err
+		}
+		if !behind {
+			log.Printf("[History] Warning: History of app %s diverged from its S3 bundle, using the bundle\n", appID)
+		}
+	}
+
+	_, err := h.git(ctx, repo, nil, nil, "update-ref", historyBranch, bundleRef)
+	return err
+}
+
+// isAncestor reports whether commit ancestor is reachable from descendant
+func (h *History) isAncestor(ctx context.Context, repo, ancestor, descendant string) (bool, error) {
+	_, err := h.git(ctx, repo, nil, nil, "merge-base", "--is-ancestor", ancestor, descendant)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *History) create(ctx context.Context, repo string) error {
+	if err := os.MkdirAll(h.Dir, 0755); err != nil {
+		return err
+	}
+	os.RemoveAll(repo)
+	if output, err := exec.CommandContext(ctx, "git", "init", "--quiet", "--bare", repo).CombinedOutput(); err != nil {
+		return fmt.Errorf("git init failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	_, err := h.git(ctx, repo, nil, nil, "symbolic-ref", "HEAD", historyBranch)
+	return err
+}
+
+// backfill commits the completed versions older than current that predate
+// the history, oldest first. Versions whose snapshot can't be read are skipped.
+func (h *History) backfill(ctx context.Context, repo string, current *models.Version) {
+	if h.S3Client == nil {
+		return
+	}
+
+	versions, err := h.Versions.ListVersions(ctx, current.AppID)
+	if err != nil {
+		log.Printf("[History] Warning: Failed to list versions to backfill app %s: %v\n", current.AppID, err)
+		return
+	}
+
+	// Versions are listed newest first
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := &versions[i]
+		if v.VersionNumber >= current.VersionNumber || v.S3CodePath == nil || *v.S3CodePath == "" ||
+			(v.Status != "completed" && v.Status != "promoted") {
+			continue
+		}
+
+		result, err := h.S3Client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(h.Bucket),
+			Key:    aws.String(*v.S3CodePath),
+		})
+		if err != nil {
+			log.Printf("[History] Warning: Skipping version %d of app %s: %v\n", v.VersionNumber, v.AppID, err)
+			continue
+		}
+		_, err = h.commitSnapshot(ctx, repo, v, result.Body)
+		result.Body.Close()
+		if err != nil {
+			log.Printf("[History] Warning: Skipping version %d of app %s: %v\n", v.VersionNumber, v.AppID, err)
+		}
+	}
+}
+
+// commitSnapshot commits a gzipped tarball of a version's code on top of the
+// branch and stores the commit on the version
+func (h *History) commitSnapshot(ctx context.Context, repo string, version *models.Version, snapshot io.Reader) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "rapidbuild-history-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	workTree := filepath.Join(tmpDir, "tree")
+	if err := os.MkdirAll(workTree, 0755); err != nil {
+		return "", err
+	}
+	if err := extractTarGz(snapshot, workTree); err != nil {
+		return "", fmt.Errorf("failed to extract snapshot: %w", err)
+	}
+
+	// A fresh index per commit makes the tree exactly the snapshot; --force
+	// keeps files the app's own .gitignore would leave out
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmpDir, "index"), "GIT_WORK_TREE=" + workTree}
+	if _, err := h.git(ctx, repo, nil, env, "add", "--all", "--force", "."); err != nil {
+		return "", err
+	}
+	tree, err := h.git(ctx, repo, nil, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	comments, err := h.Comments.GetVersionComments(ctx, version.ID)
+	if err != nil {
+		return "", err
+	}
+	message := gitExportMessage(version, comments, services.GitExportEventCompleted)
+
+	args := []string{"commit-tree", strings.TrimSpace(string(tree))}
+	parent, _ := h.git(ctx, repo, nil, nil, "rev-parse", "--verify", "--quiet", historyBranch)
+	if p := strings.TrimSpace(string(parent)); p != "" {
+		args = append(args, "-p", p)
+	}
+
+	date := version.CreatedAt.Format(time.RFC3339)
+	commitEnv := []string{
+		"GIT_AUTHOR_NAME=" + gitExportAuthorName, "GIT_AUTHOR_EMAIL=" + gitExportAuthorEmail, "GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_NAME=" + gitExportAuthorName, "GIT_COMMITTER_EMAIL=" + gitExportAuthorEmail, "GIT_COMMITTER_DATE=" + date,
+	}
+	output, err := h.git(ctx, repo, strings.NewReader(message), commitEnv, args...)
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(output))
+
+	if _, err := h.git(ctx, repo, nil, nil, "update-ref", historyBranch, commit); err != nil {
+		return "", err
+	}
+
+	if _, err := h.Versions.UpdateVersion(ctx, version.ID, map[string]interface{}{
+		"commit_sha": commit,
+	}); err != nil {
+		log.Printf("[History] Warning: Failed to store commit of version %s: %v\n", version.ID, err)
+	}
+	version.CommitSHA = &commit
+
+	log.Printf("[History] Committed version %d of app %s as %s\n", version.VersionNumber, version.AppID, commit)
+	return commit, nil
+}
+
+// saveBundle mirrors the repository to S3. Failures only cost other hosts the
+// latest commits, so they are logged.
+func (h *History) saveBundle(ctx context.Context, appID, repo string) {
+	if !h.UseS3 {
+		return
+	}
+
+	bundlePath := repo + ".bundle"
+	defer os.Remove(bundlePath)
+	if _, err := h.git(ctx, repo, nil, nil, "bundle", "create", "--quiet", bundlePath, historyBranch); err != nil {
+		log.Printf("[History] Warning: Failed to bundle history of app %s: %v\n", appID, err)
+		return
+	}
+
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		log.Printf("[History] Warning: Failed to open history bundle of app %s: %v\n", appID, err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := h.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(h.Bucket),
+		Key:    aws.String(historyBundleKey(appID)),
+		Body:   file,
+	}); err != nil {
+		log.Printf("[History] Warning: Failed to upload history bundle of app %s: %v\n", appID, err)
+	}
+}
+
+// open returns the repository of an app that has history. Restoring it takes
+// the app's lock so it can't race a commit.
+func (h *History) open(ctx context.Context, appID string) (string, error) {
+	unlock := h.lock(appID)
+	repo, found, err := h.find(ctx, appID, false)
+	unlock()
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", ErrHistoryNotFound
+	}
+	return repo, nil
+}
+
+// Log lists the commits of an app's history, newest first, optionally only
+// those touching filePath
+func (h *History) Log(ctx context.Context, appID, filePath string, limit int) ([]models.HistoryCommit, error) {
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit < 0 || limit > maxHistoryLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidHistoryRef, maxHistoryLimit)
+	}
+	pathspec, err := historyPathspec(filePath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := h.open(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields are separated by \x1f and commits by \x1e
+	args := append([]string{"log", "--format=%H%x1f%P%x1f%an%x1f%aI%x1f%B%x1e", "-n", strconv.Itoa(limit), historyBranch}, pathspec...)
+	output, err := h.git(ctx, repo, nil, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []models.HistoryCommit{}
+	for _, record := range strings.Split(string(output), "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 5)
+		if len(fields) != 5 {
+			continue
+		}
+
+		message := strings.TrimSpace(fields[4])
+		commit := models.HistoryCommit{
+			Commit:  fields[0],
+			Parents: strings.Fields(fields[1]),
+			Subject: strings.SplitN(message, "\n", 2)[0],
+			Message: message,
+			Author:  fields[2],
+		}
+		commit.Date, _ = time.Parse(time.RFC3339, fields[3])
+		commit.VersionID, commit.VersionNumber = versionTrailers(message)
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// Diff compares two commits, from defaulting to the parent of to and to to the
+// latest commit, optionally limited to filePath
+func (h *History) Diff(ctx context.Context, appID, from, to, filePath string) (*models.HistoryDiff, error) {
+	pathspec, err := historyPathspec(filePath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := h.open(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	toCommit, err := h.resolve(ctx, repo, to)
+	if err != nil {
+		return nil, err
+	}
+	var fromCommit string
+	if from != "" {
+		if fromCommit, err = h.resolve(ctx, repo, from); err != nil {
+			return nil, err
+		}
+	} else if parent, err := h.git(ctx, repo, nil, nil, "rev-parse", "--verify", "--quiet", toCommit+"^"); err == nil {
+		fromCommit = strings.TrimSpace(string(parent))
+	} else {
+		fromCommit = emptyTreeSHA
+	}
+
+	diff := &models.HistoryDiff{From: fromCommit, To: toCommit, Files: []models.HistoryFileChange{}}
+
+	numstat, err := h.git(ctx, repo, nil, nil, append([]string{"diff", "--no-renames", "--numstat", "-z", fromCommit, toCommit}, pathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(numstat), "\x00") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		change := models.HistoryFileChange{Path: fields[2], Binary: fields[0] == "-"}
+		change.Additions, _ = strconv.Atoi(fields[0])
+		change.Deletions, _ = strconv.Atoi(fields[1])
+		diff.Files = append(diff.Files, change)
+	}
+
+	patch, err := h.git(ctx, repo, nil, nil, append([]string{"diff", "--no-renames", "--no-color", fromCommit, toCommit}, pathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(patch) > maxHistoryPatchSize {
+		patch, diff.Truncated = patch[:maxHistoryPatchSize], true
+	}
+	diff.Patch = string(patch)
+	return diff, nil
+}
+
+// Blame attributes each line of a file, as of commit (the latest by default),
+// to the commit and version that last changed it
+func (h *History) Blame(ctx context.Context, appID, commit, filePath string) ([]models.BlameLine, error) {
+	pathspec, err := historyPathspec(filePath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := h.open(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := h.resolve(ctx, repo, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := h.git(ctx, repo, nil, nil, append([]string{"blame", "--porcelain", resolved}, pathspec...)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s not found in %s", ErrInvalidHistoryRef, filePath, resolved)
+	}
+
+	// Map commits to version numbers
+	versionNumbers := map[string]int{}
+	if versions, err := h.Versions.ListVersions(ctx, appID); err == nil {
+		for _, v := range versions {
+			if v.CommitSHA != nil {
+				versionNumbers[*v.CommitSHA] = v.VersionNumber
+			}
+		}
+	}
+
+	// Porcelain output is a header per line ("<sha> <orig line> <final line> ..."),
+	// commit details the first time a commit appears, then the line after a tab
+	lines := []models.BlameLine{}
+	summaries := map[string]string{}
+	var current models.BlameLine
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			current.Content = text[1:]
+			current.Summary = summaries[current.Commit]
+			if n, ok := versionNumbers[current.Commit]; ok {
+				current.VersionNumber = &n
+			}
+			lines = append(lines, current)
+		case strings.HasPrefix(text, "summary "):
+			summaries[current.Commit] = strings.TrimPrefix(text, "summary ")
+		default:
+			fields := strings.Fields(text)
+			if len(fields) >= 3 && len(fields[0]) == 40 && commitSHAPattern.MatchString(fields[0]) {
+				line, _ := strconv.Atoi(fields[2])
+				current = models.BlameLine{Commit: fields[0], Line: line}
+			}
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// ResolveCommit expands a (possibly abbreviated) commit of an app's history
+func (h *History) ResolveCommit(ctx context.Context, appID, commit string) (string, error) {
+	repo, err := h.open(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+	return h.resolve(ctx, repo, commit)
+}
+
+// resolve expands a commit SHA, or the latest commit for ""
+func (h *History) resolve(ctx context.Context, repo, commit string) (string, error) {
+	ref := historyBranch
+	if commit != "" {
+		if !commitSHAPattern.MatchString(commit) {
+			return "", fmt.Errorf("%w: %q is not a commit SHA", ErrInvalidHistoryRef, commit)
+		}
+		ref = commit
+	}
+
+	output, err := h.git(ctx, repo, nil, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		if commit == "" {
+			return "", ErrHistoryNotFound
+		}
+		return "", fmt.Errorf("%w: commit %s not found", ErrInvalidHistoryRef, commit)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Checkout writes the files of a commit into destDir
+func (h *History) Checkout(ctx context.Context, appID, commit, destDir string) error {
+	repo, err := h.open(ctx, appID)
+	if err != nil {
+		return err
+	}
+	resolved, err := h.resolve(ctx, repo, commit)
+	if err != nil {
+		return err
+	}
+
+	archive, err := h.git(ctx, repo, nil, nil, "archive", "--format=tar.gz", resolved)
+	if err != nil {
+		return err
+	}
+	return extractTarGz(bytes.NewReader(archive), destDir)
+}
+
+// historyPathspec validates a file path given to a history query and returns
+// the git arguments limiting the query to it
+func historyPathspec(filePath string, required bool) ([]string, error) {
+	if filePath == "" {
+		if required {
+			return nil, fmt.Errorf("%w: path is required", ErrInvalidHistoryRef)
+		}
+		return nil, nil
+	}
+
+	cleaned := path.Clean(strings.TrimPrefix(filePath, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return nil, fmt.Errorf("%w: invalid path %q", ErrInvalidHistoryRef, filePath)
+	}
+	// :(literal) keeps wildcards and magic in the path from being interpreted
+	return []string{"--", ":(literal)" + cleaned}, nil
+}
+
+// versionTrailers reads the version trailers written by gitExportMessage
+func versionTrailers(message string) (*string, *int) {
+	var versionID *string
+	var versionNumber *int
+	for _, line := range strings.Split(message, "\n") {
+		if value, ok := strings.CutPrefix(line, "RapidBuild-Version-ID: "); ok {
+			id := strings.TrimSpace(value)
+			versionID = &id
+		} else if value, ok := strings.CutPrefix(line, "RapidBuild-Version: "); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				versionNumber = &n
+			}
+		}
+	}
+	return versionID, versionNumber
+}
+
+// recordHistory commits a published version to the app's history if it
+// ended up completed. Failures are logged: history must not fail a build.
+func (b *Builder) recordHistory(ctx context.Context, versionID, snapshotPath string) {
+	if b.History == nil {
+		return
+	}
+
+	version, err := b.VersionService.GetVersion(ctx, versionID)
+	if err != nil {
+		log.Printf("[History] Warning: Failed to load version %s: %v\n", versionID, err)
+		return
+	}
+	if version.Status != "completed" {
+		return
+	}
+
+	if err := b.History.Record(ctx, version, snapshotPath); err != nil {
+		log.Printf("[History] Warning: Failed to record version %s: %v\n", versionID, err)
+	}
+}
+
+// RestoreCommit builds and deploys a new version from a commit of the app's
+// history without running the agent
+func (b *Builder) RestoreCommit(ctx context.Context, versionID, appID, commit, ownerEmail string) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("Build panic: %v", r)
+			log.Printf("[Restore] PANIC for version %s: %s\n", versionID, errMsg)
+			b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+				"status":        "failed",
+				"error_message": &errMsg,
+			})
+		}
+	}()
+
+	log.Printf("[Restore] Building version %s of app %s from commit %s\n", versionID, appID, commit)
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"status":       "building",
+		"instructions": fmt.Sprintf("Restored from commit %s", commit),
+	}); err != nil {
+		log.Printf("[Restore] Warning: Failed to update status to building: %v\n", err)
+	}
+
+	// Give SSE clients time to subscribe, as BuildApp does
+	time.Sleep(2 * time.Second)
+
+	workspaceDir := filepath.Join(b.Config.WorkspaceDir, appID)
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return b.handleError(ctx, versionID, "Failed to create workspace", err)
+	}
+	defer b.cleanup(workspaceDir)
+
+	b.sendProgress(versionID, "building", "Checking out commit...")
+	if err := b.History.Checkout(ctx, appID, commit, workspaceDir); err != nil {
+		return b.handleError(ctx, versionID, "Failed to check out commit", err)
+	}
+
+	return b.buildSnapshot(ctx, workspaceDir, appID, versionID, ownerEmail)
+}
@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+func TestHistoryPathspec(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		required bool
+		want     []string
+		wantErr  bool
+	}{
+		{name: "no path", path: "", want: nil},
+		{name: "required path missing", path: "", required: true, wantErr: true},
+		{name: "file", path: "src/App.tsx", want: []string{"--", ":(literal)src/App.tsx"}},
+		{name: "leading slash", path: "/src/App.tsx", want: []string{"--", ":(literal)src/App.tsx"}},
+		{name: "cleaned", path: "src/./components//Nav.tsx", want: []string{"--", ":(literal)src/components/Nav.tsx"}},
+		{name: "wildcards stay literal", path: "src/*.tsx", want: []string{"--", ":(literal)src/*.tsx"}},
+		{name: "magic stays literal", path: ":(glob)**", want: []string{"--", ":(literal):(glob)**"}},
+		{name: "dot", path: ".", wantErr: true},
+		{name: "parent", path: "..", wantErr: true},
+		{name: "escapes the repo", path: "src/../../etc/passwd", wantErr: true},
+		{name: "root only", path: "/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := historyPathspec(tt.path, tt.required)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidHistoryRef) {
+					t.Fatalf("historyPathspec(%q) error = %v, want ErrInvalidHistoryRef", tt.path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("historyPathspec(%q) error = %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historyPathspec(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionTrailers(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		wantID     string
+		wantNumber int
+	}{
+		{
+			name:       "both trailers",
+			message:    "Version 3: Add login\n\nRapidBuild-Version: 3\nRapidBuild-Version-ID: 5f0c\n",
+			wantID:     "5f0c",
+			wantNumber: 3,
+		},
+		{
+			name:    "no trailers",
+			message: "Initial commit\n",
+		},
+		{
+			name:    "unparsable number",
+			message: "RapidBuild-Version: three\nRapidBuild-Version-ID: 5f0c",
+			wantID:  "5f0c",
+		},
+		{
+			name:    "trailer text inside a line",
+			message: "Mention RapidBuild-Version: 9 in the body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, number := versionTrailers(tt.message)
+			if gotID := deref(id, ""); gotID != tt.wantID {
+				t.Errorf("version ID = %q, want %q", gotID, tt.wantID)
+			}
+			if gotNumber := deref(number, 0); gotNumber != tt.wantNumber {
+				t.Errorf("version number = %d, want %d", gotNumber, tt.wantNumber)
+			}
+		})
+	}
+}
+
+func TestVersionTrailersReadExportMessages(t *testing.T) {
+	instructions := "Add a pricing page"
+	version := &models.Version{ID: "a1b2", VersionNumber: 12, Instructions: &instructions}
+
+	id, number := versionTrailers(gitExportMessage(version, nil, services.GitExportEventCompleted))
+	if id == nil || *id != "a1b2" || number == nil || *number != 12 {
+		t.Errorf("versionTrailers() = %v, %v, want a1b2, 12", id, number)
+	}
+}
+
+// TestHistoryPullBundle checks which commit the local branch ends up on after
+// pulling another host's bundle
+func TestHistoryPullBundle(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "RapidBuild")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "history@rapidbuild.test")
+	}
+
+	tests := []struct {
+		name string
+		// local and bundle are commit names on the shared history a <- b <- c,
+		// or d, a commit on a that only exists locally
+		local  string
+		bundle string
+		want   string
+	}{
+		{"up to date", "b", "b", "b"},
+		{"bundle ahead", "a", "c", "c"},
+		{"local ahead", "c", "b", "c"},
+		{"diverged", "d", "b", "b"},
+		{"empty local repository", "", "b", "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			h := &History{Dir: dir}
+			ctx := context.Background()
+
+			// Both repositories get the same commits, only their branches differ
+			commits := map[string]string{}
+			source := filepath.Join(dir, "source.git")
+			runGit(t, "", "init", "--quiet", "--bare", source)
+			tree := runGit(t, source, "hash-object", "-t", "tree", "-w", "--stdin")
+			commits["a"] = runGit(t, source, "commit-tree", tree, "-m", "a")
+			commits["b"] = runGit(t, source, "commit-tree", tree, "-p", commits["a"], "-m", "b")
+			commits["c"] = runGit(t, source, "commit-tree", tree, "-p", commits["b"], "-m", "c")
+			commits["d"] = runGit(t, source, "commit-tree", tree, "-p", commits["a"], "-m", "d")
+			for name, commit := range commits {
+				runGit(t, source, "update-ref", "refs/commits/"+name, commit)
+			}
+
+			runGit(t, source, "update-ref", historyBranch, commits[tt.bundle])
+			bundle := filepath.Join(dir, "history.bundle")
+			runGit(t, source, "bundle", "create", "--quiet", bundle, historyBranch)
+
+			repo := h.repoDir("app")
+			if err := h.create(ctx, repo); err != nil {
+				t.Fatal(err)
+			}
+			if tt.local != "" {
+				runGit(t, repo, "fetch", "--quiet", source, "refs/commits/*:refs/commits/*")
+				runGit(t, repo, "update-ref", historyBranch, commits[tt.local])
+			}
+
+			if err := h.pullBundle(ctx, "app", repo, bundle); err != nil {
+				t.Fatalf("pullBundle() error = %v", err)
+			}
+			if got := runGit(t, repo, "rev-parse", historyBranch); got != commits[tt.want] {
+				name := "?"
+				for n, sha := range commits {
+					if sha == got {
+						name = n
+					}
+				}
+				t.Errorf("branch is at %s, want %s", name, tt.want)
+			}
+		})
+	}
+}
+
+func deref[T any](p *T, zero T) T {
+	if p == nil {
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,371 @@
+package worker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// maxImportFiles caps the number of files an import may unpack to
+const maxImportFiles = 20000
+
+// importSkipDirs are dropped wherever they appear in imported code: they are
+// rebuilt on every build or only make sense on the machine they came from
+var importSkipDirs = map[string]bool{
+	"node_modules":   true,
+	".vercel":        true,
+	".agent-history": true,
+	"dist":           true,
+	".git":           true,
+	".next":          true,
+	"__MACOSX":       true,
+}
+
+// importLimits tracks how much an import has unpacked
+type importLimits struct {
+	maxBytes int64
+	bytes    int64
+	files    int
+}
+
+// StageImportArchive validates a zip or tar.gz of an existing project and
+// unpacks it into a staging directory for BuildImport. Errors about the
+// archive itself wrap ErrInvalidImport.
+func (b *Builder) StageImportArchive(r io.ReaderAt, size int64) (string, error) {
+	limits := b.importLimits()
+	if size > limits.maxBytes {
+		return "", fmt.Errorf("%w: archive is larger than %d MB", ErrInvalidImport, b.Config.ImportMaxMB)
+	}
+
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return "", fmt.Errorf("%w: archive is empty or unreadable", ErrInvalidImport)
+	}
+
+	stageDir, err := b.newImportDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		err = extractImportZip(r, size, stageDir, limits)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		var gzr *gzip.Reader
+		if gzr, err = gzip.NewReader(io.NewSectionReader(r, 0, size)); err == nil {
+			err = extractImportTar(tar.NewReader(gzr), stageDir, limits)
+			gzr.Close()
+		}
+	default:
+		err = fmt.Errorf("%w: upload a .zip or .tar.gz archive", ErrInvalidImport)
+	}
+	if err == nil {
+		err = finishImport(stageDir)
+	}
+	if err != nil {
+		os.RemoveAll(stageDir)
+		return "", importError(err)
+	}
+	return stageDir, nil
+}
+
+// StageImportGit unpacks the HEAD commit of a local git repository under
+// Config.ImportGitRoot into a staging directory for BuildImport
+func (b *Builder) StageImportGit(ctx context.Context, repoPath string) (string, error) {
+	if b.Config.ImportGitRoot == "" {
+		return "", fmt.Errorf("%w: git imports are not enabled", ErrInvalidImport)
+	}
+
+	repoDir, err := resolveImportRepo(b.Config.ImportGitRoot, repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	if output, err := exec.CommandContext(ctx, "git", "-C", repoDir, "rev-parse", "--verify", "HEAD").CombinedOutput(); err != nil {
+		return "", fmt.Errorf("%w: not a git repository with commits: %s", ErrInvalidImport, strings.TrimSpace(string(output)))
+	}
+
+	stageDir, err := b.newImportDir()
+	if err != nil {
+		return "", err
+	}
+
+	// git archive leaves out untracked and ignored files, like node_modules
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, "git", "-C", repoDir, "archive", "--format=tar", "HEAD")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err == nil {
+		err = extractImportTar(tar.NewReader(stdout), stageDir, b.importLimits())
+		if err != nil {
+			// Stop git if extraction gave up before reading everything
+			cancel()
+		}
+		if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+			err = fmt.Errorf("git archive failed: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if err == nil {
+		err = finishImport(stageDir)
+	}
+	if err != nil {
+		os.RemoveAll(stageDir)
+		return "", importError(err)
+	}
+	return stageDir, nil
+}
+
+// DiscardImport removes a staged import that won't be built
+func (b *Builder) DiscardImport(stageDir string) {
+	os.RemoveAll(stageDir)
+}
+
+// BuildImport builds and deploys staged imported code as a version without
+// running the agent. The code is stored as the version's snapshot before
+// building, so later versions iterate on it even if this build fails.
+func (b *Builder) BuildImport(ctx context.Context, versionID, appID, stageDir, ownerEmail string) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("Build panic: %v", r)
+			log.Printf("[Import] PANIC for version %s: %s\n", versionID, errMsg)
+			b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+				"status":        "failed",
+				"error_message": &errMsg,
+			})
+		}
+	}()
+	defer os.RemoveAll(stageDir)
+
+	log.Printf("[Import] Building version %s of app %s from imported code\n", versionID, appID)
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"status":       "building",
+		"instructions": "Imported existing code",
+	}); err != nil {
+		log.Printf("[Import] Warning: Failed to update status to building: %v\n", err)
+	}
+
+	// Give SSE clients time to subscribe, as BuildApp does
+	time.Sleep(2 * time.Second)
+
+	workspaceDir := filepath.Join(b.Config.WorkspaceDir, appID)
+	os.RemoveAll(workspaceDir)
+	if err := os.Rename(stageDir, workspaceDir); err != nil {
+		return b.handleError(ctx, versionID, "Failed to create workspace", err)
+	}
+	defer b.cleanup(workspaceDir)
+
+	b.sendProgress(versionID, "building", "Saving imported code...")
+	tarPath, err := b.packageCode(workspaceDir)
+	if err != nil {
+		return b.handleError(ctx, versionID, "Failed to package code", err)
+	}
+	s3Path, err := b.uploadToS3(ctx, tarPath, appID, versionID)
+	if err != nil {
+		return b.handleError(ctx, versionID, "Failed to upload to S3", err)
+	}
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"s3_code_path": s3Path,
+	}); err != nil {
+		return b.handleError(ctx, versionID, "Failed to update S3 path", err)
+	}
+
+	return b.buildSnapshot(ctx, workspaceDir, appID, versionID, ownerEmail)
+}
+
+func (b *Builder) importLimits() *importLimits {
+	return &importLimits{maxBytes: b.Config.ImportMaxMB << 20}
+}
+
+// newImportDir creates a staging directory on the workspace filesystem, so
+// BuildImport can move it into place
+func (b *Builder) newImportDir() (string, error) {
+	importsDir := filepath.Join(b.Config.WorkspaceDir, ".imports")
+	if err := os.MkdirAll(importsDir, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(importsDir, "import-")
+}
+
+// resolveImportRepo resolves repoPath, relative or absolute, to a directory
+// inside root, following symlinks
+func resolveImportRepo(root, repoPath string) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("git import root is unavailable: %w", err)
+	}
+
+	if !filepath.IsAbs(repoPath) {
+		repoPath = filepath.Join(root, repoPath)
+	}
+	repoDir, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: repository not found: %s", ErrInvalidImport, repoPath)
+	}
+
+	rel, err := filepath.Rel(root, repoDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: repository must be inside the import root", ErrInvalidImport)
+	}
+	return repoDir, nil
+}
+
+func extractImportZip(r io.ReaderAt, size int64, destDir string, limits *importLimits) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !f.Mode().IsRegular() || skipImportPath(f.Name) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		err = writeImportFile(destDir, f.Name, f.Mode(), rc, limits)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractImportTar(tr *tar.Reader, destDir string, limits *importLimits) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		// Directories are created with their files; links could point
+		// outside the workspace, so they are left out
+		if header.Typeflag != tar.TypeReg || skipImportPath(header.Name) {
+			continue
+		}
+
+		if err := writeImportFile(destDir, header.Name, os.FileMode(header.Mode), tr, limits); err != nil {
+			return err
+		}
+	}
+}
+
+// writeImportFile writes one file of an import, enforcing the path and size limits
+func writeImportFile(destDir, name string, mode os.FileMode, r io.Reader, limits *importLimits) error {
+	target, err := safeJoin(destDir, name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	limits.files++
+	if limits.files > maxImportFiles {
+		return fmt.Errorf("%w: more than %d files", ErrInvalidImport, maxImportFiles)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()&0755|0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read one byte past the remaining allowance to tell whether it was exceeded
+	remaining := limits.maxBytes - limits.bytes
+	n, err := io.CopyN(file, r, remaining+1)
+	limits.bytes += n
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if limits.bytes > limits.maxBytes {
+		return fmt.Errorf("%w: unpacked code is larger than %d MB", ErrInvalidImport, limits.maxBytes>>20)
+	}
+	return nil
+}
+
+// skipImportPath reports whether an archive entry is inside a skipped directory
+func skipImportPath(name string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if importSkipDirs[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// finishImport moves the project to the top of the staging directory when the
+// archive wraps it in a single folder, and checks for a valid package.json
+func finishImport(stageDir string) error {
+	packageJSON := filepath.Join(stageDir, "package.json")
+	if _, err := os.Stat(packageJSON); os.IsNotExist(err) {
+		entries, err := os.ReadDir(stageDir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 1 && entries[0].IsDir() {
+			if err := hoistDir(stageDir, entries[0].Name()); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := os.ReadFile(packageJSON)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: no package.json at the top of the project", ErrInvalidImport)
+	}
+	if err != nil {
+		return err
+	}
+
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("%w: package.json is not valid JSON: %v", ErrInvalidImport, err)
+	}
+	return nil
+}
+
+// hoistDir moves the contents of dir/name up into dir
+func hoistDir(dir, name string) error {
+	tmp := dir + "-hoist"
+	if err := os.Rename(filepath.Join(dir, name), tmp); err != nil {
+		return err
+	}
+	if err := os.Remove(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// importError logs unexpected failures, which aren't the uploader's fault
+func importError(err error) error {
+	if !errors.Is(err, ErrInvalidImport) {
+		log.Printf("[Import] Error staging import: %v\n", err)
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// staleJobTimeout is how long a job may stay running before it is assumed lost
+const staleJobTimeout = 30 * time.Minute
+
+// maxJobBackoff caps the delay between retries of a failing job
+const maxJobBackoff = time.Hour
+
+// JobHandler runs one job; returning an error schedules a retry
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// JobRunner polls the jobs table and runs due jobs one at a time
+type JobRunner struct {
+	Jobs         *services.JobService
+	PollInterval time.Duration
+	handlers     map[string]JobHandler
+}
+
+func NewJobRunner(jobService *services.JobService, pollInterval time.Duration) *JobRunner {
+	return &JobRunner{
+		Jobs:         jobService,
+		PollInterval: pollInterval,
+		handlers:     map[string]JobHandler{},
+	}
+}
+
+// Register sets the handler for a job type
+func (r *JobRunner) Register(jobType string, handler JobHandler) {
+	r.handlers[jobType] = handler
+}
+
+// Start runs jobs until ctx is cancelled
+func (r *JobRunner) Start(ctx context.Context) {
+	if r.PollInterval <= 0 {
+		log.Println("[Jobs] Disabled (JOB_POLL_INTERVAL <= 0)")
+		return
+	}
+
+	log.Printf("[Jobs] Polling for jobs every %s\n", r.PollInterval)
+
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := r.Jobs.RequeueStale(ctx, staleJobTimeout); err != nil {
+			log.Printf("[Jobs] Warning: %v\n", err)
+		} else if n > 0 {
+			log.Printf("[Jobs] Requeued %d stale jobs\n", n)
+		}
+
+		r.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain runs due jobs until none are left
+func (r *JobRunner) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := r.Jobs.ClaimNext(ctx)
+		if err != nil {
+			log.Printf("[Jobs] Warning: %v\n", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+		r.run(ctx, job)
+	}
+}
+
+func (r *JobRunner) run(ctx context.Context, job *models.Job) {
+	err := r.runHandler(ctx, job)
+	if err == nil {
+		log.Printf("[Jobs] %s job %s completed\n", job.Type, job.ID)
+		if err := r.Jobs.Complete(ctx, job.ID); err != nil {
+			log.Printf("[Jobs] Warning: %v\n", err)
+		}
+		return
+	}
+
+	backoff := jobBackoff(job.Attempts)
+	if job.Attempts >= job.MaxAttempts {
+		log.Printf("[Jobs] ERROR: %s job %s failed permanently after %d attempts: %v\n", job.Type, job.ID, job.Attempts, err)
+	} else {
+		log.Printf("[Jobs] %s job %s failed (attempt %d/%d), retrying in %s: %v\n", job.Type, job.ID, job.Attempts, job.MaxAttempts, backoff, err)
+	}
+
+	if err := r.Jobs.Fail(ctx, job, err, backoff); err != nil {
+		log.Printf("[Jobs] Warning: %v\n", err)
+	}
+}
+
+// runHandler dispatches a job, turning unknown types and panics into errors
+func (r *JobRunner) runHandler(ctx context.Context, job *models.Job) (err error) {
+	handler, ok := r.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %s", job.Type)
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panic: %v", rec)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// jobBackoff doubles from 30s per attempt, capped at maxJobBackoff
+func jobBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempts && backoff < maxJobBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxJobBackoff {
+		backoff = maxJobBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,348 @@
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// schemaFile is the format of the generated app's schemas/*.json files:
+//
+//	{"collection": "todos", "schema": {<$jsonSchema>}, "indexes": [{"keys": {"userId": 1, "createdAt": -1}, "unique": false}]}
+//
+// The collection defaults to the file name. Files are read as extended JSON so
+// index key order is preserved.
+type schemaFile struct {
+	Collection string `bson:"collection"`
+	Name       string `bson:"name"`
+	Schema     bson.M `bson:"schema"`
+	Indexes    []struct {
+		Name               string `bson:"name"`
+		Keys               bson.D `bson:"keys"`
+		Unique             bool   `bson:"unique"`
+		Sparse             bool   `bson:"sparse"`
+		ExpireAfterSeconds *int32 `bson:"expireAfterSeconds"`
+	} `bson:"indexes"`
+}
+
+// parseSchemas reads schemas/*.json, returning nil when the directory does not exist
+func parseSchemas(schemasDir string) ([]models.CollectionSchema, error) {
+	entries, err := os.ReadDir(schemasDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []models.CollectionSchema
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(schemasDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var file schemaFile
+		if err := bson.UnmarshalExtJSON(data, false, &file); err != nil {
+			return nil, fmt.Errorf("invalid schema file %s: %w", entry.Name(), err)
+		}
+
+		schema := models.CollectionSchema{Name: file.Collection}
+		if schema.Name == "" {
+			schema.Name = file.Name
+		}
+		if schema.Name == "" {
+			schema.Name = strings.TrimSuffix(entry.Name(), ".json")
+		}
+
+		if file.Schema != nil {
+			if schema.Validator, err = plainJSON(file.Schema); err != nil {
+				return nil, fmt.Errorf("invalid schema in %s: %w", entry.Name(), err)
+			}
+		}
+
+		for _, index := range file.Indexes {
+			if len(index.Keys) == 0 {
+				continue
+			}
+			spec := models.IndexSpec{
+				Name:               index.Name,
+				Unique:             index.Unique,
+				Sparse:             index.Sparse,
+				ExpireAfterSeconds: index.ExpireAfterSeconds,
+			}
+			for _, key := range index.Keys {
+				spec.Keys = append(spec.Keys, models.IndexKey{Field: key.Key, Value: fmt.Sprint(key.Value)})
+			}
+			if spec.Name == "" {
+				spec.Name = defaultIndexName(spec.Keys)
+			}
+			schema.Indexes = append(schema.Indexes, spec)
+		}
+
+		schemas = append(schemas, schema)
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+	return schemas, nil
+}
+
+// plainJSON converts decoded extended JSON into plain maps so snapshots compare
+// equal after a round trip through Postgres
+func plainJSON(doc bson.M) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	return out, json.Unmarshal(data, &out)
+}
+
+// defaultIndexName matches MongoDB's generated index names, e.g. userId_1_createdAt_-1
+func defaultIndexName(keys []models.IndexKey) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Field, key.Value)
+	}
+	return strings.Join(parts, "_")
+}
+
+// planMigration computes the steps that take an app database from the baseline
+// schemas to the target schemas. Collections that disappeared from the schemas
+// are never dropped, so no data is lost because the agent removed a file.
+func planMigration(baseline, target []models.CollectionSchema, existing map[string]bool) []models.MigrationStep {
+	previous := map[string]models.CollectionSchema{}
+	for _, schema := range baseline {
+		previous[schema.Name] = schema
+	}
+
+	var steps []models.MigrationStep
+	for _, schema := range target {
+		if !existing[schema.Name] {
+			steps = append(steps, models.MigrationStep{
+				Action:     services.MigrationCreateCollection,
+				Collection: schema.Name,
+				Validator:  schema.Validator,
+			})
+			for i := range schema.Indexes {
+				steps = append(steps, createIndexStep(schema.Name, schema.Indexes[i]))
+			}
+			continue
+		}
+
+		prev := previous[schema.Name]
+		if canonicalJSON(prev.Validator) != canonicalJSON(schema.Validator) {
+			steps = append(steps, models.MigrationStep{
+				Action:     services.MigrationUpdateValidator,
+				Collection: schema.Name,
+				Validator:  schema.Validator,
+			})
+		}
+
+		prevIndexes := map[string]models.IndexSpec{}
+		for _, index := range prev.Indexes {
+			prevIndexes[index.Name] = index
+		}
+		targetIndexes := map[string]bool{}
+
+		for _, index := range schema.Indexes {
+			targetIndexes[index.Name] = true
+			old, had := prevIndexes[index.Name]
+			if had && canonicalJSON(old) == canonicalJSON(index) {
+				continue
+			}
+			// An index can't be modified in place
+			if had {
+				steps = append(steps, dropIndexStep(schema.Name, index.Name))
+			}
+			steps = append(steps, createIndexStep(schema.Name, index))
+		}
+
+		for _, index := range prev.Indexes {
+			if !targetIndexes[index.Name] {
+				steps = append(steps, dropIndexStep(schema.Name, index.Name))
+			}
+		}
+	}
+
+	return steps
+}
+
+func createIndexStep(collection string, index models.IndexSpec) models.MigrationStep {
+	return models.MigrationStep{
+		Action:     services.MigrationCreateIndex,
+		Collection: collection,
+		Index:      &index,
+	}
+}
+
+func dropIndexStep(collection, name string) models.MigrationStep {
+	return models.MigrationStep{
+		Action:     services.MigrationDropIndex,
+		Collection: collection,
+		IndexName:  name,
+	}
+}
+
+// canonicalJSON encodes v with sorted keys for comparison
+func canonicalJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// migrateDatabase brings the app database in line with the workspace schemas.
+// The first time an app has schemas the database is provisioned from scratch;
+// afterwards the difference to the last applied schema (or, for apps that
+// predate migrations, the previous version's schemas) is applied after backing
+// up the affected collections.
+func (b *Builder) migrateDatabase(ctx context.Context, workspaceDir, appID, versionID, ownerEmail string, previous []models.CollectionSchema) error {
+	if b.Schemas == nil || b.Databases == nil {
+		return fmt.Errorf("database services not configured")
+	}
+
+	schemasDir := filepath.Join(workspaceDir, "schemas")
+	schemas, err := parseSchemas(schemasDir)
+	if err != nil {
+		return err
+	}
+
+	latest, err := b.Schemas.LatestMigration(ctx, appID)
+	if err != nil {
+		return err
+	}
+	nextVersion := 1
+	if latest != nil {
+		nextVersion = latest.SchemaVersion + 1
+	}
+
+	dbName, err := b.Schemas.DatabaseName(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	if dbName == "" {
+		if _, err := b.Databases.Provision(ctx, appID, ownerEmail, schemas); err != nil {
+			return err
+		}
+		return b.recordMigration(ctx, appID, versionID, nextVersion, schemas, nil, nil)
+	}
+
+	// Apps provisioned without an owner email still need their admin account
+	if err := b.Databases.EnsureAdmin(ctx, appID, ownerEmail); err != nil {
+		return err
+	}
+
+	baseline := previous
+	if latest != nil {
+		baseline = latest.Schema
+	}
+
+	existing, err := b.Schemas.CollectionNames(ctx, dbName)
+	if err != nil {
+		return err
+	}
+
+	steps := planMigration(baseline, schemas, existing)
+	if len(steps) == 0 {
+		// Record a baseline snapshot for apps that predate migrations
+		if latest == nil {
+			return b.recordMigration(ctx, appID, versionID, nextVersion, schemas, nil, nil)
+		}
+		log.Printf("[Database] Schema unchanged for app %s (version %d)\n", appID, latest.SchemaVersion)
+		return nil
+	}
+
+	log.Printf("[Database] Migrating app %s to schema version %d (%d steps)\n", appID, nextVersion, len(steps))
+
+	backupPath, err := b.backupCollections(ctx, appID, dbName, nextVersion, steps, existing)
+	if err != nil {
+		return fmt.Errorf("backup before migration failed: %w", err)
+	}
+
+	for _, step := range steps {
+		if err := b.Schemas.ApplyStep(ctx, dbName, step); err != nil {
+			return fmt.Errorf("%s on %s failed: %w", step.Action, step.Collection, err)
+		}
+	}
+
+	return b.recordMigration(ctx, appID, versionID, nextVersion, schemas, steps, backupPath)
+}
+
+func (b *Builder) recordMigration(ctx context.Context, appID, versionID string, schemaVersion int, schemas []models.CollectionSchema, steps []models.MigrationStep, backupPath *string) error {
+	if schemas == nil {
+		schemas = []models.CollectionSchema{}
+	}
+	if steps == nil {
+		steps = []models.MigrationStep{}
+	}
+
+	err := b.Schemas.RecordMigration(ctx, &models.SchemaMigration{
+		AppID:         appID,
+		VersionID:     &versionID,
+		SchemaVersion: schemaVersion,
+		Schema:        schemas,
+		Steps:         steps,
+		BackupPath:    backupPath,
+	})
+	if err == nil {
+		log.Printf("[Database] ✅ App %s at schema version %d\n", appID, schemaVersion)
+	}
+	return err
+}
+
+// backupCollections uploads a JSON lines dump of every existing collection the
+// steps touch, returning the S3 prefix or nil when nothing needed a backup
+func (b *Builder) backupCollections(ctx context.Context, appID, dbName string, schemaVersion int, steps []models.MigrationStep, existing map[string]bool) (*string, error) {
+	collections := map[string]bool{}
+	for _, step := range steps {
+		if existing[step.Collection] {
+			collections[step.Collection] = true
+		}
+	}
+	if len(collections) == 0 {
+		return nil, nil
+	}
+
+	prefix := fmt.Sprintf("apps/%s/schema-backups/v%d/", appID, schemaVersion)
+	for collection := range collections {
+		tmp, err := os.CreateTemp("", "schema-backup-*.jsonl.gz")
+		if err != nil {
+			return nil, err
+		}
+		tmpPath := tmp.Name()
+
+		gzw := gzip.NewWriter(tmp)
+		count, err := b.Schemas.BackupCollection(ctx, dbName, collection, gzw)
+		if closeErr := gzw.Close(); err == nil {
+			err = closeErr
+		}
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = b.uploadFileToS3(ctx, tmpPath, prefix+collection+".jsonl.gz")
+		}
+		os.Remove(tmpPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", collection, err)
+		}
+
+		log.Printf("[Database] Backed up %d documents from %s.%s\n", count, dbName, collection)
+	}
+
+	return &prefix, nil
+}
@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+func TestParseSchemas(t *testing.T) {
+	ttl := int32(3600)
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []models.CollectionSchema
+		wantErr bool
+	}{
+		{
+			name: "no schemas directory",
+			want: nil,
+		},
+		{
+			name: "index key order and default names",
+			files: map[string]string{
+				"todos.json": `{"collection": "todos", "schema": {"bsonType": "object", "required": ["title"]},
+					"indexes": [{"keys": {"userId": 1, "createdAt": -1}}, {"name": "by_title", "keys": {"title": "text"}, "unique": true}]}`,
+			},
+			want: []models.CollectionSchema{{
+				Name:      "todos",
+				Validator: map[string]interface{}{"bsonType": "object", "required": []interface{}{"title"}},
+				Indexes: []models.IndexSpec{
+					{Name: "userId_1_createdAt_-1", Keys: []models.IndexKey{{Field: "userId", Value: "1"}, {Field: "createdAt", Value: "-1"}}},
+					{Name: "by_title", Keys: []models.IndexKey{{Field: "title", Value: "text"}}, Unique: true},
+				},
+			}},
+		},
+		{
+			name: "collection names from name or file, sorted",
+			files: map[string]string{
+				"sessions.json": `{"indexes": [{"keys": {"expiresAt": 1}, "expireAfterSeconds": 3600}, {"keys": {}}]}`,
+				"a.json":        `{"name": "users"}`,
+				"notes.txt":     `not a schema`,
+			},
+			want: []models.CollectionSchema{
+				{
+					Name: "sessions",
+					Indexes: []models.IndexSpec{
+						{Name: "expiresAt_1", Keys: []models.IndexKey{{Field: "expiresAt", Value: "1"}}, ExpireAfterSeconds: &ttl},
+					},
+				},
+				{Name: "users"},
+			},
+		},
+		{
+			name:    "invalid JSON",
+			files:   map[string]string{"todos.json": `{"collection": `},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "schemas")
+			if tt.files != nil {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := parseSchemas(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSchemas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSchemas() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	validator := map[string]interface{}{"bsonType": "object", "required": []interface{}{"title"}}
+	byUser := models.IndexSpec{Name: "userId_1", Keys: []models.IndexKey{{Field: "userId", Value: "1"}}}
+	byUserUnique := models.IndexSpec{Name: "userId_1", Keys: []models.IndexKey{{Field: "userId", Value: "1"}}, Unique: true}
+	byDate := models.IndexSpec{Name: "createdAt_-1", Keys: []models.IndexKey{{Field: "createdAt", Value: "-1"}}}
+	todos := models.CollectionSchema{Name: "todos", Validator: validator, Indexes: []models.IndexSpec{byUser}}
+
+	tests := []struct {
+		name     string
+		baseline []models.CollectionSchema
+		target   []models.CollectionSchema
+		existing map[string]bool
+		want     []models.MigrationStep
+	}{
+		{
+			name:     "unchanged",
+			baseline: []models.CollectionSchema{todos},
+			target:   []models.CollectionSchema{todos},
+			existing: map[string]bool{"todos": true},
+			want:     nil,
+		},
+		{
+			name:     "new collection",
+			baseline: []models.CollectionSchema{todos},
+			target:   []models.CollectionSchema{todos, {Name: "tags", Validator: validator, Indexes: []models.IndexSpec{byDate}}},
+			existing: map[string]bool{"todos": true},
+			want: []models.MigrationStep{
+				{Action: services.MigrationCreateCollection, Collection: "tags", Validator: validator},
+				createIndexStep("tags", byDate),
+			},
+		},
+		{
+			name:     "validator change",
+			baseline: []models.CollectionSchema{todos},
+			target:   []models.CollectionSchema{{Name: "todos", Validator: map[string]interface{}{"bsonType": "object"}, Indexes: todos.Indexes}},
+			existing: map[string]bool{"todos": true},
+			want: []models.MigrationStep{
+				{Action: services.MigrationUpdateValidator, Collection: "todos", Validator: map[string]interface{}{"bsonType": "object"}},
+			},
+		},
+		{
+			name:     "changed index is dropped then created",
+			baseline: []models.CollectionSchema{todos},
+			target:   []models.CollectionSchema{{Name: "todos", Validator: validator, Indexes: []models.IndexSpec{byUserUnique}}},
+			existing: map[string]bool{"todos": true},
+			want: []models.MigrationStep{
+				dropIndexStep("todos", "userId_1"),
+				createIndexStep("todos", byUserUnique),
+			},
+		},
+		{
+			name:     "added and removed indexes",
+			baseline: []models.CollectionSchema{todos},
+			target:   []models.CollectionSchema{{Name: "todos", Validator: validator, Indexes: []models.IndexSpec{byDate}}},
+			existing: map[string]bool{"todos": true},
+			want: []models.MigrationStep{
+				createIndexStep("todos", byDate),
+				dropIndexStep("todos", "userId_1"),
+			},
+		},
+		{
+			name:     "removed collection is not dropped",
+			baseline: []models.CollectionSchema{todos, {Name: "tags"}},
+			target:   []models.CollectionSchema{todos},
+			existing: map[string]bool{"todos": true, "tags": true},
+			want:     nil,
+		},
+		{
+			name:     "no baseline",
+			target:   []models.CollectionSchema{todos, {Name: "tags"}},
+			existing: map[string]bool{"todos": true},
+			want: []models.MigrationStep{
+				{Action: services.MigrationUpdateValidator, Collection: "todos", Validator: validator},
+				createIndexStep("todos", byUser),
+				{Action: services.MigrationCreateCollection, Collection: "tags"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planMigration(tt.baseline, tt.target, tt.existing)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planMigration() = %s, want %s", canonicalJSON(got), canonicalJSON(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// BuildProduction rebuilds a version's code with the app's production secrets
+// and stages a production deployment of it, which PromoteVersion then switches
+// production to. Apps without production secrets promote the preview
+// deployment, so a production build left from an earlier promotion is cleared.
+func (b *Builder) BuildProduction(ctx context.Context, versionID string) error {
+	version, err := b.VersionService.GetVersion(ctx, versionID)
+	if err != nil {
+		return err
+	}
+	if version.Status != "completed" && version.Status != "promoted" {
+		return fmt.Errorf("only completed versions can be promoted (status: %s)", version.Status)
+	}
+
+	hasProduction := false
+	if b.Secrets != nil {
+		if hasProduction, err = b.Secrets.HasProductionSecrets(ctx, version.AppID); err != nil {
+			return err
+		}
+	}
+	if !hasProduction {
+		if version.ProdDeployID == nil {
+			return nil
+		}
+		_, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+			"s3_prod_output_path": (*string)(nil),
+			"prod_deploy_id":      (*string)(nil),
+		})
+		return err
+	}
+
+	if version.S3CodePath == nil || *version.S3CodePath == "" {
+		return fmt.Errorf("version %s has no stored code to build for production", versionID)
+	}
+
+	log.Printf("[Production] Building version %s of app %s with production secrets\n", versionID, version.AppID)
+
+	// Separate from the app workspace so a concurrent build is not disturbed
+	workspaceDir := filepath.Join(b.Config.WorkspaceDir, "production-"+versionID)
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer b.cleanup(workspaceDir)
+
+	if err := b.downloadFromS3(ctx, *version.S3CodePath, workspaceDir); err != nil {
+		return fmt.Errorf("failed to restore code: %w", err)
+	}
+	if err := b.linkVercel(ctx, workspaceDir, version.AppID, versionID); err != nil {
+		return err
+	}
+
+	if b.Cache != nil {
+		if _, err := b.Cache.Restore(ctx, version.AppID, workspaceDir); err != nil {
+			log.Printf("[Cache] Warning: Failed to restore dependencies for app %s: %v\n", version.AppID, err)
+		}
+	}
+
+	removeSecrets, err := b.writeSecretsEnv(ctx, workspaceDir, version.AppID, services.SecretScopeProduction)
+	if err != nil {
+		return fmt.Errorf("failed to load app secrets: %w", err)
+	}
+	buildErr := b.buildForVercel(ctx, workspaceDir, versionID, "production", 1)
+	removeSecrets()
+	if buildErr != nil {
+		return fmt.Errorf("production build failed: %w", buildErr)
+	}
+
+	key := fmt.Sprintf("apps/%s/versions/%s/prod-output.tar.gz", version.AppID, versionID)
+	if err := b.uploadBuildOutput(ctx, workspaceDir, key); err != nil {
+		return err
+	}
+
+	_, deployID, err := b.deployToVercel(ctx, workspaceDir, version.AppID, versionID, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"s3_prod_output_path": &key,
+		"prod_deploy_id":      &deployID,
+	})
+	return err
+}
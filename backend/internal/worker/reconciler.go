@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/config"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// deploymentStateDeleted is recorded when Vercel no longer knows the deployment
+const deploymentStateDeleted = "DELETED"
+
+// brokenStates are Vercel states in which a deployment no longer serves the app
+var brokenStates = map[string]bool{
+	"ERROR":                true,
+	"CANCELED":             true,
+	deploymentStateDeleted: true,
+}
+
+// Reconciler periodically polls Vercel for the state of recent and production
+// deployments and raises events when a live deployment breaks
+type Reconciler struct {
+	Config         *config.Config
+	VersionService *services.VersionService
+	VercelService  *services.VercelService
+	EventService   *services.EventService
+}
+
+func NewReconciler(cfg *config.Config, versionService *services.VersionService, vercelService *services.VercelService, eventService *services.EventService) *Reconciler {
+	return &Reconciler{
+		Config:         cfg,
+		VersionService: versionService,
+		VercelService:  vercelService,
+		EventService:   eventService,
+	}
+}
+
+// Start runs the reconciler until ctx is cancelled
+func (r *Reconciler) Start(ctx context.Context) {
+	if r.Config.ReconcileInterval <= 0 {
+		log.Println("[Reconciler] Disabled (DEPLOYMENT_RECONCILE_INTERVAL <= 0)")
+		return
+	}
+
+	log.Printf("[Reconciler] Polling deployments every %s\n", r.Config.ReconcileInterval)
+
+	ticker := time.NewTicker(r.Config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		r.ReconcileOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileOnce checks every tracked deployment once
+func (r *Reconciler) ReconcileOnce(ctx context.Context) {
+	versions, err := r.VersionService.ListDeployedVersions(ctx, time.Now().Add(-r.Config.ReconcileWindow))
+	if err != nil {
+		log.Printf("[Reconciler] Failed to list deployed versions: %v\n", err)
+		return
+	}
+
+	for i := range versions {
+		if ctx.Err() != nil {
+			return
+		}
+		r.reconcileVersion(ctx, &versions[i])
+	}
+}
+
+func (r *Reconciler) reconcileVersion(ctx context.Context, version *models.Version) {
+	state, err := r.deploymentState(*version.VercelDeployID)
+	if err != nil {
+		log.Printf("[Reconciler] Failed to get deployment %s for version %s: %v\n", *version.VercelDeployID, version.ID, err)
+		return
+	}
+
+	previous := ""
+	if version.DeploymentState != nil {
+		previous = *version.DeploymentState
+	}
+	if state == previous {
+		return
+	}
+
+	if _, err := r.VersionService.UpdateVersion(ctx, version.ID, map[string]interface{}{
+		"deployment_state": state,
+	}); err != nil {
+		log.Printf("[Reconciler] Failed to update deployment state for version %s: %v\n", version.ID, err)
+		return
+	}
+
+	// Versions that finished building were serving traffic, even before the first poll
+	wasLive := previous == "READY" || (previous == "" && isLiveStatus(version.Status))
+
+	switch {
+	case wasLive && brokenStates[state]:
+		r.EventService.Publish(ctx, models.AppEvent{
+			Type:      services.EventDeploymentBroken,
+			AppID:     version.AppID,
+			VersionID: version.ID,
+			Message:   fmt.Sprintf("Deployment for version %d is now %s", version.VersionNumber, state),
+			Data: map[string]interface{}{
+				"deployment_id":  *version.VercelDeployID,
+				"previous_state": previous,
+				"state":          state,
+				"production":     version.Status == "promoted",
+			},
+		})
+	case brokenStates[previous] && state == "READY":
+		r.EventService.Publish(ctx, models.AppEvent{
+			Type:      services.EventDeploymentRecovered,
+			AppID:     version.AppID,
+			VersionID: version.ID,
+			Message:   fmt.Sprintf("Deployment for version %d is READY again", version.VersionNumber),
+			Data: map[string]interface{}{
+				"deployment_id":  *version.VercelDeployID,
+				"previous_state": previous,
+				"state":          state,
+			},
+		})
+	}
+}
+
+// deploymentState returns the Vercel ready state, or DELETED when the deployment is gone
+func (r *Reconciler) deploymentState(deploymentID string) (string, error) {
+	deployment, err := r.VercelService.GetDeploymentStatus(deploymentID)
+	if services.IsVercelNotFound(err) {
+		return deploymentStateDeleted, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if deployment.ReadyState != "" {
+		return deployment.ReadyState, nil
+	}
+	return deployment.State, nil
+}
+
+func isLiveStatus(status string) bool {
+	return status == "completed" || status == "promoted"
+}
@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// storeBuildOutput archives the prebuilt .vercel/output directory and uploads it
+// next to the version's code
+func (b *Builder) storeBuildOutput(ctx context.Context, workspaceDir, appID, versionID string) error {
+	key := fmt.Sprintf("apps/%s/versions/%s/output.tar.gz", appID, versionID)
+	if err := b.uploadBuildOutput(ctx, workspaceDir, key); err != nil {
+		return err
+	}
+
+	_, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"s3_output_path": key,
+	})
+	return err
+}
+
+// uploadBuildOutput archives the workspace's .vercel/output directory to key
+func (b *Builder) uploadBuildOutput(ctx context.Context, workspaceDir, key string) error {
+	outputDir := filepath.Join(workspaceDir, ".vercel", "output")
+	if _, err := os.Stat(outputDir); err != nil {
+		return fmt.Errorf("no build output found: %w", err)
+	}
+
+	tarPath := workspaceDir + "-output.tar.gz"
+	file, err := os.Create(tarPath)
+	if err != nil {
+		return err
+	}
+	if err := writeTarGz(file, outputDir, nil); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to package build output: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := b.uploadFileToS3(ctx, tarPath, key); err != nil {
+		return fmt.Errorf("failed to upload build output: %w", err)
+	}
+	return nil
+}
+
+// RedeployVersion deploys a version's stored build output again without running
+// the agent or rebuilding, e.g. after a Vercel outage or project deletion. A
+// promoted version also gets production and its domains pointed at the new
+// deployment.
+func (b *Builder) RedeployVersion(ctx context.Context, versionID string) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("Redeploy panic: %v", r)
+			log.Printf("[Redeploy] PANIC for version %s: %s\n", versionID, errMsg)
+			b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+				"status":        "failed",
+				"error_message": &errMsg,
+			})
+		}
+	}()
+
+	version, err := b.VersionService.GetVersion(ctx, versionID)
+	if err != nil {
+		return err
+	}
+	if version.S3OutputPath == nil || *version.S3OutputPath == "" {
+		return fmt.Errorf("version %s has no stored build output", versionID)
+	}
+
+	log.Printf("[Redeploy] Starting redeploy for version %s, app %s\n", versionID, version.AppID)
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"status": "building",
+	}); err != nil {
+		log.Printf("[Redeploy] Warning: Failed to update status to building: %v\n", err)
+	}
+
+	// Give SSE clients time to subscribe, same as BuildApp
+	time.Sleep(2 * time.Second)
+
+	// Separate from the app workspace so a concurrent build is not disturbed
+	workspaceDir := filepath.Join(b.Config.WorkspaceDir, "redeploy-"+versionID)
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return b.handleError(ctx, versionID, "Failed to create workspace", err)
+	}
+	defer b.cleanup(workspaceDir)
+
+	// Source is only needed for route discovery in smoke tests, so it is best effort
+	b.sendProgress(versionID, "building", "Restoring build output...")
+	if version.S3CodePath != nil && *version.S3CodePath != "" {
+		if err := b.downloadFromS3(ctx, *version.S3CodePath, workspaceDir); err != nil {
+			log.Printf("[Redeploy] Warning: Failed to restore source for version %s: %v\n", versionID, err)
+		}
+	}
+
+	if err := b.downloadOutput(ctx, *version.S3OutputPath, workspaceDir); err != nil {
+		return b.handleError(ctx, versionID, "Failed to restore build output", err)
+	}
+
+	b.sendProgress(versionID, "building", "Linking Vercel project...")
+	if err := b.linkVercel(ctx, workspaceDir, version.AppID, versionID); err != nil {
+		return b.handleError(ctx, versionID, "Failed to link Vercel project", err)
+	}
+
+	if version.Status != "promoted" {
+		return b.deployVersion(ctx, workspaceDir, version.AppID, versionID, "completed", "Redeploy completed successfully!")
+	}
+
+	if err := b.deployVersion(ctx, workspaceDir, version.AppID, versionID, "promoted", "Redeploy completed, restoring production..."); err != nil {
+		return err
+	}
+	if err := b.redeployProduction(ctx, workspaceDir, version); err != nil {
+		return b.handleError(ctx, versionID, "Failed to redeploy production", err)
+	}
+	if err := b.VersionService.RestoreProduction(ctx, versionID); err != nil {
+		return b.handleError(ctx, versionID, "Failed to restore production", err)
+	}
+
+	b.sendProgress(versionID, "completed", "Redeploy completed successfully!")
+	log.Printf("[Redeploy] ✅ Production restored for version %s\n", versionID)
+	return nil
+}
+
+// redeployProduction stages the production deployment of a promoted version:
+// its stored production output if it has one, else whatever BuildProduction
+// makes of the app's current production secrets
+func (b *Builder) redeployProduction(ctx context.Context, workspaceDir string, version *models.Version) error {
+	if version.ProdOutputPath == nil || *version.ProdOutputPath == "" {
+		return b.BuildProduction(ctx, version.ID)
+	}
+
+	if err := os.RemoveAll(filepath.Join(workspaceDir, ".vercel", "output")); err != nil {
+		return err
+	}
+	if err := b.downloadOutput(ctx, *version.ProdOutputPath, workspaceDir); err != nil {
+		return fmt.Errorf("failed to restore production output: %w", err)
+	}
+
+	_, deployID, err := b.deployToVercel(ctx, workspaceDir, version.AppID, version.ID, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.VersionService.UpdateVersion(ctx, version.ID, map[string]interface{}{
+		"prod_deploy_id": &deployID,
+	})
+	return err
+}
+
+// downloadOutput extracts a stored build output archive into the workspace's .vercel/output
+func (b *Builder) downloadOutput(ctx context.Context, s3Path, workspaceDir string) error {
+	result, err := b.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.Config.S3Bucket),
+		Key:    aws.String(s3Path),
+	})
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+
+	outputDir := filepath.Join(workspaceDir, ".vercel", "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	return extractTarGz(result.Body, outputDir)
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// secretsEnvFile is loaded by Vite and vercel build in production mode and wins
+// over any .env files the agent wrote
+const secretsEnvFile = ".env.production.local"
+
+// writeSecretsEnv writes the app's secrets for scope into the workspace for the
+// duration of a build. The returned func removes them again (restoring any file
+// that was there before) so the agent and the packaged code never see them.
+func (b *Builder) writeSecretsEnv(ctx context.Context, workspaceDir, appID, scope string) (func(), error) {
+	noop := func() {}
+	if b.Secrets == nil {
+		return noop, nil
+	}
+
+	values, err := b.Secrets.GetBuildValues(ctx, appID, scope)
+	if err != nil {
+		return noop, err
+	}
+	if len(values) == 0 {
+		return noop, nil
+	}
+
+	path := filepath.Join(workspaceDir, secretsEnvFile)
+	original, readErr := os.ReadFile(path)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	if readErr == nil {
+		sb.Write(original)
+		sb.WriteString("\n")
+	}
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", key, dotenvQuote(values[key])))
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0600); err != nil {
+		return noop, err
+	}
+
+	return func() {
+		var err error
+		if readErr == nil {
+			err = os.WriteFile(path, original, 0644)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			log.Printf("[Secrets] ERROR: Failed to remove secrets from workspace %s: %v\n", workspaceDir, err)
+		}
+	}, nil
+}
+
+// dotenvQuote quotes a value for a dotenv file. Single quotes keep the value
+// literal; values containing single quotes or newlines use double quotes.
+func dotenvQuote(value string) string {
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,394 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+)
+
+// maxSmokeRoutes caps how many routes are probed per deployment
+const maxSmokeRoutes = 20
+
+// maxSmokeBody caps how much of a response body is read when probing
+const maxSmokeBody = 2 << 20
+
+var (
+	// routeElementPattern matches the path of a React Router element such as <Route path="/about" ...>
+	routeElementPattern = regexp.MustCompile(`<Route\b[^>]*?\bpath\s*=\s*\{?\s*["'](/[^"']*)["']`)
+	// routeObjectPattern matches path: '/about' in an object literal; it only
+	// counts as a route when the object also has a routerObjectKeyPattern key
+	routeObjectPattern     = regexp.MustCompile(`\{[^{}]*?\bpath\s*:\s*["'](/[^"']*)["']`)
+	routerObjectKeyPattern = regexp.MustCompile(`\b(element|Component|component|lazy|children)\s*:`)
+	// rootMountPattern matches the element the SPA mounts into, including the
+	// Next.js pages router's __next
+	rootMountPattern = regexp.MustCompile(`id\s*=\s*["'](root|app|__next)["']`)
+	// scriptSrcPattern matches module/script tags referencing a JS bundle
+	scriptSrcPattern = regexp.MustCompile(`<script[^>]+src\s*=\s*["']([^"']+\.m?js)["']`)
+)
+
+// SmokeChecker probes a deployed app over HTTP. It only needs a base URL, so a
+// local server can stand in for a Vercel deployment.
+type SmokeChecker struct {
+	Client     *http.Client
+	Attempts   int
+	RetryDelay time.Duration
+	// SkipMountCheck passes pages without a root mount element, for server
+	// rendered frameworks such as the Next.js app router
+	SkipMountCheck bool
+}
+
+func NewSmokeChecker(attempts int, retryDelay time.Duration) *SmokeChecker {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &SmokeChecker{
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		Attempts:   attempts,
+		RetryDelay: retryDelay,
+	}
+}
+
+// Check probes each route under baseURL plus the JS bundle referenced by the index page
+func (c *SmokeChecker) Check(ctx context.Context, baseURL string, routes []string) *models.SmokeReport {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if len(routes) == 0 {
+		routes = []string{"/"}
+	}
+
+	report := &models.SmokeReport{
+		Healthy:   true,
+		BaseURL:   baseURL,
+		Routes:    []models.SmokeCheck{},
+		CheckedAt: time.Now(),
+	}
+
+	var indexHTML string
+	for _, route := range routes {
+		check, body := c.checkRoute(ctx, baseURL, route)
+		if route == "/" {
+			indexHTML = body
+		}
+		if !check.Passed {
+			report.Healthy = false
+		}
+		report.Routes = append(report.Routes, check)
+	}
+
+	// Verify the JS bundle referenced by the index page loads
+	if match := scriptSrcPattern.FindStringSubmatch(indexHTML); match != nil {
+		bundleURL, err := resolveURL(baseURL+"/", match[1])
+		if err == nil {
+			report.BundleURL = bundleURL
+			report.BundleLoaded = c.checkBundle(ctx, bundleURL)
+		}
+	}
+	if !report.BundleLoaded {
+		report.Healthy = false
+	}
+
+	return report
+}
+
+// checkRoute fetches a route with retries and returns the check plus the last body read
+func (c *SmokeChecker) checkRoute(ctx context.Context, baseURL, route string) (models.SmokeCheck, string) {
+	check := models.SmokeCheck{Path: route}
+	var body string
+
+	for attempt := 1; attempt <= c.Attempts; attempt++ {
+		check.Attempts = attempt
+		check.Error = ""
+
+		status, data, err := c.get(ctx, baseURL+route)
+		check.StatusCode = status
+		body = data
+
+		switch {
+		case err != nil:
+			check.Error = err.Error()
+		case status != http.StatusOK:
+			check.Error = fmt.Sprintf("unexpected status %d", status)
+		case !c.SkipMountCheck && !rootMountPattern.MatchString(body):
+			check.Error = "HTML does not contain a root mount element"
+		default:
+			check.RootMount = rootMountPattern.MatchString(body)
+			check.Passed = true
+			return check, body
+		}
+
+		if attempt < c.Attempts && !c.wait(ctx) {
+			break
+		}
+	}
+
+	return check, body
+}
+
+func (c *SmokeChecker) checkBundle(ctx context.Context, bundleURL string) bool {
+	for attempt := 1; attempt <= c.Attempts; attempt++ {
+		status, body, err := c.get(ctx, bundleURL)
+		if err == nil && status == http.StatusOK && len(body) > 0 {
+			return true
+		}
+		if attempt < c.Attempts && !c.wait(ctx) {
+			break
+		}
+	}
+	return false
+}
+
+func (c *SmokeChecker) get(ctx context.Context, target string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return 0, "", err
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSmokeBody))
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+
+	return resp.StatusCode, string(data), nil
+}
+
+// wait sleeps for the retry delay and reports whether the context is still alive
+func (c *SmokeChecker) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(c.RetryDelay):
+		return true
+	}
+}
+
+func resolveURL(base, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+// findAppRoutes collects static client-side routes declared in the app's source.
+// Deep routes only load when the deployment falls back to index.html, so
+// without one configured only "/" is probed.
+func findAppRoutes(workspaceDir string) []string {
+	seen := map[string]bool{"/": true}
+	routes := []string{"/"}
+	if !hasSPAFallback(workspaceDir) {
+		return routes
+	}
+
+	srcDir := filepath.Join(workspaceDir, "src")
+	filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".js", ".jsx", ".ts", ".tsx":
+		default:
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		for _, route := range declaredRoutes(string(data)) {
+			// Dynamic segments and catch-alls need real data to render, skip them
+			if strings.ContainsAny(route, ":*") || seen[route] {
+				continue
+			}
+			seen[route] = true
+			routes = append(routes, route)
+		}
+		return nil
+	})
+
+	sort.Strings(routes[1:])
+	if len(routes) > maxSmokeRoutes {
+		routes = routes[:maxSmokeRoutes]
+	}
+	return routes
+}
+
+// declaredRoutes returns the paths of <Route> elements and router config objects
+// in a source file, so other path: '/x' values (API clients, menus) are ignored
+func declaredRoutes(source string) []string {
+	var routes []string
+	for _, match := range routeElementPattern.FindAllStringSubmatch(source, -1) {
+		routes = append(routes, match[1])
+	}
+	for _, match := range routeObjectPattern.FindAllStringSubmatchIndex(source, -1) {
+		if routerObjectKeyPattern.MatchString(objectLiteral(source, match[0])) {
+			routes = append(routes, source[match[2]:match[3]])
+		}
+	}
+	return routes
+}
+
+// objectLiteral returns the text from the brace at start up to its matching
+// closing brace, or to the end of source if it is not closed
+func objectLiteral(source string, start int) string {
+	depth := 0
+	for i := start; i < len(source); i++ {
+		switch source[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return source[start : i+1]
+			}
+		}
+	}
+	return source[start:]
+}
+
+// hasSPAFallback reports whether unknown paths of the deployment are served
+// index.html, through a catch-all rewrite in vercel.json or a route in the
+// prebuilt output config
+func hasSPAFallback(workspaceDir string) bool {
+	var vercelConfig struct {
+		Rewrites []struct {
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+		} `json:"rewrites"`
+		Routes []struct {
+			Src  string `json:"src"`
+			Dest string `json:"dest"`
+		} `json:"routes"`
+	}
+
+	if readJSONFile(filepath.Join(workspaceDir, "vercel.json"), &vercelConfig) {
+		for _, rewrite := range vercelConfig.Rewrites {
+			if isIndexDestination(rewrite.Destination) && isCatchAll(rewrite.Source) {
+				return true
+			}
+		}
+		for _, route := range vercelConfig.Routes {
+			if isIndexDestination(route.Dest) && isCatchAll(route.Src) {
+				return true
+			}
+		}
+	}
+
+	vercelConfig.Routes = nil
+	if readJSONFile(filepath.Join(workspaceDir, ".vercel", "output", "config.json"), &vercelConfig) {
+		for _, route := range vercelConfig.Routes {
+			if isIndexDestination(route.Dest) && isCatchAll(route.Src) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func readJSONFile(path string, v interface{}) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func isIndexDestination(dest string) bool {
+	return dest == "/" || dest == "/index.html"
+}
+
+// isCatchAll reports whether a rewrite source matches arbitrary paths, e.g.
+// /(.*), /:path* or /((?!api/).*)
+func isCatchAll(source string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(source, "^"), "/") &&
+		(strings.Contains(source, "*") || strings.Contains(source, "(.+)"))
+}
+
+// smokeTestDeployment probes the deployed URL and the app's routes and stores the report on the version
+func (b *Builder) smokeTestDeployment(ctx context.Context, workspaceDir, appID, versionID, deploymentURL string) *models.SmokeReport {
+	checker := NewSmokeChecker(b.Config.SmokeTestAttempts, b.Config.SmokeTestRetryDelay)
+
+	// Redeploys may run without the source, so fall back to the app's framework
+	framework := detectFramework(workspaceDir)
+	if framework == "" {
+		if app, err := b.AppService.GetAppByID(ctx, appID); err == nil && app.Framework != nil {
+			framework = *app.Framework
+		}
+	}
+	// Next.js renders on the server; the app router has no mount element at all
+	checker.SkipMountCheck = framework == "nextjs"
+
+	report := checker.Check(ctx, deploymentURL, findAppRoutes(workspaceDir))
+
+	log.Printf("[Smoke] Version %s at %s: healthy=%t\n", versionID, deploymentURL, report.Healthy)
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"smoke_report": report,
+	}); err != nil {
+		log.Printf("[Smoke] Warning: Failed to store smoke report for version %s: %v\n", versionID, err)
+	}
+
+	return report
+}
+
+// markUnhealthy flags a version whose deployment failed its smoke tests
+func (b *Builder) markUnhealthy(ctx context.Context, appID, versionID string, report *models.SmokeReport) error {
+	var failed []string
+	for _, check := range report.Routes {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s (%s)", check.Path, check.Error))
+		}
+	}
+	if !report.BundleLoaded {
+		failed = append(failed, "JS bundle did not load")
+	}
+
+	errMsg := "Deployment failed smoke tests: " + strings.Join(failed, "; ")
+	log.Printf("[BuildApp] Version %s is unhealthy: %s\n", versionID, errMsg)
+	b.sendProgress(versionID, "unhealthy", errMsg)
+
+	if _, err := b.VersionService.UpdateVersion(ctx, versionID, map[string]interface{}{
+		"status":        "unhealthy",
+		"error_message": &errMsg,
+	}); err != nil {
+		log.Printf("[BuildApp] Failed to mark version %s unhealthy: %v\n", versionID, err)
+	}
+
+	if _, err := b.AppService.UpdateApp(ctx, appID, "", map[string]interface{}{
+		"status": "error",
+	}); err != nil {
+		log.Printf("[BuildApp] Warning: Failed to update app status: %v\n", err)
+	}
+
+	return fmt.Errorf("%s", errMsg)
+}
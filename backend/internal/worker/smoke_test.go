@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeclaredRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name:   "route elements",
+			source: `<Routes><Route path="/" element={<Home />} /><Route index path={"/about"} element={<About />} /></Routes>`,
+			want:   []string{"/", "/about"},
+		},
+		{
+			name: "router config objects",
+			source: `createBrowserRouter([
+				{ path: "/", element: <Layout />, children: [{ path: '/pricing', element: <Pricing /> }] },
+				{ Component: Settings, path: "/settings" },
+			])`,
+			want: []string{"/", "/pricing", "/settings"},
+		},
+		{
+			name:   "other path keys",
+			source: `fetch({ path: '/api/items', method: 'GET' }); const links = [{ label: 'Docs', path: '/docs' }]`,
+			want:   nil,
+		},
+		{
+			name:   "path props on other elements",
+			source: `<Link path="/contact">Contact</Link>`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := declaredRoutes(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("declaredRoutes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAppRoutes(t *testing.T) {
+	app := `<Route path="/" element={<Home />} /><Route path="/about" element={<About />} /><Route path="/users/:id" element={<User />} />`
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "no fallback",
+			files: map[string]string{"src/App.tsx": app},
+			want:  []string{"/"},
+		},
+		{
+			name: "vercel.json rewrite",
+			files: map[string]string{
+				"src/App.tsx": app,
+				"vercel.json": `{"rewrites": [{"source": "/(.*)", "destination": "/index.html"}]}`,
+			},
+			want: []string{"/", "/about"},
+		},
+		{
+			name: "rewrite to another page",
+			files: map[string]string{
+				"src/App.tsx": app,
+				"vercel.json": `{"rewrites": [{"source": "/(.*)", "destination": "/404.html"}]}`,
+			},
+			want: []string{"/"},
+		},
+		{
+			name: "prebuilt output route",
+			files: map[string]string{
+				"src/App.tsx":                app,
+				".vercel/output/config.json": `{"version": 3, "routes": [{"handle": "filesystem"}, {"src": "/(.*)", "dest": "/index.html"}]}`,
+			},
+			want: []string{"/", "/about"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := findAppRoutes(dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findAppRoutes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmokeCheckerMount(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		skipMount bool
+		healthy   bool
+	}{
+		{
+			name:    "vite root",
+			html:    `<div id="root"></div><script type="module" src="/assets/index.js"></script>`,
+			healthy: true,
+		},
+		{
+			name:    "next.js pages router",
+			html:    `<div id="__next"><h1>Home</h1></div><script src="/_next/static/chunks/main.js" defer></script>`,
+			healthy: true,
+		},
+		{
+			name:      "next.js app router",
+			html:      `<html><body><main><h1>Home</h1></main><script src="/_next/static/chunks/webpack.js" async></script></body></html>`,
+			skipMount: true,
+			healthy:   true,
+		},
+		{
+			name:    "no mount element",
+			html:    `<html><body><main><h1>Home</h1></main><script src="/assets/index.js"></script></body></html>`,
+			healthy: false,
+		},
+		{
+			name:      "no mount check but missing bundle",
+			html:      `<html><body><h1>Home</h1></body></html>`,
+			skipMount: true,
+			healthy:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, ".js") {
+					w.Write([]byte("console.log('ok')"))
+					return
+				}
+				w.Write([]byte(tt.html))
+			}))
+			defer server.Close()
+
+			checker := NewSmokeChecker(1, 0)
+			checker.SkipMountCheck = tt.skipMount
+			report := checker.Check(context.Background(), server.URL, nil)
+			if report.Healthy != tt.healthy {
+				t.Errorf("Check() healthy = %v, want %v (report %+v)", report.Healthy, tt.healthy, report)
+			}
+		})
+	}
+}
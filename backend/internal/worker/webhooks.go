@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/rapidbuildapp/rapidbuild/internal/models"
+	"github.com/rapidbuildapp/rapidbuild/internal/services"
+)
+
+// WebhookSender sends queued webhook deliveries. Failed sends are retried with
+// the job runner's backoff until the job runs out of attempts.
+type WebhookSender struct {
+	Webhooks *services.WebhookService
+}
+
+func NewWebhookSender(webhookService *services.WebhookService) *WebhookSender {
+	return &WebhookSender{Webhooks: webhookService}
+}
+
+// Register adds the delivery handler to a job runner
+func (s *WebhookSender) Register(runner *JobRunner) {
+	runner.Register(services.JobWebhookDeliver, s.deliver)
+}
+
+func (s *WebhookSender) deliver(ctx context.Context, job *models.Job) error {
+	deliveryID := job.Payload["delivery_id"]
+
+	delivery, err := s.Webhooks.Deliver(ctx, deliveryID, job.Attempts < job.MaxAttempts)
+	if errors.Is(err, services.ErrDeliveryNotFound) {
+		log.Printf("[Webhooks] Delivery %s no longer exists, skipping\n", deliveryID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[Webhooks] Delivered %s to webhook %s in %dms\n", delivery.EventType, delivery.WebhookID, *delivery.DurationMS)
+	return nil
+}